@@ -0,0 +1,176 @@
+// Package backup dumps RethinkDB databases and tables to tar archives and
+// restores them, without requiring the Python rethinkdb dump and restore
+// scripts.
+//
+// Archives use the same layout as rethinkdb dump, a gzip compressed tar
+// containing a single directory with one directory per database:
+//
+//	rethinkdb_dump_2006-01-02T15:04:05/
+//		test/
+//			users.info
+//			users.json
+//
+// The .info file of a table contains the result of Info, with the indexes
+// returned by IndexStatus and the write hook returned by GetWriteHook, which
+// include the functions used to recreate them, and the result of Config. The
+// documents of the table are written to a .json file containing an array, as
+// written by rethinkdb dump, or when DumpOpts.Format is "ndjson" to a .ndjson
+// file, one document per line, which rethinkdb restore cannot read. Restore
+// reads both formats.
+package backup
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	rethinkdb "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// TableInfo is the metadata of a table, stored in the .info file of the
+// table.
+type TableInfo struct {
+	DB         DBInfo      `json:"db"`
+	ID         string      `json:"id,omitempty"`
+	Name       string      `json:"name"`
+	PrimaryKey string      `json:"primary_key"`
+	Type       string      `json:"type,omitempty"`
+	Indexes    []IndexInfo `json:"indexes"`
+	WriteHook  *WriteHook  `json:"write_hook"`
+	// Config is the result of Config, written by Dump but not by rethinkdb
+	// dump.
+	Config *TableConfig `json:"config,omitempty"`
+}
+
+// DBInfo identifies the database of a table.
+type DBInfo struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+// IndexInfo is a secondary index, as returned by IndexStatus.
+type IndexInfo struct {
+	Index string `json:"index"`
+	// Function is the serialized index function, which includes the multi
+	// and geo options of the index.
+	Function Binary `json:"function"`
+	Geo      bool   `json:"geo"`
+	Multi    bool   `json:"multi"`
+	Outdated bool   `json:"outdated"`
+	Query    string `json:"query,omitempty"`
+}
+
+// WriteHook is the write hook of a table, as returned by GetWriteHook.
+type WriteHook struct {
+	Function Binary `json:"function"`
+	Query    string `json:"query,omitempty"`
+}
+
+// TableConfig is the part of the configuration of a table used when it is
+// restored.
+type TableConfig struct {
+	Durability string        `json:"durability,omitempty"`
+	Shards     []ShardConfig `json:"shards,omitempty"`
+}
+
+// ShardConfig is the configuration of a shard of a table.
+type ShardConfig struct {
+	PrimaryReplica string   `json:"primary_replica,omitempty"`
+	Replicas       []string `json:"replicas,omitempty"`
+}
+
+// Binary is a BINARY pseudo-type, stored in .info files in the raw format
+// returned by the server.
+type Binary []byte
+
+// MarshalJSON encodes the value as a BINARY pseudo-type object.
+func (b Binary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{
+		"$reql_type$": "BINARY",
+		"data":        base64.StdEncoding.EncodeToString(b),
+	})
+}
+
+// UnmarshalJSON decodes a BINARY pseudo-type object.
+func (b *Binary) UnmarshalJSON(data []byte) error {
+	var obj struct {
+		Type string `json:"$reql_type$"`
+		Data string `json:"data"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	if obj.Type != "BINARY" {
+		return fmt.Errorf("pseudo-type BINARY object is not valid")
+	}
+
+	var err error
+	*b, err = base64.StdEncoding.DecodeString(obj.Data)
+	return err
+}
+
+// tableName is the name of a table and its database.
+type tableName struct {
+	db, table string
+}
+
+func (t tableName) String() string {
+	return t.db + "." + t.table
+}
+
+func (t tableName) term() rethinkdb.Term {
+	return rethinkdb.DB(t.db).Table(t.table)
+}
+
+// tableFilter matches tables selected using a list of "db" or "db.table"
+// names, every table is matched if the list is empty.
+type tableFilter []string
+
+func (f tableFilter) validate() error {
+	for _, name := range f {
+		parts := strings.Split(name, ".")
+		if len(parts) > 2 || parts[0] == "" || len(parts) == 2 && parts[1] == "" {
+			return fmt.Errorf("rethinkdb: backup: table name %q is not valid, expected db or db.table", name)
+		}
+	}
+	return nil
+}
+
+func (f tableFilter) matches(t tableName) bool {
+	if len(f) == 0 {
+		return true
+	}
+	for _, name := range f {
+		if name == t.db || name == t.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// Archive file extensions.
+const (
+	infoExt   = ".info"
+	ndjsonExt = ".ndjson"
+	jsonExt   = ".json"
+)
+
+// parsePath returns the table and extension of a file in an archive, ok is
+// false for files which are not part of a table.
+func parsePath(name string) (t tableName, ext string, ok bool) {
+	parts := strings.Split(path.Clean(strings.TrimPrefix(name, "./")), "/")
+	if len(parts) != 3 {
+		return tableName{}, "", false
+	}
+
+	ext = path.Ext(parts[2])
+	switch ext {
+	case infoExt, ndjsonExt, jsonExt:
+	default:
+		return tableName{}, "", false
+	}
+	return tableName{db: parts[1], table: strings.TrimSuffix(parts[2], ext)}, ext, true
+}
@@ -0,0 +1,213 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+var (
+	users = r.DB("test").Table("users")
+	docs  = []interface{}{
+		map[string]interface{}{"id": 1.0, "name": "Alice"},
+		map[string]interface{}{"id": 2.0, "name": "Bob"},
+	}
+)
+
+func dumpMock() *r.Mock {
+	mock := r.NewMock()
+	mock.On(r.DBList()).Return([]interface{}{"rethinkdb", "test"}, nil)
+	mock.On(r.DB("test").TableList()).Return([]interface{}{"users"}, nil)
+	mock.On(users.Info()).Return(map[string]interface{}{
+		"db":          map[string]interface{}{"id": "d1", "name": "test", "type": "DB"},
+		"id":          "t1",
+		"name":        "users",
+		"primary_key": "id",
+		"type":        "TABLE",
+		"indexes":     []interface{}{"name"},
+	}, nil)
+	mock.On(users.IndexStatus()).Return([]interface{}{
+		map[string]interface{}{
+			"index":    "name",
+			"function": []byte("index function"),
+			"geo":      false,
+			"multi":    true,
+			"outdated": false,
+			"ready":    true,
+			"query":    "indexCreate('name', ...)",
+		},
+	}, nil)
+	mock.On(users.GetWriteHook()).Return(map[string]interface{}{
+		"function": []byte("hook function"),
+		"query":    "setWriteHook(...)",
+	}, nil)
+	mock.On(users.Config()).Return(map[string]interface{}{
+		"durability": "soft",
+		"shards": []interface{}{
+			map[string]interface{}{"primary_replica": "a", "replicas": []interface{}{"a", "b"}},
+		},
+	}, nil)
+	mock.On(users).Return(docs, nil)
+	return mock
+}
+
+// restoreMock returns a mock expecting the queries restoring the users table
+// to an empty server.
+func restoreMock(opts r.TableCreateOpts) *r.Mock {
+	mock := r.NewMock()
+	mock.On(r.DBList()).Return([]interface{}{"rethinkdb"}, nil)
+	mock.On(r.DBCreate("test")).Return(map[string]interface{}{"dbs_created": 1}, nil)
+	mock.On(r.DB("test").TableList()).Return([]interface{}{}, nil)
+	mock.On(r.DB("test").TableCreate("users", opts)).Return(map[string]interface{}{"tables_created": 1}, nil)
+	mock.On(users.Wait()).Return(map[string]interface{}{"ready": 1}, nil)
+	mock.On(users.Insert(docs)).Return(map[string]interface{}{"inserted": 2}, nil)
+	mock.On(users.IndexList()).Return([]interface{}{}, nil)
+	mock.On(users.IndexCreateFunc("name", []byte("index function"))).Return(map[string]interface{}{"created": 1}, nil)
+	mock.On(users.IndexWait()).Return([]interface{}{map[string]interface{}{"index": "name", "ready": true}}, nil)
+	mock.On(users.SetWriteHookFunc([]byte("hook function"))).Return(map[string]interface{}{"created": 1}, nil)
+	return mock
+}
+
+// archiveFiles returns the names and contents of the files of a gzip
+// compressed tar archive.
+func archiveFiles(t *testing.T, archive []byte) map[string]string {
+	t.Helper()
+
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	files := map[string]string{}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("got error %v, expected nil", err)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("got error %v, expected nil", err)
+		}
+		files[hdr.Name] = string(b)
+	}
+}
+
+func TestDumpRestore(t *testing.T) {
+	var buf bytes.Buffer
+	mock := dumpMock()
+	if err := Dump(context.Background(), mock, &buf, DumpOpts{Dir: "dump", TempDir: t.TempDir()}); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	mock.AssertExpectations(t)
+
+	files := archiveFiles(t, buf.Bytes())
+	if len(files) != 2 {
+		t.Fatalf("got files %v, want users.info and users.json", files)
+	}
+	wantData := "[\n{\"id\":1,\"name\":\"Alice\"},\n{\"id\":2,\"name\":\"Bob\"}\n]\n"
+	if data := files["dump/test/users.json"]; data != wantData {
+		t.Errorf("got data %q, want %q", data, wantData)
+	}
+	info := files["dump/test/users.info"]
+	for _, want := range []string{
+		`"primary_key":"id"`,
+		`"function":{"$reql_type$":"BINARY","data":"aW5kZXggZnVuY3Rpb24="}`,
+		`"write_hook":{"function":{"$reql_type$":"BINARY","data":"aG9vayBmdW5jdGlvbg=="}`,
+		`"durability":"soft"`,
+	} {
+		if !strings.Contains(info, want) {
+			t.Errorf("got info %s, want it to contain %s", info, want)
+		}
+	}
+
+	mock = restoreMock(r.TableCreateOpts{PrimaryKey: "id", Durability: "soft", Shards: 1, Replicas: 2})
+	if err := Restore(context.Background(), mock, &buf, RestoreOpts{}); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	mock.AssertExpectations(t)
+}
+
+func TestDump_NDJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	err := Dump(context.Background(), dumpMock(), &buf, DumpOpts{Dir: "dump", Format: "ndjson", Tables: []string{"test.users"}})
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+
+	files := archiveFiles(t, buf.Bytes())
+	want := "{\"id\":1,\"name\":\"Alice\"}\n{\"id\":2,\"name\":\"Bob\"}\n"
+	if data := files["dump/test/users.ndjson"]; data != want {
+		t.Errorf("got data %q, want %q", data, want)
+	}
+
+	mock := restoreMock(r.TableCreateOpts{PrimaryKey: "id", Durability: "soft", Shards: 1, Replicas: 2})
+	if err := Restore(context.Background(), mock, &buf, RestoreOpts{}); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	mock.AssertExpectations(t)
+}
+
+func TestDump_Filter(t *testing.T) {
+	mock := r.NewMock()
+	mock.On(r.DBList()).Return([]interface{}{"test"}, nil)
+	mock.On(r.DB("test").TableList()).Return([]interface{}{"users"}, nil)
+
+	var buf bytes.Buffer
+	if err := Dump(context.Background(), mock, &buf, DumpOpts{Tables: []string{"test.other"}}); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	if files := archiveFiles(t, buf.Bytes()); len(files) != 0 {
+		t.Errorf("got files %v, want none", files)
+	}
+
+	if err := Dump(context.Background(), mock, &buf, DumpOpts{Tables: []string{"a.b.c"}}); err == nil {
+		t.Error("got nil, expected an error for an invalid table name")
+	}
+}
+
+// TestRestore_DumpLayout restores an uncompressed archive in the layout
+// written by rethinkdb dump, with the documents before the table info.
+func TestRestore_DumpLayout(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct{ name, data string }{
+		{"rethinkdb_dump_2020-01-01T00:00:00/test/users.json", "[\n{\"id\": 1, \"name\": \"Alice\"},\n{\"id\": 2, \"name\": \"Bob\"}\n]"},
+		{"rethinkdb_dump_2020-01-01T00:00:00/test/users.info", `{"db": {"name": "test"}, "name": "users", "primary_key": "id",
+"indexes": [{"index": "name", "function": {"$reql_type$": "BINARY", "data": "aW5kZXggZnVuY3Rpb24="}}],
+"write_hook": {"function": {"$reql_type$": "BINARY", "data": "aG9vayBmdW5jdGlvbg=="}, "query": "setWriteHook(...)"}}`},
+	} {
+		tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.data))})
+		tw.Write([]byte(f.data))
+	}
+	tw.Close()
+
+	mock := restoreMock(r.TableCreateOpts{PrimaryKey: "id"})
+	if err := Restore(context.Background(), mock, &buf, RestoreOpts{TempDir: t.TempDir()}); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	mock.AssertExpectations(t)
+}
+
+func TestRestore_TableExists(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Dump(context.Background(), dumpMock(), &buf, DumpOpts{}); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+
+	mock := r.NewMock()
+	mock.On(r.DBList()).Return([]interface{}{"test"}, nil)
+	mock.On(r.DB("test").TableList()).Return([]interface{}{"users"}, nil)
+	err := Restore(context.Background(), mock, &buf, RestoreOpts{})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("got error %v, want the table to exist", err)
+	}
+}
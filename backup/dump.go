@@ -0,0 +1,292 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	rethinkdb "gopkg.in/rethinkdb/rethinkdb-go.v6"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/export"
+)
+
+// DumpOpts contains the optional arguments for Dump.
+type DumpOpts struct {
+	// Tables selects the tables to dump, each entry is either the name of a
+	// database or a table in the form db.table. By default every table of
+	// every database except the rethinkdb system database is dumped.
+	Tables []string
+	// Format is the format of the documents, either "json", the default, for
+	// the JSON arrays written by rethinkdb dump, or "ndjson" for one document
+	// per line.
+	Format string
+	// Dir is the name of the directory containing the databases in the
+	// archive, by default rethinkdb_dump_ followed by the current time.
+	Dir string
+	// TempDir is the directory the documents of each table are buffered in
+	// before they are added to the archive, by default os.TempDir.
+	TempDir string
+}
+
+// Dump writes a gzip compressed tar archive of the selected tables to w.
+func Dump(ctx context.Context, s rethinkdb.QueryExecutor, w io.Writer, opts DumpOpts) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	filter := tableFilter(opts.Tables)
+	if err := filter.validate(); err != nil {
+		return err
+	}
+	ext := jsonExt
+	switch opts.Format {
+	case "", "json":
+	case "ndjson":
+		ext = ndjsonExt
+	default:
+		return fmt.Errorf("rethinkdb: backup: format %q is not valid", opts.Format)
+	}
+	dir := opts.Dir
+	if dir == "" {
+		dir = "rethinkdb_dump_" + time.Now().Format("2006-01-02T15:04:05")
+	}
+
+	tables, err := listTables(ctx, s, filter)
+	if err != nil {
+		return err
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	d := &dumper{ctx: ctx, s: s, tw: tw, dir: dir, ext: ext, tempDir: opts.TempDir}
+	for _, t := range tables {
+		if err := d.dumpTable(t); err != nil {
+			return fmt.Errorf("rethinkdb: backup: dumping %s: %w", t, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// listTables returns the tables matched by filter, sorted by name.
+func listTables(ctx context.Context, s rethinkdb.QueryExecutor, filter tableFilter) ([]tableName, error) {
+	runOpts := rethinkdb.RunOpts{Context: ctx}
+
+	var dbs []string
+	if err := rethinkdb.DBList().ReadAll(&dbs, s, runOpts); err != nil {
+		return nil, err
+	}
+	sort.Strings(dbs)
+
+	var tables []tableName
+	for _, db := range dbs {
+		if db == "rethinkdb" {
+			continue
+		}
+		var names []string
+		if err := rethinkdb.DB(db).TableList().ReadAll(&names, s, runOpts); err != nil {
+			return nil, err
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if t := (tableName{db: db, table: name}); filter.matches(t) {
+				tables = append(tables, t)
+			}
+		}
+	}
+	return tables, nil
+}
+
+type dumper struct {
+	ctx     context.Context
+	s       rethinkdb.QueryExecutor
+	tw      *tar.Writer
+	dir     string
+	ext     string
+	tempDir string
+}
+
+func (d *dumper) dumpTable(t tableName) error {
+	info, err := d.tableInfo(t)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	// The size of each file must be known before it is added to the archive
+	// so the documents are written to a temporary file first
+	f, err := os.CreateTemp(d.tempDir, "rethinkdb-dump-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	cursor, err := t.term().Run(d.s, rethinkdb.RunOpts{Context: d.ctx})
+	if err != nil {
+		return err
+	}
+	var writer export.Writer = export.NewNDJSONWriter(f)
+	if d.ext == jsonExt {
+		writer = newJSONArrayWriter(f)
+	}
+	if _, err := export.Export(cursor, writer); err != nil {
+		return err
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	name := path.Join(d.dir, t.db, t.table)
+	if err := d.writeFile(name+infoExt, int64(len(b)), bytes.NewReader(b)); err != nil {
+		return err
+	}
+	return d.writeFile(name+d.ext, size, f)
+}
+
+// tableInfo reads the metadata of a table.
+func (d *dumper) tableInfo(t tableName) (*TableInfo, error) {
+	var info struct {
+		DB         DBInfo `json:"db"`
+		ID         string `json:"id"`
+		Name       string `json:"name"`
+		PrimaryKey string `json:"primary_key"`
+		Type       string `json:"type"`
+	}
+	if err := d.readOne(t.term().Info(), &info); err != nil {
+		return nil, err
+	}
+
+	ti := &TableInfo{
+		DB:         info.DB,
+		ID:         info.ID,
+		Name:       info.Name,
+		PrimaryKey: info.PrimaryKey,
+		Type:       info.Type,
+		Indexes:    []IndexInfo{},
+	}
+	if err := d.readAll(t.term().IndexStatus(), func(doc []byte) error {
+		var index IndexInfo
+		if err := json.Unmarshal(doc, &index); err != nil {
+			return err
+		}
+		ti.Indexes = append(ti.Indexes, index)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := d.readOne(t.term().GetWriteHook(), &ti.WriteHook); err != nil {
+		return nil, err
+	}
+	if err := d.readOne(t.term().Config(), &ti.Config); err != nil {
+		return nil, err
+	}
+
+	return ti, nil
+}
+
+// readOne decodes the raw JSON of the result of a query into v, so that
+// pseudo-types are kept in the format returned by the server.
+func (d *dumper) readOne(t rethinkdb.Term, v interface{}) error {
+	cursor, err := t.Run(d.s, rethinkdb.RunOpts{Context: d.ctx})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	doc, ok := cursor.NextResponse()
+	if !ok {
+		if err := cursor.Err(); err != nil {
+			return err
+		}
+		return rethinkdb.ErrEmptyResult
+	}
+	return json.Unmarshal(doc, v)
+}
+
+// readAll calls fn with the raw JSON of each document of the result of a
+// query.
+func (d *dumper) readAll(t rethinkdb.Term, fn func(doc []byte) error) error {
+	cursor, err := t.Run(d.s, rethinkdb.RunOpts{Context: d.ctx})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	for {
+		doc, ok := cursor.NextDocument()
+		if !ok {
+			return cursor.Err()
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+}
+
+func (d *dumper) writeFile(name string, size int64, r io.Reader) error {
+	err := d.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(d.tw, r)
+	return err
+}
+
+// jsonArrayWriter writes documents as a JSON array with one document per
+// line, in the format written by rethinkdb dump.
+type jsonArrayWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func newJSONArrayWriter(w io.Writer) *jsonArrayWriter {
+	return &jsonArrayWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *jsonArrayWriter) WriteDocument(doc []byte) error {
+	sep := ",\n"
+	if w.count == 0 {
+		sep = "[\n"
+	}
+	w.count++
+	if _, err := w.w.WriteString(sep); err != nil {
+		return err
+	}
+	_, err := w.w.Write(doc)
+	return err
+}
+
+func (w *jsonArrayWriter) Close() error {
+	end := "\n]\n"
+	if w.count == 0 {
+		end = "[]\n"
+	}
+	if _, err := w.w.WriteString(end); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
@@ -0,0 +1,340 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	rethinkdb "gopkg.in/rethinkdb/rethinkdb-go.v6"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/export"
+)
+
+// RestoreOpts contains the optional arguments for Restore.
+type RestoreOpts struct {
+	// Tables selects the tables to restore in the same way as
+	// DumpOpts.Tables, by default every table in the archive is restored.
+	Tables []string
+	// Force inserts the documents of tables which already exist instead of
+	// returning an error, indexes which already exist are not recreated.
+	Force bool
+	// Shards and Replicas are the number of shards and replicas of each
+	// table, by default the numbers in the configuration of the dumped table
+	// are used, or the server defaults if the archive was written by
+	// rethinkdb dump.
+	Shards   int
+	Replicas int
+	// ImportOpts are the options used to insert the documents, by default
+	// batches of 200 documents are inserted by 8 queries in parallel.
+	ImportOpts export.ImportOpts
+	// TempDir is the directory the documents of a table are buffered in when
+	// the archive contains them before the .info file of the table, by
+	// default os.TempDir.
+	TempDir string
+}
+
+// Restore restores the tables of a tar archive written by Dump or rethinkdb
+// dump, which may be gzip compressed. For each table the database and table
+// are created, the documents are inserted and then the secondary indexes and
+// write hook of the table are created.
+func Restore(ctx context.Context, s rethinkdb.QueryExecutor, r io.Reader, opts RestoreOpts) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	filter := tableFilter(opts.Tables)
+	if err := filter.validate(); err != nil {
+		return err
+	}
+	if opts.ImportOpts.Concurrency <= 0 {
+		opts.ImportOpts.Concurrency = 8
+	}
+
+	br := bufio.NewReader(r)
+	var ar io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		ar = gr
+	}
+
+	rs := &restorer{ctx: ctx, s: s, opts: opts, tables: map[tableName]*restoreTable{}}
+	defer rs.cleanup()
+
+	tr := tar.NewReader(ar)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		t, ext, ok := parsePath(hdr.Name)
+		if !ok || !filter.matches(t) {
+			continue
+		}
+
+		if err := rs.restoreFile(t, ext, tr); err != nil {
+			return fmt.Errorf("rethinkdb: backup: restoring %s: %w", t, err)
+		}
+	}
+
+	return rs.finish()
+}
+
+// restoreTable is the state of a table being restored.
+type restoreTable struct {
+	info *TableInfo
+	// pending is the file the documents are buffered in if they are read
+	// before the table info.
+	pending    *os.File
+	pendingExt string
+	imported   bool
+}
+
+type restorer struct {
+	ctx    context.Context
+	s      rethinkdb.QueryExecutor
+	opts   RestoreOpts
+	tables map[tableName]*restoreTable
+	dbs    map[string]bool
+}
+
+func (rs *restorer) table(t tableName) *restoreTable {
+	rt, ok := rs.tables[t]
+	if !ok {
+		rt = &restoreTable{}
+		rs.tables[t] = rt
+	}
+	return rt
+}
+
+func (rs *restorer) restoreFile(t tableName, ext string, r io.Reader) error {
+	rt := rs.table(t)
+	if ext == infoExt {
+		var info TableInfo
+		if err := json.NewDecoder(r).Decode(&info); err != nil {
+			return err
+		}
+		rt.info = &info
+		if err := rs.createTable(t, &info); err != nil {
+			return err
+		}
+		if rt.pending == nil {
+			return nil
+		}
+		if _, err := rt.pending.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return rs.importDocuments(t, rt, rt.pendingExt, rt.pending)
+	}
+
+	if rt.info != nil {
+		return rs.importDocuments(t, rt, ext, r)
+	}
+
+	// Buffer the documents until the table has been created
+	f, err := os.CreateTemp(rs.opts.TempDir, "rethinkdb-restore-*")
+	if err != nil {
+		return err
+	}
+	rt.pending, rt.pendingExt = f, ext
+	_, err = io.Copy(f, r)
+	return err
+}
+
+func (rs *restorer) createTable(t tableName, info *TableInfo) error {
+	runOpts := rethinkdb.RunOpts{Context: rs.ctx}
+
+	if rs.dbs == nil {
+		var dbs []string
+		if err := rethinkdb.DBList().ReadAll(&dbs, rs.s, runOpts); err != nil {
+			return err
+		}
+		rs.dbs = map[string]bool{}
+		for _, db := range dbs {
+			rs.dbs[db] = true
+		}
+	}
+	if !rs.dbs[t.db] {
+		if _, err := rethinkdb.DBCreate(t.db).RunWrite(rs.s, runOpts); err != nil {
+			return err
+		}
+		rs.dbs[t.db] = true
+	}
+
+	var tables []string
+	if err := rethinkdb.DB(t.db).TableList().ReadAll(&tables, rs.s, runOpts); err != nil {
+		return err
+	}
+	for _, name := range tables {
+		if name == t.table {
+			if !rs.opts.Force {
+				return fmt.Errorf("table already exists, use Force to insert into it")
+			}
+			return nil
+		}
+	}
+
+	_, err := rethinkdb.DB(t.db).TableCreate(t.table, rs.tableCreateOpts(info)).RunWrite(rs.s, runOpts)
+	if err != nil {
+		return err
+	}
+	return t.term().Wait().Exec(rs.s, rethinkdb.ExecOpts{Context: rs.ctx})
+}
+
+func (rs *restorer) tableCreateOpts(info *TableInfo) rethinkdb.TableCreateOpts {
+	var opts rethinkdb.TableCreateOpts
+	if info.PrimaryKey != "" {
+		opts.PrimaryKey = info.PrimaryKey
+	}
+	if config := info.Config; config != nil {
+		if config.Durability != "" {
+			opts.Durability = config.Durability
+		}
+		if len(config.Shards) > 0 {
+			opts.Shards = len(config.Shards)
+			opts.Replicas = len(config.Shards[0].Replicas)
+		}
+	}
+	if rs.opts.Shards > 0 {
+		opts.Shards = rs.opts.Shards
+	}
+	if rs.opts.Replicas > 0 {
+		opts.Replicas = rs.opts.Replicas
+	}
+	return opts
+}
+
+// importDocuments inserts the documents of a table and then creates its
+// indexes and write hook.
+func (rs *restorer) importDocuments(t tableName, rt *restoreTable, ext string, r io.Reader) error {
+	var reader export.Reader
+	if ext == jsonExt {
+		reader = newJSONArrayReader(r)
+	} else {
+		reader = export.NewNDJSONReader(r)
+	}
+	if _, err := export.Import(rs.ctx, rs.s, t.term(), reader, rs.opts.ImportOpts); err != nil {
+		return err
+	}
+	rt.imported = true
+
+	return rs.createIndexes(t, rt.info)
+}
+
+func (rs *restorer) createIndexes(t tableName, info *TableInfo) error {
+	runOpts := rethinkdb.RunOpts{Context: rs.ctx}
+
+	var existing []string
+	if err := t.term().IndexList().ReadAll(&existing, rs.s, runOpts); err != nil {
+		return err
+	}
+	exists := map[string]bool{}
+	for _, name := range existing {
+		exists[name] = true
+	}
+
+	var created bool
+	for _, index := range info.Indexes {
+		if exists[index.Index] {
+			continue
+		}
+		if _, err := t.term().IndexCreateFunc(index.Index, []byte(index.Function)).RunWrite(rs.s, runOpts); err != nil {
+			return err
+		}
+		created = true
+	}
+	if created {
+		if err := t.term().IndexWait().Exec(rs.s, rethinkdb.ExecOpts{Context: rs.ctx}); err != nil {
+			return err
+		}
+	}
+
+	if info.WriteHook != nil {
+		if _, err := t.term().SetWriteHookFunc([]byte(info.WriteHook.Function)).RunWrite(rs.s, runOpts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish creates the indexes of tables without documents in the archive and
+// checks that every table with documents had a .info file.
+func (rs *restorer) finish() error {
+	var names []tableName
+	for t := range rs.tables {
+		names = append(names, t)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i].String() < names[j].String()
+	})
+
+	for _, t := range names {
+		rt := rs.tables[t]
+		if rt.info == nil {
+			return fmt.Errorf("rethinkdb: backup: restoring %s: missing %s file", t, infoExt)
+		}
+		if !rt.imported {
+			if err := rs.createIndexes(t, rt.info); err != nil {
+				return fmt.Errorf("rethinkdb: backup: restoring %s: %w", t, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (rs *restorer) cleanup() {
+	for _, rt := range rs.tables {
+		if rt.pending != nil {
+			rt.pending.Close()
+			os.Remove(rt.pending.Name())
+		}
+	}
+}
+
+// jsonArrayReader reads the documents of a JSON array.
+type jsonArrayReader struct {
+	dec     *json.Decoder
+	started bool
+}
+
+func newJSONArrayReader(r io.Reader) *jsonArrayReader {
+	return &jsonArrayReader{dec: json.NewDecoder(r)}
+}
+
+func (r *jsonArrayReader) ReadDocument() (map[string]interface{}, error) {
+	if !r.started {
+		tok, err := r.dec.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			return nil, fmt.Errorf("rethinkdb: backup: expected a JSON array of documents")
+		}
+		r.started = true
+	}
+	if !r.dec.More() {
+		return nil, io.EOF
+	}
+
+	var doc map[string]interface{}
+	if err := r.dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,272 @@
+package rethinkdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+const (
+	// defaultBlobChunkSize is the size of the chunks blobs are split into,
+	// well below the 64MB limit of a query.
+	defaultBlobChunkSize = 255 * 1024
+	defaultBlobTable     = "blobs"
+)
+
+// ErrBlobNotFound is returned when opening a blob which does not exist.
+var ErrBlobNotFound = errors.New("rethinkdb: blob not found")
+
+// BlobStoreOpts contains the optional arguments for NewBlobStore.
+type BlobStoreOpts struct {
+	// DB is the database containing the tables, by default the database of
+	// the session is used.
+	DB string
+	// Table is the table storing the manifest of each blob, by default
+	// "blobs".
+	Table string
+	// ChunksTable is the table storing the chunks of each blob, by default the
+	// name of Table followed by "_chunks".
+	ChunksTable string
+	// ChunkSize is the size of each chunk in bytes, by default 255KB.
+	ChunkSize int
+}
+
+// BlobStore stores large binary values in a table without holding the whole
+// value in memory. Each blob is split into chunks which are stored as separate
+// documents, keyed by the ID of the blob and the index of the chunk, and a
+// manifest document containing the length and SHA-256 hash of the blob.
+//
+// Both tables must already exist and use the default primary key "id":
+//
+//	r.DB("files").TableCreate("blobs").Exec(session)
+//	r.DB("files").TableCreate("blobs_chunks").Exec(session)
+//
+//	store := r.NewBlobStore(session, r.BlobStoreOpts{DB: "files"})
+//	manifest, err := store.Put(ctx, "report.pdf", file)
+type BlobStore struct {
+	s         QueryExecutor
+	files     Term
+	chunks    Term
+	chunkSize int
+}
+
+// BlobManifest describes a blob stored in a BlobStore.
+type BlobManifest struct {
+	ID        string `rethinkdb:"id"`
+	Length    int64  `rethinkdb:"length"`
+	ChunkSize int    `rethinkdb:"chunk_size"`
+	Chunks    int    `rethinkdb:"chunks"`
+	SHA256    string `rethinkdb:"sha256"`
+}
+
+type blobChunk struct {
+	Data []byte `rethinkdb:"data"`
+}
+
+// NewBlobStore returns a BlobStore using the tables given in the options.
+func NewBlobStore(s QueryExecutor, opts BlobStoreOpts) *BlobStore {
+	if opts.Table == "" {
+		opts.Table = defaultBlobTable
+	}
+	if opts.ChunksTable == "" {
+		opts.ChunksTable = opts.Table + "_chunks"
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultBlobChunkSize
+	}
+
+	files, chunks := Table(opts.Table), Table(opts.ChunksTable)
+	if opts.DB != "" {
+		files, chunks = DB(opts.DB).Table(opts.Table), DB(opts.DB).Table(opts.ChunksTable)
+	}
+
+	return &BlobStore{
+		s:         s,
+		files:     files,
+		chunks:    chunks,
+		chunkSize: opts.ChunkSize,
+	}
+}
+
+// Put reads r until EOF and stores the data as the blob with the given ID,
+// replacing any existing blob. Only a single chunk is held in memory at a
+// time. The manifest is written once all of the chunks have been written, so
+// a new blob is never visible before it is complete. If Put fails while
+// replacing a blob the existing blob may be left corrupt, which is detected
+// when it is read.
+func (b *BlobStore) Put(ctx context.Context, id string, r io.Reader) (BlobManifest, error) {
+	manifest := BlobManifest{
+		ID:        id,
+		ChunkSize: b.chunkSize,
+	}
+	h := sha256.New()
+
+	for {
+		// Use a new buffer for each chunk as the query executor may keep the
+		// query after it has run
+		buf := make([]byte, b.chunkSize)
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			h.Write(buf[:n])
+
+			_, werr := b.chunks.Insert(map[string]interface{}{
+				"id":   []interface{}{id, manifest.Chunks},
+				"data": Binary(buf[:n]),
+			}, InsertOpts{Conflict: "replace"}).RunWrite(b.s, RunOpts{Context: ctx})
+			if werr != nil {
+				return BlobManifest{}, werr
+			}
+
+			manifest.Chunks++
+			manifest.Length += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return BlobManifest{}, err
+		}
+	}
+	manifest.SHA256 = hex.EncodeToString(h.Sum(nil))
+
+	_, err := b.files.Insert(manifest, InsertOpts{Conflict: "replace"}).RunWrite(b.s, RunOpts{Context: ctx})
+	if err != nil {
+		return BlobManifest{}, err
+	}
+
+	// Remove any chunks left over from a previous, longer, blob
+	_, err = b.chunks.Between([]interface{}{id, manifest.Chunks}, []interface{}{id, MaxVal}).Delete().RunWrite(b.s, RunOpts{Context: ctx})
+	if err != nil {
+		return BlobManifest{}, err
+	}
+
+	return manifest, nil
+}
+
+// Stat returns the manifest of the blob, or ErrBlobNotFound if the blob does
+// not exist.
+func (b *BlobStore) Stat(ctx context.Context, id string) (BlobManifest, error) {
+	cursor, err := b.files.Get(id).Run(b.s, RunOpts{Context: ctx})
+	if err != nil {
+		return BlobManifest{}, err
+	}
+	defer cursor.Close()
+
+	if cursor.IsNil() {
+		return BlobManifest{}, ErrBlobNotFound
+	}
+
+	var manifest BlobManifest
+	if err := cursor.One(&manifest); err != nil {
+		return BlobManifest{}, err
+	}
+
+	return manifest, nil
+}
+
+// Open returns a reader which streams the chunks of the blob from the
+// database using a cursor. The reader checks the length and hash of the blob
+// once all of the chunks have been read and must be closed after use.
+func (b *BlobStore) Open(ctx context.Context, id string) (*BlobReader, error) {
+	manifest, err := b.Stat(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := b.chunks.Between([]interface{}{id, 0}, []interface{}{id, manifest.Chunks}).
+		OrderBy(OrderByOpts{Index: "id"}).
+		Run(b.s, RunOpts{Context: ctx})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BlobReader{
+		manifest: manifest,
+		cursor:   cursor,
+		hash:     sha256.New(),
+	}, nil
+}
+
+// Delete removes the blob and its chunks, deleting a blob which does not
+// exist is not an error.
+func (b *BlobStore) Delete(ctx context.Context, id string) error {
+	// Delete the manifest first so that the blob is not visible while the
+	// chunks are being removed
+	_, err := b.files.Get(id).Delete().RunWrite(b.s, RunOpts{Context: ctx})
+	if err != nil {
+		return err
+	}
+
+	_, err = b.chunks.Between([]interface{}{id, MinVal}, []interface{}{id, MaxVal}).Delete().RunWrite(b.s, RunOpts{Context: ctx})
+	return err
+}
+
+// BlobReader reads a blob from a BlobStore, see BlobStore.Open.
+type BlobReader struct {
+	manifest BlobManifest
+	cursor   *Cursor
+	hash     hash.Hash
+
+	buf    []byte
+	chunks int
+	read   int64
+	err    error
+}
+
+// Manifest returns the manifest of the blob being read.
+func (r *BlobReader) Manifest() BlobManifest {
+	return r.manifest
+}
+
+// Read reads the next bytes of the blob. Once all of the chunks have been
+// read an error is returned if the blob does not match its manifest.
+func (r *BlobReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		var chunk blobChunk
+		if !r.cursor.Next(&chunk) {
+			if err := r.cursor.Err(); err != nil {
+				r.err = err
+			} else {
+				r.err = r.verify()
+			}
+			continue
+		}
+
+		r.hash.Write(chunk.Data)
+		r.buf = chunk.Data
+		r.chunks++
+		r.read += int64(len(chunk.Data))
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// verify checks the data read matches the manifest, returning io.EOF if it
+// does.
+func (r *BlobReader) verify() error {
+	if r.chunks != r.manifest.Chunks || r.read != r.manifest.Length {
+		return RQLDriverError{rqlError(fmt.Sprintf("blob %s is incomplete, read %d bytes in %d chunks, expected %d bytes in %d chunks",
+			r.manifest.ID, r.read, r.chunks, r.manifest.Length, r.manifest.Chunks))}
+	}
+	if sum := hex.EncodeToString(r.hash.Sum(nil)); sum != r.manifest.SHA256 {
+		return RQLDriverError{rqlError(fmt.Sprintf("blob %s is corrupt, SHA-256 is %s, expected %s",
+			r.manifest.ID, sum, r.manifest.SHA256))}
+	}
+
+	return io.EOF
+}
+
+// Close closes the cursor used to read the chunks.
+func (r *BlobReader) Close() error {
+	return r.cursor.Close()
+}
@@ -0,0 +1,154 @@
+package rethinkdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"reflect"
+
+	test "gopkg.in/check.v1"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
+)
+
+type BlobSuite struct{}
+
+var _ = test.Suite(&BlobSuite{})
+
+func binaryResponse(data string) map[string]interface{} {
+	return map[string]interface{}{
+		"$reql_type$": "BINARY",
+		"data":        base64.StdEncoding.EncodeToString([]byte(data)),
+	}
+}
+
+func (s *BlobSuite) TestBlobStore_Put(c *test.C) {
+	mock := NewMock()
+	store := NewBlobStore(mock, BlobStoreOpts{DB: "files", ChunkSize: 4})
+	chunks := DB("files").Table("blobs_chunks")
+
+	for i, chunk := range []string{"hell", "o wo", "rld"} {
+		mock.On(chunks.Insert(map[string]interface{}{
+			"id":   []interface{}{"greeting", i},
+			"data": Binary([]byte(chunk)),
+		}, InsertOpts{Conflict: "replace"})).Return(map[string]interface{}{"inserted": 1}, nil)
+	}
+	manifest := BlobManifest{
+		ID:        "greeting",
+		Length:    11,
+		ChunkSize: 4,
+		Chunks:    3,
+		SHA256:    "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+	}
+	mock.On(DB("files").Table("blobs").Insert(manifest, InsertOpts{Conflict: "replace"})).Return(map[string]interface{}{"inserted": 1}, nil)
+	mock.On(chunks.Between([]interface{}{"greeting", 3}, []interface{}{"greeting", MaxVal}).Delete()).Return(map[string]interface{}{"deleted": 1}, nil)
+
+	res, err := store.Put(context.Background(), "greeting", bytes.NewBufferString("hello world"))
+	c.Assert(err, test.IsNil)
+	c.Assert(res, test.Equals, manifest)
+	mock.AssertExpectations(c)
+}
+
+func (s *BlobSuite) TestBlobStore_Open(c *test.C) {
+	mock := NewMock()
+	store := NewBlobStore(mock, BlobStoreOpts{})
+
+	mock.On(Table("blobs").Get("greeting")).Return(map[string]interface{}{
+		"id":         "greeting",
+		"length":     11,
+		"chunk_size": 4,
+		"chunks":     3,
+		"sha256":     "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+	}, nil)
+	mock.On(Table("blobs_chunks").Between([]interface{}{"greeting", 0}, []interface{}{"greeting", 3}).OrderBy(OrderByOpts{Index: "id"})).Return([]interface{}{
+		map[string]interface{}{"data": binaryResponse("hell")},
+		map[string]interface{}{"data": binaryResponse("o wo")},
+		map[string]interface{}{"data": binaryResponse("rld")},
+	}, nil)
+
+	r, err := store.Open(context.Background(), "greeting")
+	c.Assert(err, test.IsNil)
+	b, err := io.ReadAll(r)
+	c.Assert(err, test.IsNil)
+	c.Assert(string(b), test.Equals, "hello world")
+	c.Assert(r.Close(), test.IsNil)
+	mock.AssertExpectations(c)
+}
+
+func (s *BlobSuite) TestBlobStore_OpenCorrupt(c *test.C) {
+	mock := NewMock()
+	store := NewBlobStore(mock, BlobStoreOpts{})
+
+	mock.On(Table("blobs").Get("greeting")).Return(map[string]interface{}{
+		"id":     "greeting",
+		"length": 3,
+		"chunks": 1,
+		"sha256": "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+	}, nil)
+	mock.On(Table("blobs_chunks").Between([]interface{}{"greeting", 0}, []interface{}{"greeting", 1}).OrderBy(OrderByOpts{Index: "id"})).Return([]interface{}{
+		map[string]interface{}{"data": binaryResponse("abc")},
+	}, nil)
+
+	r, err := store.Open(context.Background(), "greeting")
+	c.Assert(err, test.IsNil)
+	_, err = io.ReadAll(r)
+	c.Assert(err, test.ErrorMatches, "rethinkdb: blob greeting is corrupt, .*")
+}
+
+func (s *BlobSuite) TestBlobStore_NotFound(c *test.C) {
+	mock := NewMock()
+	store := NewBlobStore(mock, BlobStoreOpts{})
+	mock.On(Table("blobs").Get("missing")).Return(nil, nil)
+
+	_, err := store.Open(context.Background(), "missing")
+	c.Assert(err, test.Equals, ErrBlobNotFound)
+}
+
+func (s *BlobSuite) TestBinary_MarshalJSON(c *test.C) {
+	q, err := newQuery(Expr([]byte("hello")), nil, &ConnectOpts{})
+	c.Assert(err, test.IsNil)
+
+	b, err := json.Marshal(q.Build())
+	c.Assert(err, test.IsNil)
+	c.Assert(string(b), test.Equals, `[1,{"$reql_type$":"BINARY","data":"aGVsbG8="}]`)
+}
+
+// hexBytes is a byte slice encoded as a hexadecimal string.
+type hexBytes []byte
+
+func (b hexBytes) MarshalRQL() (interface{}, error) {
+	return hex.EncodeToString(b), nil
+}
+
+// ipBytes is a byte array encoded using SetTypeEncoding.
+type ipBytes [4]byte
+
+func (s *BlobSuite) TestExpr_BinaryCustomEncoding(c *test.C) {
+	built, err := Expr(hexBytes("hello")).Build()
+	c.Assert(err, test.IsNil)
+	c.Assert(built, test.Equals, "68656c6c6f")
+
+	encoding.SetTypeEncoding(reflect.TypeOf(ipBytes{}), func(value interface{}) (interface{}, error) {
+		ip := value.(ipBytes)
+		return net.IP(ip[:]).String(), nil
+	}, func(encoded interface{}, value reflect.Value) error {
+		copy(value.Addr().Interface().(*ipBytes)[:], net.ParseIP(encoded.(string)).To4())
+		return nil
+	})
+	built, err = Expr(ipBytes{127, 0, 0, 1}).Build()
+	c.Assert(err, test.IsNil)
+	c.Assert(built, test.Equals, "127.0.0.1")
+}
+
+func (s *BlobSuite) TestExpr_NilBinary(c *test.C) {
+	built, err := Expr([]byte(nil)).Build()
+	c.Assert(err, test.IsNil)
+	c.Assert(built, test.IsNil)
+
+	built, err = Expr([]byte{}).Build()
+	c.Assert(err, test.IsNil)
+	c.Assert(built, test.DeepEquals, map[string]interface{}{"$reql_type$": "BINARY", "data": binaryData{}})
+}
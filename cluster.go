@@ -89,15 +89,15 @@ func (c *Cluster) run() error {
 func (c *Cluster) Query(ctx context.Context, q Query) (cursor *Cursor, err error) {
 	for i := 0; i < c.numRetries(); i++ {
 		var node *Node
-		var hpr hostpool.HostPoolResponse
+		var mark func(error)
 
-		node, hpr, err = c.GetNextNode()
+		node, mark, err = c.selectNode(q)
 		if err != nil {
 			return nil, err
 		}
 
 		cursor, err = node.Query(ctx, q)
-		hpr.Mark(err)
+		mark(err)
 
 		if !shouldRetryQuery(q, err) {
 			break
@@ -111,15 +111,15 @@ func (c *Cluster) Query(ctx context.Context, q Query) (cursor *Cursor, err error
 func (c *Cluster) Exec(ctx context.Context, q Query) (err error) {
 	for i := 0; i < c.numRetries(); i++ {
 		var node *Node
-		var hpr hostpool.HostPoolResponse
+		var mark func(error)
 
-		node, hpr, err = c.GetNextNode()
+		node, mark, err = c.selectNode(q)
 		if err != nil {
 			return err
 		}
 
 		err = node.Exec(ctx, q)
-		hpr.Mark(err)
+		mark(err)
 
 		if !shouldRetryQuery(q, err) {
 			break
@@ -129,6 +129,17 @@ func (c *Cluster) Exec(ctx context.Context, q Query) (err error) {
 	return err
 }
 
+// noReplyWait waits for the noreply queries of every connection to be
+// processed by the server.
+func (c *Cluster) noReplyWait(ctx context.Context) error {
+	for _, node := range c.GetNodes() {
+		if err := node.noReplyWait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Server returns the server name and server UUID being used by a connection.
 func (c *Cluster) Server() (response ServerResponse, err error) {
 	for i := 0; i < c.numRetries(); i++ {
@@ -263,6 +274,17 @@ func (c *Cluster) listenForNodeChanges() error {
 		if result.NewVal != nil && result.OldVal == nil {
 			// added new node
 			if !c.nodeExists(result.NewVal.ID) {
+				var discoverable bool
+				discoverable, err = c.isDiscoverable(node, result.NewVal)
+				if err != nil {
+					hpr.Mark(err)
+					return err
+				}
+				if !discoverable {
+					c.log.Debug("Skipping node", "id", result.NewVal.ID, "name", result.NewVal.Name)
+					continue
+				}
+
 				// Connect to node using exponential backoff (give up after waiting 5s)
 				// to give the node time to start-up.
 				b := backoff.NewExponentialBackOff()
@@ -359,10 +381,52 @@ func (c *Cluster) connectCluster() error {
 	return nil
 }
 
+// isDiscoverable checks whether a node reported by the cluster matches the
+// DiscoverServerNames and DiscoverServerTags connect options. The tags of
+// the server are fetched using the node the driver is currently listening to.
+func (c *Cluster) isDiscoverable(listenNode *Node, s *nodeStatus) (bool, error) {
+	if len(c.opts.DiscoverServerNames) > 0 && !containsString(c.opts.DiscoverServerNames, s.Name) {
+		return false, nil
+	}
+	if len(c.opts.DiscoverServerTags) == 0 {
+		return true, nil
+	}
+
+	q, err := newQuery(
+		DB(SystemDatabase).Table(ServerConfigSystemTable).Get(s.ID).Field("tags").Default([]interface{}{}),
+		map[string]interface{}{},
+		c.opts,
+	)
+	if err != nil {
+		return false, fmt.Errorf("Error building query: %w", err)
+	}
+
+	cursor, err := listenNode.Query(context.TODO(), q)
+	if err != nil {
+		return false, err
+	}
+
+	var tags []string
+	if err = cursor.One(&tags); err != nil && err != ErrEmptyResult {
+		return false, err
+	}
+
+	for _, tag := range tags {
+		if containsString(c.opts.DiscoverServerTags, tag) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 func (c *Cluster) connectNodeWithStatus(s *nodeStatus) (*Node, error) {
 	aliases := make([]Host, len(s.Network.CanonicalAddresses))
 	for i, aliasAddress := range s.Network.CanonicalAddresses {
 		aliases[i] = NewHost(aliasAddress.Host, int(s.Network.ReqlPort))
+		if c.opts.AddressTranslator != nil {
+			aliases[i] = c.opts.AddressTranslator(aliases[i])
+		}
 	}
 
 	return c.connectNode(s.ID, aliases)
@@ -423,6 +487,44 @@ func (c *Cluster) GetNextNode() (*Node, hostpool.HostPoolResponse, error) {
 	return nil, nil, ErrNoConnections
 }
 
+// selectNode returns the node used to run the query and a function which
+// must be called with the result of the query. The node is chosen using the
+// NodeSelector from the connect options if set, otherwise the host pool is used.
+func (c *Cluster) selectNode(q Query) (*Node, func(error), error) {
+	selector := c.opts.NodeSelector
+	if selector == nil {
+		node, hpr, err := c.GetNextNode()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return node, hpr.Mark, nil
+	}
+
+	if !c.IsConnected() {
+		return nil, nil, ErrNoConnections
+	}
+
+	nodes := c.GetNodes()
+	open := nodes[:0]
+	for _, n := range nodes {
+		if !n.Closed() {
+			open = append(open, n)
+		}
+	}
+	if len(open) == 0 {
+		return nil, nil, ErrNoConnections
+	}
+	sort.Slice(open, func(i, j int) bool { return open[i].ID < open[j].ID })
+
+	node, err := selector.Select(q, open)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return node, func(err error) { selector.Mark(node, err) }, nil
+}
+
 // GetNodes returns a list of all nodes in the cluster
 func (c *Cluster) GetNodes() []*Node {
 	c.mu.RLock()
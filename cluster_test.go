@@ -305,6 +305,57 @@ func (s *ClusterSuite) TestCluster_NewSingle_Discover_Ok(c *test.C) {
 	mock.AssertExpectationsForObjects(c, dialMock, conn1, conn2, conn3, conn4)
 }
 
+func (s *ClusterSuite) TestCluster_Discover_DefaultRunOpts(c *test.C) {
+	host1 := Host{Name: "host1", Port: 28015}
+	host2 := Host{Name: "1.1.1.1", Port: 2222}
+	node1 := "node1"
+	node2 := "node2"
+
+	conn1 := &connMock{}
+	expectServerQuery(conn1, 1, node1)
+	conn1.onCloseReturn(nil)
+	conn2 := &connMock{}
+	expectServerStatus(conn2, 1, []string{node1, node2}, []Host{host1, host2})
+	conn2.onCloseReturn(nil)
+	conn3 := &connMock{}
+	conn3.onCloseReturn(nil)
+
+	dialMock := &mockDial{}
+	dialMock.On("Dial", host1.String()).Return(conn1, nil).Once()
+	dialMock.On("Dial", host1.String()).Return(conn2, nil).Once()
+	dialMock.On("Dial", host2.String()).Return(conn3, nil).Once()
+
+	// The default run options of the session are not used to discover hosts
+	opts := &ConnectOpts{
+		DiscoverHosts:  true,
+		DefaultRunOpts: RunOpts{DecodeOpts: encoding.DecodeOpts{DisallowUnknownFields: true}},
+	}
+	cluster := &Cluster{
+		hp:               newHostPool(opts),
+		seeds:            []Host{host1},
+		opts:             opts,
+		closed:           clusterWorking,
+		connFactory:      mockedConnectionFactory(dialMock),
+		discoverInterval: 10 * time.Second,
+		log:              slog.Default(),
+	}
+
+	err := cluster.run()
+	c.Assert(err, test.IsNil)
+	conn1.waitDial()
+	conn2.waitDial()
+	conn3.waitDial()
+	for !cluster.nodeExists(node2) {
+		time.Sleep(time.Millisecond)
+	}
+	err = cluster.Close()
+	c.Assert(err, test.IsNil)
+	conn1.waitDone()
+	conn2.waitDone()
+	conn3.waitDone()
+	mock.AssertExpectationsForObjects(c, dialMock, conn1, conn2, conn3)
+}
+
 func (s *ClusterSuite) TestCluster_NewMultiple_Discover_Ok(c *test.C) {
 	host1 := Host{Name: "host1", Port: 28015}
 	host2 := Host{Name: "host2", Port: 28016}
@@ -366,6 +417,67 @@ func (s *ClusterSuite) TestCluster_NewMultiple_Discover_Ok(c *test.C) {
 	mock.AssertExpectationsForObjects(c, dialMock, conn1, conn2, conn3, conn4, conn5)
 }
 
+func (s *ClusterSuite) TestCluster_NewSingle_Discover_TranslateAndFilter(c *test.C) {
+	host1 := Host{Name: "host1", Port: 28015}
+	host2 := Host{Name: "1.1.1.1", Port: 2222}
+	host3 := Host{Name: "2.2.2.2", Port: 3333}
+	host2Public := Host{Name: "public2", Port: 28015}
+	node1 := "node1"
+	node2 := "node2"
+	node3 := "node3"
+
+	conn1 := &connMock{}
+	expectServerQuery(conn1, 1, node1)
+	conn1.onCloseReturn(nil)
+	conn2 := &connMock{}
+	expectServerStatus(conn2, 1, []string{node1, node2, node3}, []Host{host1, host2, host3})
+	conn2.onCloseReturn(nil)
+	conn3 := &connMock{}
+	conn3.onCloseReturn(nil)
+
+	dialMock := &mockDial{}
+	dialMock.On("Dial", host1.String()).Return(conn1, nil).Once()
+	dialMock.On("Dial", host1.String()).Return(conn2, nil).Once()
+	dialMock.On("Dial", host2Public.String()).Return(conn3, nil).Once()
+
+	opts := &ConnectOpts{
+		DiscoverHosts:       true,
+		DiscoverServerNames: []string{node1, node2},
+		AddressTranslator: func(host Host) Host {
+			if host == host2 {
+				return host2Public
+			}
+			return host
+		},
+	}
+	seeds := []Host{host1}
+	cluster := &Cluster{
+		hp:               newHostPool(opts),
+		seeds:            seeds,
+		opts:             opts,
+		closed:           clusterWorking,
+		connFactory:      mockedConnectionFactory(dialMock),
+		discoverInterval: 10 * time.Second,
+		log:              slog.Default(),
+	}
+
+	err := cluster.run()
+	c.Assert(err, test.IsNil)
+	conn1.waitDial()
+	conn2.waitDial()
+	conn3.waitDial()
+	for !cluster.nodeExists(node2) { // wait node to be added to list to be closed with cluster
+		time.Sleep(time.Millisecond)
+	}
+	c.Assert(cluster.nodeExists(node3), test.Equals, false)
+	err = cluster.Close()
+	c.Assert(err, test.IsNil)
+	conn1.waitDone()
+	conn2.waitDone()
+	conn3.waitDone()
+	mock.AssertExpectationsForObjects(c, dialMock, conn1, conn2, conn3)
+}
+
 type mockDial struct {
 	mock.Mock
 }
@@ -538,7 +650,7 @@ func makeServerStatusResponseRaw(token int64, nodeIDs []string, hosts []Host) []
 	}
 	jresps := make([]json.RawMessage, len(nodeIDs))
 	for i := range nodeIDs {
-		status := &nodeStatus{ID: nodeIDs[i], Network: nodeStatusNetwork{
+		status := &nodeStatus{ID: nodeIDs[i], Name: nodeIDs[i], Network: nodeStatusNetwork{
 			ReqlPort: int64(hosts[i].Port),
 			CanonicalAddresses: []nodeStatusNetworkAddr{
 				{Host: hosts[i].Name},
@@ -549,6 +661,8 @@ func makeServerStatusResponseRaw(token int64, nodeIDs []string, hosts []Host) []
 		if err != nil {
 			panic(fmt.Sprintf("must encode response failed: %v", err))
 		}
+		// The server returns more fields than the driver decodes
+		coded.(map[string]interface{})["new_val"].(map[string]interface{})["process"] = map[string]interface{}{"pid": 1}
 		jresps[i], err = json.Marshal(coded)
 		if err != nil {
 			panic(fmt.Sprintf("must encode response failed: %v", err))
@@ -0,0 +1,202 @@
+// Command rethinkdb-backup dumps RethinkDB tables to a tar archive and
+// restores them, using the same archive layout as the rethinkdb dump and
+// rethinkdb restore commands without requiring Python.
+//
+// Dump every table except the system tables to a file:
+//
+//	rethinkdb-backup dump -addr localhost:28015 -o backup.tar.gz
+//
+// Restore the tables of the test database from the file:
+//
+//	rethinkdb-backup restore -addr localhost:28015 -tables test backup.tar.gz
+//
+// The password is read from the RETHINKDB_PASSWORD environment variable if
+// the -password flag is not set.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/backup"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/export"
+)
+
+const usage = `usage: rethinkdb-backup dump [flags]
+       rethinkdb-backup restore [flags] archive
+
+Run rethinkdb-backup dump -h or rethinkdb-backup restore -h for the flags of
+each command.
+`
+
+var errUsage = errors.New("invalid arguments")
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("rethinkdb-backup: ")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		switch err {
+		case flag.ErrHelp:
+			return
+		case errUsage:
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		log.Fatal(err)
+	}
+}
+
+// connectFlags are the flags used to connect to the server.
+type connectFlags struct {
+	addr     string
+	user     string
+	password string
+	tables   string
+}
+
+func (c *connectFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.addr, "addr", "localhost:28015", "address of the server")
+	fs.StringVar(&c.user, "user", "admin", "user name")
+	fs.StringVar(&c.password, "password", os.Getenv("RETHINKDB_PASSWORD"), "password, by default $RETHINKDB_PASSWORD")
+	fs.StringVar(&c.tables, "tables", "", "comma-separated list of databases or db.table names, by default every table")
+}
+
+func (c *connectFlags) connect() (*r.Session, error) {
+	return r.Connect(r.ConnectOpts{
+		Address:  c.addr,
+		Username: c.user,
+		Password: c.password,
+	})
+}
+
+func (c *connectFlags) tableList() []string {
+	if c.tables == "" {
+		return nil
+	}
+	return strings.Split(c.tables, ",")
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "dump":
+		return dump(ctx, args[1:], stdout)
+	case "restore":
+		return restore(ctx, args[1:])
+	default:
+		return errUsage
+	}
+}
+
+// parseError returns the error of parsing the flags of a command, the flag
+// package has already printed the error and the flags of the command.
+func parseError(err error) error {
+	if err == flag.ErrHelp {
+		return err
+	}
+	return errUsage
+}
+
+func dump(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	var c connectFlags
+	c.register(fs)
+	output := fs.String("o", "", "output file, by default rethinkdb_dump_<time>.tar.gz or - for stdout")
+	format := fs.String("format", "json", "format of the documents, json or ndjson")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() > 0 {
+		return errUsage
+	}
+
+	opts := backup.DumpOpts{
+		Tables: c.tableList(),
+		Format: *format,
+		Dir:    "rethinkdb_dump_" + time.Now().Format("2006-01-02T15:04:05"),
+	}
+
+	session, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	if *output == "-" {
+		return backup.Dump(ctx, session, stdout, opts)
+	}
+
+	name := *output
+	if name == "" {
+		name = opts.Dir + ".tar.gz"
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := backup.Dump(ctx, session, f, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func restore(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	var c connectFlags
+	c.register(fs)
+	force := fs.Bool("force", false, "insert documents into tables which already exist")
+	clients := fs.Int("clients", 8, "number of insert queries run in parallel")
+	batchSize := fs.Int("batch-size", 200, "number of documents inserted by each query")
+	shards := fs.Int("shards", 0, "number of shards of each table, by default the number in the archive")
+	replicas := fs.Int("replicas", 0, "number of replicas of each table, by default the number in the archive")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	var in io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	session, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	return backup.Restore(ctx, session, in, backup.RestoreOpts{
+		Tables:   c.tableList(),
+		Force:    *force,
+		Shards:   *shards,
+		Replicas: *replicas,
+		ImportOpts: export.ImportOpts{
+			BatchSize:   *batchSize,
+			Concurrency: *clients,
+		},
+	})
+}
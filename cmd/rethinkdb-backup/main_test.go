@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+func TestRun_Usage(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"backup"},
+		{"dump", "extra"},
+		{"dump", "-unknown"},
+		{"restore"},
+		{"restore", "a.tar.gz", "b.tar.gz"},
+	} {
+		if err := run(context.Background(), args, io.Discard); err != errUsage {
+			t.Errorf("run(%q) got error %v, want %v", args, err, errUsage)
+		}
+	}
+}
+
+func TestConnectFlags_TableList(t *testing.T) {
+	c := connectFlags{}
+	if tables := c.tableList(); tables != nil {
+		t.Errorf("got %v, want nil", tables)
+	}
+	c.tables = "test,other.users"
+	if tables := c.tableList(); len(tables) != 2 || tables[0] != "test" || tables[1] != "other.users" {
+		t.Errorf("got %v, want [test other.users]", tables)
+	}
+}
@@ -0,0 +1,70 @@
+// Package example contains structs with generated MarshalRQL and UnmarshalRQL
+// methods, used to check the generated methods behave in the same way as the
+// reflection based encoder and decoder.
+package example
+
+import "time"
+
+//go:generate go run gopkg.in/rethinkdb/rethinkdb-go.v6/cmd/rethinkdb-gen
+
+// Status is a named string type, encoded using the encoding package.
+type Status string
+
+// Document uses each kind of field supported by the generator.
+//
+//rethinkdb:generate
+type Document struct {
+	ID       string                 `rethinkdb:"id,omitempty"`
+	Name     string                 `rethinkdb:"name"`
+	Count    int                    `rethinkdb:"count,omitempty"`
+	Small    int8                   `rethinkdb:"small"`
+	Size     uint32                 `rethinkdb:"size,omitempty"`
+	Score    float64                `rethinkdb:"score,omitempty"`
+	Ratio    float32                `rethinkdb:"ratio"`
+	Active   bool                   `rethinkdb:"active,omitempty"`
+	Status   Status                 `rethinkdb:"status,omitempty"`
+	Created  time.Time              `rethinkdb:"created,omitempty"`
+	Updated  time.Time              `rethinkdb:"updated,omitzero"`
+	Expires  time.Time              `rethinkdb:"expires,omitempty,omitzero"`
+	Limit    int                    `rethinkdb:"limit,omitzero"`
+	Labels   []string               `rethinkdb:"labels,omitzero"`
+	Data     []byte                 `rethinkdb:"data,omitempty"`
+	Tags     []string               `rethinkdb:"tags"`
+	Attrs    map[string]interface{} `rethinkdb:"attrs,omitempty"`
+	Meta     interface{}            `rethinkdb:"meta"`
+	Parent   *Document              `rethinkdb:"parent,omitempty"`
+	Owner    Owner                  `rethinkdb:"owner_id,reference" rethinkdb_ref:"id"`
+	Author   *Owner                 `rethinkdb:"author,omitempty"`
+	Editor   Owner                  `rethinkdb:"editor,omitzero"`
+	Legacy   string                 `gorethink:"legacy"`
+	Untagged int
+	Ignored  string `rethinkdb:"-"`
+	A, B     int    `rethinkdb:",omitempty"`
+	Dup1     string `rethinkdb:"dup"`
+	Dup2     string `rethinkdb:"dup"`
+	private  string
+}
+
+// Owner is not generated and is always encoded using reflection.
+type Owner struct {
+	ID   string `rethinkdb:"id"`
+	Name string `rethinkdb:"name"`
+}
+
+// Compound has a compound primary key.
+//
+//rethinkdb:generate
+type Compound struct {
+	Partition string `rethinkdb:"id[0]"`
+	Sequence  int64  `rethinkdb:"id[1],omitempty"`
+	Tenant    string `rethinkdb:"key[1]"`
+	Region    Status `rethinkdb:"key[0],omitempty"`
+	Value     string `rethinkdb:"value"`
+}
+
+// Empty has no encoded fields.
+//
+//rethinkdb:generate
+type Empty struct {
+	hidden int
+}
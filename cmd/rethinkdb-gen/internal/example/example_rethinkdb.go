@@ -0,0 +1,771 @@
+// Code generated by rethinkdb-gen. DO NOT EDIT.
+
+package example
+
+import (
+	"strings"
+
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
+)
+
+// MarshalRQL encodes the Document as a map, in the same way as the reflection
+// based encoder.
+func (v *Document) MarshalRQL() (interface{}, error) {
+	return v.MarshalRQLState(nil)
+}
+
+// MarshalRQLState encodes the Document as a map using the codec of enc.
+func (v *Document) MarshalRQLState(enc *encoding.EncodeState) (interface{}, error) {
+	m := make(map[string]interface{}, 26)
+	if v.ID != "" {
+		m["id"] = v.ID
+	}
+	m["name"] = v.Name
+	if v.Count != 0 {
+		m["count"] = int64(v.Count)
+	}
+	m["small"] = int64(v.Small)
+	if v.Size != 0 {
+		m["size"] = uint64(v.Size)
+	}
+	if v.Score != 0 {
+		m["score"] = v.Score
+	}
+	m["ratio"] = v.Ratio
+	if v.Active {
+		m["active"] = v.Active
+	}
+	if !encoding.IsEmptyField(&v.Status) {
+		ev, err := enc.Encode(&v.Status)
+		if err != nil {
+			return nil, err
+		}
+		m["status"] = ev
+	}
+	if !encoding.IsEmptyField(&v.Created) {
+		ev, err := enc.Encode(&v.Created)
+		if err != nil {
+			return nil, err
+		}
+		m["created"] = ev
+	}
+	if !encoding.IsZeroField(&v.Updated) {
+		ev, err := enc.Encode(&v.Updated)
+		if err != nil {
+			return nil, err
+		}
+		m["updated"] = ev
+	}
+	if !encoding.IsEmptyField(&v.Expires) && !encoding.IsZeroField(&v.Expires) {
+		ev, err := enc.Encode(&v.Expires)
+		if err != nil {
+			return nil, err
+		}
+		m["expires"] = ev
+	}
+	if v.Limit != 0 {
+		m["limit"] = int64(v.Limit)
+	}
+	if v.Labels != nil {
+		var ev interface{} = []interface{}(nil)
+		if v.Labels != nil {
+			s := make([]interface{}, len(v.Labels))
+			for i, e := range v.Labels {
+				s[i] = e
+			}
+			ev = s
+		}
+		m["labels"] = ev
+	}
+	if !encoding.IsEmptyField(&v.Data) {
+		ev, err := enc.Encode(&v.Data)
+		if err != nil {
+			return nil, err
+		}
+		m["data"] = ev
+	}
+	{
+		var ev interface{} = []interface{}(nil)
+		if v.Tags != nil {
+			s := make([]interface{}, len(v.Tags))
+			for i, e := range v.Tags {
+				s[i] = e
+			}
+			ev = s
+		}
+		m["tags"] = ev
+	}
+	if !encoding.IsEmptyField(&v.Attrs) {
+		ev, err := enc.Encode(&v.Attrs)
+		if err != nil {
+			return nil, err
+		}
+		m["attrs"] = ev
+	}
+	{
+		var ev interface{}
+		if v.Meta != nil {
+			var err error
+			if ev, err = enc.Encode(&v.Meta); err != nil {
+				return nil, err
+			}
+		}
+		m["meta"] = ev
+	}
+	if v.Parent != nil {
+		var ev interface{}
+		if v.Parent != nil {
+			var err error
+			if ev, err = enc.Encode(&v.Parent); err != nil {
+				return nil, err
+			}
+		}
+		m["parent"] = ev
+	}
+	{
+		ev, err := enc.Encode(&v.Owner)
+		if err != nil {
+			return nil, err
+		}
+		ev, err = encoding.ReferenceField(v, ev, "owner_id", "id")
+		if err != nil {
+			return nil, err
+		}
+		m["owner_id"] = ev
+	}
+	if v.Author != nil {
+		var ev interface{}
+		if v.Author != nil {
+			var err error
+			if ev, err = enc.Encode(&v.Author); err != nil {
+				return nil, err
+			}
+		}
+		m["author"] = ev
+	}
+	if !encoding.IsZeroField(&v.Editor) {
+		ev, err := enc.Encode(&v.Editor)
+		if err != nil {
+			return nil, err
+		}
+		m["editor"] = ev
+	}
+	m["legacy"] = v.Legacy
+	m["Untagged"] = int64(v.Untagged)
+	if v.A != 0 {
+		m["A"] = int64(v.A)
+	}
+	if v.B != 0 {
+		m["B"] = int64(v.B)
+	}
+
+	return m, nil
+}
+
+// UnmarshalRQL decodes the Document from a map, in the same way as the
+// reflection based decoder.
+func (v *Document) UnmarshalRQL(data interface{}) error {
+	return v.UnmarshalRQLState(data, nil)
+}
+
+// UnmarshalRQLState decodes the Document from a map using the codec and
+// options of d.
+func (v *Document) UnmarshalRQLState(data interface{}, d *encoding.DecodeState) error {
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return d.DecodeReflect(v, data)
+	}
+
+	for key, value := range m {
+		switch key {
+		case "id", "name", "count", "small", "size", "score", "ratio", "active", "status", "created", "updated", "expires", "limit", "labels", "data", "tags", "attrs", "meta", "parent", "owner_id", "author", "editor", "legacy", "Untagged", "A", "B":
+		default:
+			switch {
+			case strings.EqualFold(key, "id"):
+				if !encoding.FoldMatch(m, key, "id") {
+					continue
+				}
+				key = "id"
+			case strings.EqualFold(key, "name"):
+				if !encoding.FoldMatch(m, key, "name") {
+					continue
+				}
+				key = "name"
+			case strings.EqualFold(key, "count"):
+				if !encoding.FoldMatch(m, key, "count") {
+					continue
+				}
+				key = "count"
+			case strings.EqualFold(key, "small"):
+				if !encoding.FoldMatch(m, key, "small") {
+					continue
+				}
+				key = "small"
+			case strings.EqualFold(key, "size"):
+				if !encoding.FoldMatch(m, key, "size") {
+					continue
+				}
+				key = "size"
+			case strings.EqualFold(key, "score"):
+				if !encoding.FoldMatch(m, key, "score") {
+					continue
+				}
+				key = "score"
+			case strings.EqualFold(key, "ratio"):
+				if !encoding.FoldMatch(m, key, "ratio") {
+					continue
+				}
+				key = "ratio"
+			case strings.EqualFold(key, "active"):
+				if !encoding.FoldMatch(m, key, "active") {
+					continue
+				}
+				key = "active"
+			case strings.EqualFold(key, "status"):
+				if !encoding.FoldMatch(m, key, "status") {
+					continue
+				}
+				key = "status"
+			case strings.EqualFold(key, "created"):
+				if !encoding.FoldMatch(m, key, "created") {
+					continue
+				}
+				key = "created"
+			case strings.EqualFold(key, "updated"):
+				if !encoding.FoldMatch(m, key, "updated") {
+					continue
+				}
+				key = "updated"
+			case strings.EqualFold(key, "expires"):
+				if !encoding.FoldMatch(m, key, "expires") {
+					continue
+				}
+				key = "expires"
+			case strings.EqualFold(key, "limit"):
+				if !encoding.FoldMatch(m, key, "limit") {
+					continue
+				}
+				key = "limit"
+			case strings.EqualFold(key, "labels"):
+				if !encoding.FoldMatch(m, key, "labels") {
+					continue
+				}
+				key = "labels"
+			case strings.EqualFold(key, "data"):
+				if !encoding.FoldMatch(m, key, "data") {
+					continue
+				}
+				key = "data"
+			case strings.EqualFold(key, "tags"):
+				if !encoding.FoldMatch(m, key, "tags") {
+					continue
+				}
+				key = "tags"
+			case strings.EqualFold(key, "attrs"):
+				if !encoding.FoldMatch(m, key, "attrs") {
+					continue
+				}
+				key = "attrs"
+			case strings.EqualFold(key, "meta"):
+				if !encoding.FoldMatch(m, key, "meta") {
+					continue
+				}
+				key = "meta"
+			case strings.EqualFold(key, "parent"):
+				if !encoding.FoldMatch(m, key, "parent") {
+					continue
+				}
+				key = "parent"
+			case strings.EqualFold(key, "owner_id"):
+				if !encoding.FoldMatch(m, key, "owner_id") {
+					continue
+				}
+				key = "owner_id"
+			case strings.EqualFold(key, "author"):
+				if !encoding.FoldMatch(m, key, "author") {
+					continue
+				}
+				key = "author"
+			case strings.EqualFold(key, "editor"):
+				if !encoding.FoldMatch(m, key, "editor") {
+					continue
+				}
+				key = "editor"
+			case strings.EqualFold(key, "legacy"):
+				if !encoding.FoldMatch(m, key, "legacy") {
+					continue
+				}
+				key = "legacy"
+			case strings.EqualFold(key, "Untagged"):
+				if !encoding.FoldMatch(m, key, "Untagged") {
+					continue
+				}
+				key = "Untagged"
+			case strings.EqualFold(key, "A"):
+				if !encoding.FoldMatch(m, key, "A") {
+					continue
+				}
+				key = "A"
+			case strings.EqualFold(key, "B"):
+				if !encoding.FoldMatch(m, key, "B") {
+					continue
+				}
+				key = "B"
+			default:
+				if err := d.UnknownField(v, key); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		switch key {
+		case "id":
+			switch value := value.(type) {
+			case nil:
+			case string:
+				v.ID = value
+			default:
+				if err := d.Decode(&v.ID, value, "id"); err != nil {
+					return err
+				}
+			}
+		case "name":
+			switch value := value.(type) {
+			case nil:
+			case string:
+				v.Name = value
+			default:
+				if err := d.Decode(&v.Name, value, "name"); err != nil {
+					return err
+				}
+			}
+		case "count":
+			switch value := value.(type) {
+			case nil:
+			case float64:
+				if err := encoding.DecodeInt(d, &v.Count, value, "count"); err != nil {
+					return err
+				}
+			default:
+				if err := d.Decode(&v.Count, value, "count"); err != nil {
+					return err
+				}
+			}
+		case "small":
+			switch value := value.(type) {
+			case nil:
+			case float64:
+				if err := encoding.DecodeInt(d, &v.Small, value, "small"); err != nil {
+					return err
+				}
+			default:
+				if err := d.Decode(&v.Small, value, "small"); err != nil {
+					return err
+				}
+			}
+		case "size":
+			switch value := value.(type) {
+			case nil:
+			case float64:
+				if err := encoding.DecodeUint(d, &v.Size, value, "size"); err != nil {
+					return err
+				}
+			default:
+				if err := d.Decode(&v.Size, value, "size"); err != nil {
+					return err
+				}
+			}
+		case "score":
+			switch value := value.(type) {
+			case nil:
+			case float64:
+				v.Score = value
+			default:
+				if err := d.Decode(&v.Score, value, "score"); err != nil {
+					return err
+				}
+			}
+		case "ratio":
+			switch value := value.(type) {
+			case nil:
+			case float64:
+				v.Ratio = float32(value)
+			default:
+				if err := d.Decode(&v.Ratio, value, "ratio"); err != nil {
+					return err
+				}
+			}
+		case "active":
+			switch value := value.(type) {
+			case nil:
+			case bool:
+				v.Active = value
+			default:
+				if err := d.Decode(&v.Active, value, "active"); err != nil {
+					return err
+				}
+			}
+		case "status":
+			if value != nil {
+				if err := d.Decode(&v.Status, value, "status"); err != nil {
+					return err
+				}
+			}
+		case "created":
+			if value != nil {
+				if err := d.Decode(&v.Created, value, "created"); err != nil {
+					return err
+				}
+			}
+		case "updated":
+			if value != nil {
+				if err := d.Decode(&v.Updated, value, "updated"); err != nil {
+					return err
+				}
+			}
+		case "expires":
+			if value != nil {
+				if err := d.Decode(&v.Expires, value, "expires"); err != nil {
+					return err
+				}
+			}
+		case "limit":
+			switch value := value.(type) {
+			case nil:
+			case float64:
+				if err := encoding.DecodeInt(d, &v.Limit, value, "limit"); err != nil {
+					return err
+				}
+			default:
+				if err := d.Decode(&v.Limit, value, "limit"); err != nil {
+					return err
+				}
+			}
+		case "labels":
+			switch value := value.(type) {
+			case nil:
+			case []interface{}:
+				s := make([]string, len(value))
+				for i, e := range value {
+					switch e := e.(type) {
+					case nil:
+					case string:
+						s[i] = e
+					default:
+						if err := d.Decode(&s[i], e, "labels"); err != nil {
+							return err
+						}
+					}
+				}
+				v.Labels = s
+			default:
+				if err := d.Decode(&v.Labels, value, "labels"); err != nil {
+					return err
+				}
+			}
+		case "data":
+			if value != nil {
+				if err := d.Decode(&v.Data, value, "data"); err != nil {
+					return err
+				}
+			}
+		case "tags":
+			switch value := value.(type) {
+			case nil:
+			case []interface{}:
+				s := make([]string, len(value))
+				for i, e := range value {
+					switch e := e.(type) {
+					case nil:
+					case string:
+						s[i] = e
+					default:
+						if err := d.Decode(&s[i], e, "tags"); err != nil {
+							return err
+						}
+					}
+				}
+				v.Tags = s
+			default:
+				if err := d.Decode(&v.Tags, value, "tags"); err != nil {
+					return err
+				}
+			}
+		case "attrs":
+			if value != nil {
+				if err := d.Decode(&v.Attrs, value, "attrs"); err != nil {
+					return err
+				}
+			}
+		case "meta":
+			if value != nil {
+				if err := d.Decode(&v.Meta, value, "meta"); err != nil {
+					return err
+				}
+			}
+		case "parent":
+			if value != nil {
+				if err := d.Decode(&v.Parent, value, "parent"); err != nil {
+					return err
+				}
+			}
+		case "owner_id":
+			if value != nil {
+				if err := d.Decode(&v.Owner, value, "owner_id"); err != nil {
+					return err
+				}
+			}
+		case "author":
+			if value != nil {
+				if err := d.Decode(&v.Author, value, "author"); err != nil {
+					return err
+				}
+			}
+		case "editor":
+			if value != nil {
+				if err := d.Decode(&v.Editor, value, "editor"); err != nil {
+					return err
+				}
+			}
+		case "legacy":
+			switch value := value.(type) {
+			case nil:
+			case string:
+				v.Legacy = value
+			default:
+				if err := d.Decode(&v.Legacy, value, "legacy"); err != nil {
+					return err
+				}
+			}
+		case "Untagged":
+			switch value := value.(type) {
+			case nil:
+			case float64:
+				if err := encoding.DecodeInt(d, &v.Untagged, value, "Untagged"); err != nil {
+					return err
+				}
+			default:
+				if err := d.Decode(&v.Untagged, value, "Untagged"); err != nil {
+					return err
+				}
+			}
+		case "A":
+			switch value := value.(type) {
+			case nil:
+			case float64:
+				if err := encoding.DecodeInt(d, &v.A, value, "A"); err != nil {
+					return err
+				}
+			default:
+				if err := d.Decode(&v.A, value, "A"); err != nil {
+					return err
+				}
+			}
+		case "B":
+			switch value := value.(type) {
+			case nil:
+			case float64:
+				if err := encoding.DecodeInt(d, &v.B, value, "B"); err != nil {
+					return err
+				}
+			default:
+				if err := d.Decode(&v.B, value, "B"); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// MarshalRQL encodes the Compound as a map, in the same way as the reflection
+// based encoder.
+func (v *Compound) MarshalRQL() (interface{}, error) {
+	return v.MarshalRQLState(nil)
+}
+
+// MarshalRQLState encodes the Compound as a map using the codec of enc.
+func (v *Compound) MarshalRQLState(enc *encoding.EncodeState) (interface{}, error) {
+	m := make(map[string]interface{}, 5)
+	var compound0 []interface{}
+	var compound1 []interface{}
+	compound0 = encoding.CompoundField(compound0, 0, v.Partition)
+	if v.Sequence != 0 {
+		compound0 = encoding.CompoundField(compound0, 1, v.Sequence)
+	}
+	compound1 = encoding.CompoundField(compound1, 1, v.Tenant)
+	if !encoding.IsEmptyField(&v.Region) {
+		ev, err := enc.Encode(&v.Region)
+		if err != nil {
+			return nil, err
+		}
+		compound1 = encoding.CompoundField(compound1, 0, ev)
+	}
+	m["value"] = v.Value
+	if compound0 != nil {
+		m["id"] = compound0
+	}
+	if compound1 != nil {
+		m["key"] = compound1
+	}
+
+	return m, nil
+}
+
+// UnmarshalRQL decodes the Compound from a map, in the same way as the
+// reflection based decoder.
+func (v *Compound) UnmarshalRQL(data interface{}) error {
+	return v.UnmarshalRQLState(data, nil)
+}
+
+// UnmarshalRQLState decodes the Compound from a map using the codec and
+// options of d.
+func (v *Compound) UnmarshalRQLState(data interface{}, d *encoding.DecodeState) error {
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return d.DecodeReflect(v, data)
+	}
+
+	for key, value := range m {
+		exact := true
+		switch key {
+		case "id", "key", "value":
+		default:
+			exact = false
+			switch {
+			case strings.EqualFold(key, "id"):
+				if !encoding.FoldMatch(m, key, "id") {
+					continue
+				}
+				key = "id"
+			case strings.EqualFold(key, "key"):
+				if !encoding.FoldMatch(m, key, "key") {
+					continue
+				}
+				key = "key"
+			case strings.EqualFold(key, "value"):
+				if !encoding.FoldMatch(m, key, "value") {
+					continue
+				}
+				key = "value"
+			default:
+				if err := d.UnknownField(v, key); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		switch key {
+		case "id":
+			{
+				elem, err := encoding.CompoundElem(value, 0)
+				if err != nil {
+					return err
+				}
+				switch elem := elem.(type) {
+				case nil:
+				case string:
+					v.Partition = elem
+				default:
+					if err := d.Decode(&v.Partition, elem, "id[0]"); err != nil {
+						return err
+					}
+				}
+			}
+			if exact {
+				{
+					elem, err := encoding.CompoundElem(value, 1)
+					if err != nil {
+						return err
+					}
+					switch elem := elem.(type) {
+					case nil:
+					case float64:
+						if err := encoding.DecodeInt(d, &v.Sequence, elem, "id[1]"); err != nil {
+							return err
+						}
+					default:
+						if err := d.Decode(&v.Sequence, elem, "id[1]"); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		case "key":
+			{
+				elem, err := encoding.CompoundElem(value, 1)
+				if err != nil {
+					return err
+				}
+				switch elem := elem.(type) {
+				case nil:
+				case string:
+					v.Tenant = elem
+				default:
+					if err := d.Decode(&v.Tenant, elem, "key[1]"); err != nil {
+						return err
+					}
+				}
+			}
+			if exact {
+				{
+					elem, err := encoding.CompoundElem(value, 0)
+					if err != nil {
+						return err
+					}
+					if elem != nil {
+						if err := d.Decode(&v.Region, elem, "key[0]"); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		case "value":
+			switch value := value.(type) {
+			case nil:
+			case string:
+				v.Value = value
+			default:
+				if err := d.Decode(&v.Value, value, "value"); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// MarshalRQL encodes the Empty as a map, in the same way as the reflection
+// based encoder.
+func (v *Empty) MarshalRQL() (interface{}, error) {
+	return v.MarshalRQLState(nil)
+}
+
+// MarshalRQLState encodes the Empty as a map using the codec of enc.
+func (v *Empty) MarshalRQLState(enc *encoding.EncodeState) (interface{}, error) {
+	m := make(map[string]interface{}, 0)
+
+	return m, nil
+}
+
+// UnmarshalRQL decodes the Empty from a map, in the same way as the
+// reflection based decoder.
+func (v *Empty) UnmarshalRQL(data interface{}) error {
+	return v.UnmarshalRQLState(data, nil)
+}
+
+// UnmarshalRQLState decodes the Empty from a map using the codec and
+// options of d.
+func (v *Empty) UnmarshalRQLState(data interface{}, d *encoding.DecodeState) error {
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return d.DecodeReflect(v, data)
+	}
+
+	_ = m
+	return nil
+}
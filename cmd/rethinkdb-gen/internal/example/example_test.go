@@ -0,0 +1,357 @@
+package example
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
+)
+
+// The reflect types have the same fields but not the generated methods, so
+// they are encoded and decoded using reflection.
+type (
+	reflectDocument Document
+	reflectCompound Compound
+)
+
+func checkEncode(t *testing.T, generated, reflected interface{}) {
+	t.Helper()
+
+	genValue, genErr := encoding.Encode(generated)
+	refValue, refErr := encoding.Encode(reflected)
+	if (genErr == nil) != (refErr == nil) {
+		t.Fatalf("generated error %v, reflection error %v", genErr, refErr)
+	}
+	if genErr == nil && !reflect.DeepEqual(genValue, refValue) {
+		t.Fatalf("generated %#v\nreflection %#v", genValue, refValue)
+	}
+}
+
+func checkDecode(t *testing.T, data []byte, generated, reflected interface{}, convert func(interface{}) interface{}) {
+	t.Helper()
+
+	// The values are decoded without options and with the strict options
+	for _, opts := range []encoding.DecodeOpts{
+		{},
+		{DisallowUnknownFields: true, DisallowLossyConversion: true},
+	} {
+		var src interface{}
+		if err := json.Unmarshal(data, &src); err != nil || hasFoldedKeys(src) {
+			t.Skip()
+		}
+		// Each decoder may modify the source so decode a copy
+		var src2 interface{}
+		json.Unmarshal(data, &src2)
+
+		genErr := encoding.DecodeWithOpts(generated, src, opts)
+		refErr := encoding.DecodeWithOpts(reflected, src2, opts)
+		if (genErr == nil) != (refErr == nil) {
+			t.Fatalf("%+v: generated error %v, reflection error %v", opts, genErr, refErr)
+		}
+		if genErr == nil && !reflect.DeepEqual(reflect.ValueOf(generated).Elem().Interface(), convert(reflected)) {
+			t.Fatalf("%+v: generated %#v\nreflection %#v", opts, reflect.ValueOf(generated).Elem().Interface(), convert(reflected))
+		}
+	}
+}
+
+// hasFoldedKeys returns true if an object of v has keys which only differ by
+// case, which the reflection based decoder decodes in the order of the map.
+func hasFoldedKeys(v interface{}) bool {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := map[string]bool{}
+		for k, e := range v {
+			if keys[strings.ToLower(k)] || hasFoldedKeys(e) {
+				return true
+			}
+			keys[strings.ToLower(k)] = true
+		}
+	case []interface{}:
+		for _, e := range v {
+			if hasFoldedKeys(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func FuzzDocumentEncode(f *testing.F) {
+	f.Add("id", "name", 1, int8(-2), uint32(3), 1.5, true, "tag", int64(1375147296), []byte("data"), "owner", 0)
+	f.Add("", "", 0, int8(0), uint32(0), 0.0, false, "", int64(0), []byte(nil), "", 1)
+	f.Add("é", "\x00", -1, int8(127), uint32(1<<31), -1e300, false, "<>", int64(-1), []byte{}, "", 2)
+
+	f.Fuzz(func(t *testing.T, id, name string, count int, small int8, size uint32, score float64, active bool, tag string, created int64, data []byte, owner string, variant int) {
+		if score != score {
+			t.Skip()
+		}
+
+		doc := Document{
+			ID:       id,
+			Name:     name,
+			Count:    count,
+			Small:    small,
+			Size:     size,
+			Score:    score,
+			Ratio:    float32(score),
+			Active:   active,
+			Status:   Status(tag),
+			Data:     data,
+			Owner:    Owner{ID: owner, Name: name},
+			Legacy:   tag,
+			Untagged: count,
+			A:        int(small),
+			Dup1:     name,
+		}
+		if created != 0 {
+			doc.Created = time.Unix(created%1e10, 0).UTC()
+			doc.Updated = doc.Created.In(time.FixedZone("", 3600))
+			doc.Expires = doc.Created
+			doc.Limit = count
+		}
+		switch variant % 3 {
+		case 1:
+			doc.Tags = []string{tag, name}
+			doc.Attrs = map[string]interface{}{tag: count}
+			doc.Meta = []interface{}{id, score}
+			doc.Author = &Owner{ID: owner}
+			doc.Labels = []string{}
+			doc.Editor = Owner{Name: name}
+		case 2:
+			doc.Parent = &Document{ID: name, Tags: []string{}, Meta: map[string]interface{}{"a": active}}
+			doc.Meta = doc.Parent
+		}
+
+		checkEncode(t, &doc, (*reflectDocument)(&doc))
+
+		compound := Compound{Partition: id, Sequence: int64(count), Tenant: name, Region: Status(tag), Value: owner}
+		checkEncode(t, &compound, (*reflectCompound)(&compound))
+	})
+}
+
+func FuzzDocumentDecode(f *testing.F) {
+	for _, seed := range []string{
+		`{"id":"a","name":"b","count":1.9,"small":-3,"size":4,"score":1.5,"ratio":0.1,"active":true,"status":"ok"}`,
+		`{"count":-2.5,"small":300,"size":1e10,"untagged":1e300,"a":-0.5}`,
+		`{"created":{"$reql_type$":"TIME","epoch_time":1375147296.5,"timezone":"+01:00"},"data":{"$reql_type$":"BINARY","data":"AAEC"}}`,
+		`{"tags":["a",null],"attrs":{"x":1},"meta":{"k":[1,"2"]},"parent":{"id":"p","parent":null},"author":{"id":"o"}}`,
+		`{"ID":"upper","NAME":"fold","Count":"12","small":"x","legacy":true,"untagged":2,"a":1,"B":2,"dup":"d"}`,
+		`{"owner_id":"o","count":null,"name":null,"tags":null}`,
+		`{"count":{"a":1},"active":"true","size":-1,"score":"1e3","Ignored":"x","private":"y"}`,
+		`{"id":["p",2],"key":["r","t"],"value":"v"}`,
+		`{"ID":["p",2],"KEY":["r","t"],"Value":1}`,
+		`{"id":["p"],"key":"x"}`,
+		`{"id":null,"key":[null,null]}`,
+		`[1,2]`,
+		`"string"`,
+		`null`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var doc Document
+		var refDoc reflectDocument
+		checkDecode(t, data, &doc, &refDoc, func(v interface{}) interface{} {
+			return Document(*v.(*reflectDocument))
+		})
+
+		var compound Compound
+		var refCompound reflectCompound
+		checkDecode(t, data, &compound, &refCompound, func(v interface{}) interface{} {
+			return Compound(*v.(*reflectCompound))
+		})
+	})
+}
+
+func TestDocument_RoundTrip(t *testing.T) {
+	doc := Document{
+		ID:    "a",
+		Name:  "b",
+		Count: 3,
+		Tags:  []string{"x"},
+		Owner: Owner{ID: "o"},
+	}
+
+	encoded, err := encoding.Encode(&doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := encoded.(map[string]interface{})["owner_id"]; id != "o" {
+		t.Errorf("owner_id = %v, want o", id)
+	}
+
+	// Referenced fields cannot be decoded
+	delete(encoded.(map[string]interface{}), "owner_id")
+
+	var decoded Document
+	if err := encoding.Decode(&decoded, encoded); err != nil {
+		t.Fatal(err)
+	}
+	decoded.Owner = doc.Owner
+	if !reflect.DeepEqual(decoded, doc) {
+		t.Errorf("decoded %#v\nwant %#v", decoded, doc)
+	}
+}
+
+func TestDocument_DecodeLossy(t *testing.T) {
+	// Numbers are truncated in the same way as by the reflection based decoder
+	var doc Document
+	var refDoc reflectDocument
+	src := map[string]interface{}{"count": 1.5, "small": 300.0, "size": 1e10}
+	if err := encoding.Decode(&doc, src); err != nil {
+		t.Fatal(err)
+	}
+	if err := encoding.Decode(&refDoc, src); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Count != 1 || doc.Small != refDoc.Small || doc.Size != refDoc.Size {
+		t.Errorf("decoded %#v\nreflection %#v", doc, refDoc)
+	}
+
+	opts := encoding.DecodeOpts{DisallowLossyConversion: true}
+	for _, src := range []map[string]interface{}{
+		{"count": 1.5},
+		{"small": 300.0},
+		{"size": -1.0},
+		{"count": 1e300},
+	} {
+		var doc Document
+		err := encoding.DecodeWithOpts(&doc, src, opts)
+		if err, ok := err.(*encoding.LossyConversionError); !ok {
+			t.Errorf("%v: got error %v, want LossyConversionError", src, err)
+		} else if _, ok := src[err.Path]; !ok {
+			t.Errorf("%v: got path %q", src, err.Path)
+		}
+	}
+
+	if err := encoding.DecodeWithOpts(&doc, map[string]interface{}{"count": 2.0, "size": 3.0, "small": -4.0}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Count != 2 || doc.Size != 3 || doc.Small != -4 {
+		t.Errorf("decoded %#v", doc)
+	}
+}
+
+func TestDocument_DecodeExactKey(t *testing.T) {
+	// The exact key is decoded whichever key the map iterates first
+	for i := 0; i < 100; i++ {
+		var doc Document
+		src := map[string]interface{}{"NAME": "upper", "name": "exact", "Name": "title"}
+		if err := encoding.Decode(&doc, src); err != nil {
+			t.Fatal(err)
+		}
+		if doc.Name != "exact" {
+			t.Fatalf("name = %q, want exact", doc.Name)
+		}
+
+		src = map[string]interface{}{"Name": "title", "NAME": "upper"}
+		if err := encoding.Decode(&doc, src); err != nil {
+			t.Fatal(err)
+		}
+		if doc.Name != "upper" {
+			t.Fatalf("name = %q, want upper", doc.Name)
+		}
+	}
+}
+
+func TestDocument_EncodeCodec(t *testing.T) {
+	codec := encoding.NewCodec(encoding.CodecOpts{})
+	codec.SetTypeEncoding(reflect.TypeOf(Status("")),
+		func(value interface{}) (interface{}, error) {
+			return strings.ToUpper(string(value.(Status))), nil
+		},
+		func(encoded interface{}, value reflect.Value) error {
+			value.SetString(strings.ToLower(encoded.(string)))
+			return nil
+		},
+	)
+
+	doc := Document{Status: "active", Parent: &Document{Status: "archived"}}
+	genValue, err := codec.Encode(&doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refValue, err := codec.Encode((*reflectDocument)(&doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(genValue, refValue) {
+		t.Fatalf("generated %#v\nreflection %#v", genValue, refValue)
+	}
+	m := genValue.(map[string]interface{})
+	if m["status"] != "ACTIVE" || m["parent"].(map[string]interface{})["status"] != "ARCHIVED" {
+		t.Errorf("encoded %#v", m)
+	}
+}
+
+func TestDocument_DecodeOpts(t *testing.T) {
+	codec := encoding.NewCodec(encoding.CodecOpts{})
+	strict := encoding.DecodeOpts{DisallowUnknownFields: true, DisallowLossyConversion: true}
+
+	var doc Document
+	err := codec.DecodeWithOpts(&doc, map[string]interface{}{"id": "a", "unknown": 1.0}, strict)
+	if err, ok := err.(*encoding.UnknownFieldError); !ok || err.Path != "unknown" {
+		t.Errorf("got error %v, want UnknownFieldError for unknown", err)
+	}
+	err = codec.DecodeWithOpts(&doc, map[string]interface{}{"parent": map[string]interface{}{"Ignored": "x"}}, strict)
+	if err, ok := err.(*encoding.UnknownFieldError); !ok || err.Path != "parent.Ignored" {
+		t.Errorf("got error %v, want UnknownFieldError for parent.Ignored", err)
+	}
+	err = codec.DecodeWithOpts(&doc, map[string]interface{}{"status": 1.0}, strict)
+	if err, ok := err.(*encoding.LossyConversionError); !ok || err.Path != "status" {
+		t.Errorf("got error %v, want LossyConversionError for status", err)
+	}
+	// Maps of other types are decoded using reflection with the same options
+	err = codec.DecodeWithOpts(&doc, map[string]string{"unknown": "x"}, strict)
+	if err, ok := err.(*encoding.UnknownFieldError); !ok || err.Path != "unknown" {
+		t.Errorf("got error %v, want UnknownFieldError for unknown", err)
+	}
+	if err := codec.Decode(&doc, map[string]interface{}{"unknown": 1.0, "status": 1.0}); err != nil {
+		t.Errorf("got error %v without options", err)
+	}
+}
+
+func TestDocument_DecodeCodec(t *testing.T) {
+	codec := encoding.NewCodec(encoding.CodecOpts{})
+	codec.SetTypeEncoding(reflect.TypeOf(Status("")),
+		func(value interface{}) (interface{}, error) {
+			return value, nil
+		},
+		func(encoded interface{}, value reflect.Value) error {
+			value.SetString("custom " + encoded.(string))
+			return nil
+		},
+	)
+
+	var doc Document
+	if err := codec.Decode(&doc, map[string]interface{}{"status": "ok", "parent": map[string]interface{}{"status": "ok"}}); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Status != "custom ok" || doc.Parent.Status != "custom ok" {
+		t.Errorf("status = %q, parent status = %q", doc.Status, doc.Parent.Status)
+	}
+}
+
+func BenchmarkEncode_Generated(b *testing.B) {
+	doc := Document{ID: "a", Name: "b", Count: 3, Tags: []string{"x", "y"}, Owner: Owner{ID: "o"}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := encoding.Encode(&doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncode_Reflection(b *testing.B) {
+	doc := reflectDocument{ID: "a", Name: "b", Count: 3, Tags: []string{"x", "y"}, Owner: Owner{ID: "o"}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := encoding.Encode(&doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,860 @@
+// Command rethinkdb-gen generates MarshalRQL and UnmarshalRQL methods for
+// structs, so that values can be encoded and decoded without the reflection
+// based encoder having to look up the fields of the struct. The generated
+// methods honor the rethinkdb and rethinkdb_ref tags, including the
+// omitempty, omitzero and reference options and compound fields, in the same
+// way as the encoding package.
+//
+// Structs are selected either using the -type flag or by adding a
+// rethinkdb:generate comment to the type:
+//
+//	//go:generate go run gopkg.in/rethinkdb/rethinkdb-go.v6/cmd/rethinkdb-gen
+//
+//	// User is a user of the application.
+//	//
+//	//rethinkdb:generate
+//	type User struct {
+//		ID    string   `rethinkdb:"id,omitempty"`
+//		Name  string   `rethinkdb:"name"`
+//		Teams []string `rethinkdb:"teams"`
+//	}
+//
+// The methods use pointer receivers, values which are not addressable, such
+// as struct values stored in a map, are still encoded using reflection with
+// the same result. Fields of the predeclared boolean, numeric and string
+// types, and slices and string keyed maps of them, are encoded and decoded
+// directly, the values of other fields are encoded and decoded using the
+// encoding package. Embedded and inline fields are not supported and Merge
+// does not keep the existing values of fields nested inside a generated
+// struct.
+//
+// The MarshalRQLState and UnmarshalRQLState methods encode and decode the
+// document with the codec and encoding.DecodeOpts it is encoded or decoded
+// with, such as ConnectOpts.Codec, in the same way as the reflection based
+// encoder and decoder.
+//
+// The names of fields without a name in their tag are fixed when the methods
+// are generated, if encoding.NamingStrategy is set to encoding.SnakeCase the
+// -naming=snake_case flag must be used.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
+)
+
+const (
+	generateDirective = "rethinkdb:generate"
+	encodingPackage   = "gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of type names, by default types with a "+generateDirective+" comment")
+	output    = flag.String("output", "", "output file name, by default <package>_rethinkdb.go")
+	tags      = flag.String("tags", "", "comma-separated list of struct tags to read field names from, the same as encoding.Tags")
+	naming    = flag.String("naming", "", "naming strategy of fields without a name in their tag, snake_case or by default the Go field name")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("rethinkdb-gen: ")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	g := &generator{}
+	if *typeNames != "" {
+		g.types = strings.Split(*typeNames, ",")
+	}
+	if *tags != "" {
+		g.tags = strings.Split(*tags, ",")
+	}
+	switch *naming {
+	case "":
+	case "snake_case":
+		g.naming = encoding.SnakeCase
+	default:
+		log.Fatalf("unknown naming strategy %q", *naming)
+	}
+
+	src, pkg, err := g.generate(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	name := *output
+	if name == "" {
+		name = strings.ToLower(pkg) + "_rethinkdb.go"
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type generator struct {
+	// types are the names of the types to generate methods for, if empty the
+	// types with a rethinkdb:generate comment are used.
+	types []string
+	// tags are the struct tags field names are read from, if empty the
+	// default tags of the encoding package are used.
+	tags []string
+	// naming returns the names of fields without a name in their tag, the
+	// same as encoding.NamingStrategy.
+	naming func(name string) string
+
+	// declared contains the names of the types declared in the package.
+	declared    map[string]bool
+	buf         bytes.Buffer
+	usesStrings bool
+}
+
+// field is a field of a struct, found in the same way as the fields found by
+// the encoding package.
+type field struct {
+	goName        string
+	name          string
+	tagged        bool
+	index         int
+	kind          string
+	elem          string
+	omitEmpty     bool
+	omitZero      bool
+	reference     bool
+	refName       string
+	compound      bool
+	compoundIndex int
+}
+
+// generate returns the generated source for the package in dir and the name
+// of the package.
+func (g *generator) generate(dir string) ([]byte, string, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Find the structs in the order they are declared, ignoring any
+	// previously generated file
+	fset := token.NewFileSet()
+	var specs []*ast.TypeSpec
+	found := map[string]bool{}
+	g.declared = map[string]bool{}
+	for _, name := range bp.GoFiles {
+		if strings.HasSuffix(name, "_rethinkdb.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				g.declared[ts.Name.Name] = true
+				if _, ok := ts.Type.(*ast.StructType); !ok {
+					continue
+				}
+				if g.selected(ts, gd) {
+					specs = append(specs, ts)
+					found[ts.Name.Name] = true
+				}
+			}
+		}
+	}
+	for _, name := range g.types {
+		if !found[name] {
+			return nil, "", fmt.Errorf("struct type %s not found in %s", name, dir)
+		}
+	}
+	if len(specs) == 0 {
+		return nil, "", fmt.Errorf("no types to generate in %s", dir)
+	}
+
+	g.buf.Reset()
+	g.usesStrings = false
+	for _, ts := range specs {
+		if ts.TypeParams != nil && len(ts.TypeParams.List) > 0 {
+			return nil, "", fmt.Errorf("%s: generic types are not supported", ts.Name.Name)
+		}
+		fields, err := g.fields(ts.Type.(*ast.StructType))
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %v", ts.Name.Name, err)
+		}
+
+		g.marshal(ts.Name.Name, fields)
+		g.unmarshal(ts.Name.Name, fields)
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by rethinkdb-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", bp.Name)
+	if g.usesStrings {
+		fmt.Fprintf(&src, "import (\n\t\"strings\"\n\n\t%q\n)\n", encodingPackage)
+	} else {
+		fmt.Fprintf(&src, "import %q\n", encodingPackage)
+	}
+	src.Write(g.buf.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, "", fmt.Errorf("formatting generated code: %v", err)
+	}
+
+	return formatted, bp.Name, nil
+}
+
+func (g *generator) selected(ts *ast.TypeSpec, gd *ast.GenDecl) bool {
+	if len(g.types) > 0 {
+		for _, name := range g.types {
+			if name == ts.Name.Name {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, doc := range []*ast.CommentGroup{ts.Doc, gd.Doc} {
+		if doc == nil {
+			continue
+		}
+		for _, c := range doc.List {
+			if strings.TrimSpace(strings.TrimPrefix(c.Text, "//")) == generateDirective {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fields returns the fields of the struct, using the same rules as the
+// typeFields function of the encoding package.
+func (g *generator) fields(st *ast.StructType) ([]field, error) {
+	var fields []field
+
+	index := 0
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 {
+			return nil, fmt.Errorf("embedded field %s is not supported", exprString(f.Type))
+		}
+
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			s, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+			tag = reflect.StructTag(s)
+		}
+
+		for _, ident := range f.Names {
+			i := index
+			index++
+			if !ident.IsExported() {
+				continue
+			}
+
+			t := g.tag(tag)
+			if t == "-" {
+				continue
+			}
+			name, opts := parseTag(t)
+			name, compoundIndex, isCompound := parseCompoundIndex(name)
+			if !isValidTag(name) {
+				name = ""
+			}
+			ref, _ := parseTag(refTag(tag))
+			if !isValidTag(ref) {
+				ref = ""
+			}
+
+			if _, ok := f.Type.(*ast.MapType); ok && opts.contains("inline") {
+				return nil, fmt.Errorf("inline field %s is not supported", ident.Name)
+			}
+
+			tagged := name != ""
+			if name == "" {
+				name = ident.Name
+				if g.naming != nil {
+					name = g.naming(name)
+				}
+			}
+			kind, elem := g.fieldType(f.Type)
+			fields = append(fields, field{
+				goName:        ident.Name,
+				name:          name,
+				tagged:        tagged,
+				index:         i,
+				kind:          kind,
+				elem:          elem,
+				omitEmpty:     opts.contains("omitempty"),
+				omitZero:      opts.contains("omitzero"),
+				reference:     opts.contains("reference"),
+				refName:       ref,
+				compound:      isCompound,
+				compoundIndex: compoundIndex,
+			})
+		}
+	}
+
+	// Delete the fields with conflicting names
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].name != fields[j].name {
+			return fields[i].name < fields[j].name
+		}
+		if fields[i].tagged != fields[j].tagged {
+			return fields[i].tagged
+		}
+		return fields[i].index < fields[j].index
+	})
+
+	out := fields[:0]
+	for advance, i := 0, 0; i < len(fields); i += advance {
+		fi := fields[i]
+		for advance = 1; i+advance < len(fields); advance++ {
+			fj := fields[i+advance]
+			if fj.name != fi.name {
+				break
+			}
+			if fi.compound && fj.compound && fi.compoundIndex != fj.compoundIndex {
+				break
+			}
+		}
+		if advance == 1 {
+			out = append(out, fi)
+			continue
+		}
+
+		tagged := -1
+		for j, f := range fields[i : i+advance] {
+			if f.tagged {
+				if tagged >= 0 {
+					tagged = -2
+					break
+				}
+				tagged = j
+			}
+		}
+		if tagged >= 0 {
+			out = append(out, fields[i+tagged])
+		}
+	}
+
+	fields = out
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].index < fields[j].index
+	})
+
+	return fields, nil
+}
+
+func (g *generator) tag(tag reflect.StructTag) string {
+	if len(g.tags) == 0 {
+		if value := tag.Get("rethinkdb"); value != "" {
+			return value
+		}
+		return tag.Get("gorethink")
+	}
+
+	for _, name := range g.tags {
+		if value := tag.Get(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) marshal(typeName string, fields []field) {
+	g.printf("\n// MarshalRQL encodes the %s as a map, in the same way as the reflection\n", typeName)
+	g.printf("// based encoder.\n")
+	g.printf("func (v *%s) MarshalRQL() (interface{}, error) {\n", typeName)
+	g.printf("return v.MarshalRQLState(nil)\n}\n")
+
+	g.printf("\n// MarshalRQLState encodes the %s as a map using the codec of enc.\n", typeName)
+	g.printf("func (v *%s) MarshalRQLState(enc *encoding.EncodeState) (interface{}, error) {\n", typeName)
+	g.printf("m := make(map[string]interface{}, %d)\n", len(fields))
+
+	compounds := compoundVars(fields)
+	for _, f := range fields {
+		if v := compounds[f.name]; f.compound && v.first == f.index {
+			g.printf("var %s []interface{}\n", v.name)
+		}
+	}
+
+	for _, f := range fields {
+		x := "v." + f.goName
+		kind := f.kind
+		if f.reference {
+			// Only objects can be referenced
+			kind = ""
+		}
+
+		omit := f.omitEmpty || f.omitZero
+		switch {
+		case f.omitEmpty && f.omitZero && f.kind == "":
+			g.printf("if %s && %s {\n", notEmpty(f), notZero(f))
+		case f.omitEmpty:
+			g.printf("if %s {\n", notEmpty(f))
+		case f.omitZero:
+			g.printf("if %s {\n", notZero(f))
+		case kind != "basic":
+			g.printf("{\n")
+		}
+
+		value := "ev"
+		switch kind {
+		case "basic":
+			value = encodeBasic(f.elem, x)
+		case "slice":
+			g.printf("var ev interface{} = []interface{}(nil)\n")
+			g.printf("if %s != nil {\n", x)
+			g.printf("s := make([]interface{}, len(%s))\n", x)
+			g.printf("for i, e := range %s {\ns[i] = %s\n}\n", x, encodeBasic(f.elem, "e"))
+			g.printf("ev = s\n}\n")
+		case "map":
+			g.printf("var ev interface{}\n")
+			g.printf("if %s != nil {\n", x)
+			g.printf("mm := make(map[string]interface{}, len(%s))\n", x)
+			g.printf("for k, e := range %s {\nmm[k] = %s\n}\n", x, encodeBasic(f.elem, "e"))
+			g.printf("ev = mm\n}\n")
+		case "pointer", "interface":
+			g.printf("var ev interface{}\n")
+			g.printf("if %s != nil {\n", x)
+			g.printf("var err error\n")
+			g.printf("if ev, err = enc.Encode(&%s); err != nil {\nreturn nil, err\n}\n", x)
+			g.printf("}\n")
+		default:
+			g.printf("ev, err := enc.Encode(&%s)\n", x)
+			g.printf("if err != nil {\nreturn nil, err\n}\n")
+			if f.reference {
+				g.printf("ev, err = encoding.ReferenceField(v, ev, %q, %q)\n", f.name, refName(f))
+				g.printf("if err != nil {\nreturn nil, err\n}\n")
+			}
+		}
+
+		if f.compound {
+			v := compounds[f.name].name
+			g.printf("%s = encoding.CompoundField(%s, %d, %s)\n", v, v, f.compoundIndex, value)
+		} else {
+			g.printf("m[%q] = %s\n", f.name, value)
+		}
+
+		if omit || kind != "basic" {
+			g.printf("}\n")
+		}
+	}
+
+	// Compound fields are only set if at least one element is not omitted
+	for _, f := range fields {
+		if v := compounds[f.name]; f.compound && v.first == f.index {
+			g.printf("if %s != nil {\nm[%q] = %s\n}\n", v.name, f.name, v.name)
+		}
+	}
+
+	g.printf("\nreturn m, nil\n}\n")
+}
+
+func (g *generator) unmarshal(typeName string, fields []field) {
+	// Group the fields by name, keeping the order of the fields
+	var names []string
+	byName := map[string][]field{}
+	for _, f := range fields {
+		if _, ok := byName[f.name]; !ok {
+			names = append(names, f.name)
+		}
+		byName[f.name] = append(byName[f.name], f)
+	}
+
+	g.printf("\n// UnmarshalRQL decodes the %s from a map, in the same way as the\n", typeName)
+	g.printf("// reflection based decoder.\n")
+	g.printf("func (v *%s) UnmarshalRQL(data interface{}) error {\n", typeName)
+	g.printf("return v.UnmarshalRQLState(data, nil)\n}\n")
+
+	g.printf("\n// UnmarshalRQLState decodes the %s from a map using the codec and\n", typeName)
+	g.printf("// options of d.\n")
+	g.printf("func (v *%s) UnmarshalRQLState(data interface{}, d *encoding.DecodeState) error {\n", typeName)
+	g.printf("m, ok := data.(map[string]interface{})\n")
+	g.printf("if !ok {\nreturn d.DecodeReflect(v, data)\n}\n\n")
+	if len(fields) == 0 {
+		g.printf("_ = m\nreturn nil\n}\n")
+		return
+	}
+
+	// exact is only needed by compound fields with more than one element
+	needsExact := false
+	for _, name := range names {
+		if group := byName[name]; group[0].compound && len(group) > 1 {
+			needsExact = true
+		}
+	}
+
+	g.printf("for key, value := range m {\n")
+	if needsExact {
+		g.printf("exact := true\n")
+	}
+	g.printf("switch key {\n")
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.Quote(name)
+	}
+	g.printf("case %s:\n", strings.Join(quoted, ", "))
+	g.usesStrings = true
+	g.printf("default:\n")
+	if needsExact {
+		g.printf("exact = false\n")
+	}
+	g.printf("switch {\n")
+	// A key matching a field case insensitively is only decoded if no other
+	// key matches it first, in the same way as the reflection based decoder
+	for _, name := range names {
+		g.printf("case strings.EqualFold(key, %q):\n", name)
+		g.printf("if !encoding.FoldMatch(m, key, %q) {\ncontinue\n}\n", name)
+		g.printf("key = %q\n", name)
+	}
+	g.printf("default:\n")
+	g.printf("if err := d.UnknownField(v, key); err != nil {\nreturn err\n}\n")
+	g.printf("continue\n}\n}\n\n")
+
+	g.printf("switch key {\n")
+	for _, name := range names {
+		g.printf("case %q:\n", name)
+		group := byName[name]
+		if !group[0].compound {
+			g.decodeValue(group[0], "value", name)
+			continue
+		}
+
+		// Only the first field of a compound field is decoded if the key
+		// does not match exactly
+		for i, f := range group {
+			if i == 1 {
+				g.printf("if exact {\n")
+			}
+			g.printf("{\n")
+			g.printf("elem, err := encoding.CompoundElem(value, %d)\n", f.compoundIndex)
+			g.printf("if err != nil {\nreturn err\n}\n")
+			g.decodeValue(f, "elem", fmt.Sprintf("%s[%d]", name, f.compoundIndex))
+			g.printf("}\n")
+		}
+		if len(group) > 1 {
+			g.printf("}\n")
+		}
+	}
+	g.printf("}\n}\n\nreturn nil\n}\n")
+}
+
+// decodeValue writes the code decoding the variable src into the field, name
+// is the path of the field in the errors returned.
+func (g *generator) decodeValue(f field, src, name string) {
+	x := "v." + f.goName
+	switch f.kind {
+	case "basic":
+		g.decodeBasic(x, f.elem, src, name)
+	case "slice":
+		g.printf("switch %s := %s.(type) {\n", src, src)
+		g.printf("case nil:\n")
+		g.printf("case []interface{}:\n")
+		g.printf("s := make([]%s, len(%s))\n", f.elem, src)
+		g.printf("for i, e := range %s {\n", src)
+		g.decodeBasic("s[i]", f.elem, "e", name)
+		g.printf("}\n%s = s\n", x)
+		g.printf("default:\n")
+		g.printf("if err := d.Decode(&%s, %s, %q); err != nil {\nreturn err\n}\n", x, src, name)
+		g.printf("}\n")
+	case "map":
+		// The decoder sets the elements of a map which are null to the zero
+		// value
+		g.printf("switch %s := %s.(type) {\n", src, src)
+		g.printf("case nil:\n")
+		g.printf("case map[string]interface{}:\n")
+		g.printf("mm := make(map[string]%s, len(%s))\n", f.elem, src)
+		g.printf("for k, e := range %s {\n", src)
+		g.printf("var elem %s\n", f.elem)
+		g.decodeBasic("elem", f.elem, "e", name)
+		g.printf("mm[k] = elem\n}\n%s = mm\n", x)
+		g.printf("default:\n")
+		g.printf("if err := d.Decode(&%s, %s, %q); err != nil {\nreturn err\n}\n", x, src, name)
+		g.printf("}\n")
+	default:
+		g.printf("if %s != nil {\n", src)
+		g.printf("if err := d.Decode(&%s, %s, %q); err != nil {\nreturn err\n}\n", x, src, name)
+		g.printf("}\n")
+	}
+}
+
+// decodeBasic writes the code decoding the variable src into x, which has a
+// predeclared type. Values decoded from JSON are converted directly and
+// any other values are decoded using the codec of the DecodeState.
+func (g *generator) decodeBasic(x, typ, src, name string) {
+	g.printf("switch %s := %s.(type) {\n", src, src)
+	g.printf("case nil:\n")
+	switch basicKind(typ) {
+	case "bool":
+		g.printf("case bool:\n%s = %s\n", x, src)
+	case "string":
+		g.printf("case string:\n%s = %s\n", x, src)
+	case "int":
+		g.printf("case float64:\n")
+		g.printf("if err := encoding.DecodeInt(d, &%s, %s, %q); err != nil {\nreturn err\n}\n", x, src, name)
+	case "uint":
+		g.printf("case float64:\n")
+		g.printf("if err := encoding.DecodeUint(d, &%s, %s, %q); err != nil {\nreturn err\n}\n", x, src, name)
+	case "float":
+		g.printf("case float64:\n%s = %s\n", x, convertFloat(typ, src))
+	}
+	g.printf("default:\n")
+	g.printf("if err := d.Decode(&%s, %s, %q); err != nil {\nreturn err\n}\n", x, src, name)
+	g.printf("}\n")
+}
+
+type compoundVar struct {
+	name  string
+	first int
+}
+
+// compoundVars returns the variable holding each compound field while it is
+// encoded, and the index of the first field of the compound field.
+func compoundVars(fields []field) map[string]compoundVar {
+	vars := map[string]compoundVar{}
+	for _, f := range fields {
+		if _, ok := vars[f.name]; f.compound && !ok {
+			vars[f.name] = compoundVar{name: fmt.Sprintf("compound%d", len(vars)), first: f.index}
+		}
+	}
+	return vars
+}
+
+func refName(f field) string {
+	if f.refName != "" {
+		return f.refName
+	}
+	return f.name
+}
+
+// convertFloat returns the expression converting the float64 variable src to
+// the predeclared floating point type.
+func convertFloat(typ, src string) string {
+	if typ == "float64" {
+		return src
+	}
+	return fmt.Sprintf("%s(%s)", typ, src)
+}
+
+// encodeBasic returns the expression encoding x, which has a predeclared
+// type, with the same type as the value returned by the reflection based
+// encoder.
+func encodeBasic(typ, x string) string {
+	switch basicKind(typ) {
+	case "int":
+		if typ != "int64" {
+			return fmt.Sprintf("int64(%s)", x)
+		}
+	case "uint":
+		if typ != "uint64" {
+			return fmt.Sprintf("uint64(%s)", x)
+		}
+	}
+	return x
+}
+
+// notEmpty returns the condition checking the field is not empty.
+func notEmpty(f field) string {
+	x := "v." + f.goName
+	switch f.kind {
+	case "basic":
+		switch basicKind(f.elem) {
+		case "bool":
+			return x
+		case "string":
+			return fmt.Sprintf("%s != \"\"", x)
+		default:
+			return fmt.Sprintf("%s != 0", x)
+		}
+	case "slice", "map":
+		return fmt.Sprintf("len(%s) != 0", x)
+	case "pointer", "interface":
+		return fmt.Sprintf("%s != nil", x)
+	default:
+		return fmt.Sprintf("!encoding.IsEmptyField(&%s)", x)
+	}
+}
+
+// notZero returns the condition checking the field is not the zero value.
+func notZero(f field) string {
+	x := "v." + f.goName
+	switch f.kind {
+	case "basic":
+		return notEmpty(f)
+	case "slice", "map", "pointer", "interface":
+		return fmt.Sprintf("%s != nil", x)
+	default:
+		return fmt.Sprintf("!encoding.IsZeroField(&%s)", x)
+	}
+}
+
+var basicKinds = map[string]string{
+	"bool":    "bool",
+	"string":  "string",
+	"int":     "int",
+	"int8":    "int",
+	"int16":   "int",
+	"int32":   "int",
+	"int64":   "int",
+	"rune":    "int",
+	"uint":    "uint",
+	"uint8":   "uint",
+	"uint16":  "uint",
+	"uint32":  "uint",
+	"uint64":  "uint",
+	"uintptr": "uint",
+	"byte":    "uint",
+	"float32": "float",
+	"float64": "float",
+}
+
+// fieldType returns the kind of the type of a field, if it is a type the
+// generated code handles directly, and the name of the predeclared type of
+// its elements:
+//
+//   - basic: a predeclared boolean, numeric or string type
+//   - slice: a slice of a predeclared type, other than a byte slice
+//   - map: a map from strings to a predeclared type
+//   - pointer: a pointer
+//   - interface: an empty interface
+func (g *generator) fieldType(expr ast.Expr) (kind, elem string) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if t.Name == "any" && !g.declared[t.Name] {
+			return "interface", ""
+		}
+		if elem := g.basicType(t); elem != "" {
+			return "basic", elem
+		}
+	case *ast.ArrayType:
+		if elem := g.basicType(t.Elt); t.Len == nil && elem != "" && elem != "byte" && elem != "uint8" {
+			return "slice", elem
+		}
+	case *ast.MapType:
+		if elem := g.basicType(t.Value); g.basicType(t.Key) == "string" && elem != "" {
+			return "map", elem
+		}
+	case *ast.StarExpr:
+		return "pointer", ""
+	case *ast.InterfaceType:
+		if len(t.Methods.List) == 0 {
+			return "interface", ""
+		}
+	}
+	return "", ""
+}
+
+// basicType returns the name of the type if it is a predeclared boolean,
+// numeric or string type.
+func (g *generator) basicType(expr ast.Expr) string {
+	if ident, ok := expr.(*ast.Ident); ok && !g.declared[ident.Name] {
+		if _, ok := basicKinds[ident.Name]; ok {
+			return ident.Name
+		}
+	}
+	return ""
+}
+
+func basicKind(name string) string {
+	return basicKinds[name]
+}
+
+func exprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	format.Node(&buf, token.NewFileSet(), expr)
+	return buf.String()
+}
+
+func refTag(tag reflect.StructTag) string {
+	if value := tag.Get("rethinkdb_ref"); value != "" {
+		return value
+	}
+	return tag.Get("gorethink_ref")
+}
+
+// The following functions are the same as the functions used to parse tags
+// in the encoding package.
+
+type tagOptions string
+
+func parseTag(tag string) (string, tagOptions) {
+	if idx := strings.Index(tag, ","); idx != -1 {
+		return tag[:idx], tagOptions(tag[idx+1:])
+	}
+	return tag, tagOptions("")
+}
+
+func parseCompoundIndex(tag string) (string, int, bool) {
+	lIdx := strings.Index(tag, "[")
+	rIdx := strings.Index(tag, "]")
+	if lIdx > 1 && rIdx > lIdx+1 {
+		if elemIndex, err := strconv.ParseInt(tag[lIdx+1:rIdx], 10, 64); err == nil {
+			return tag[:lIdx], int(elemIndex), true
+		}
+	}
+
+	return tag, 0, false
+}
+
+func isValidTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:<=>?@[]^_{|}~ ", c):
+		default:
+			if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (o tagOptions) contains(optionName string) bool {
+	s := string(o)
+	for s != "" {
+		var next string
+		i := strings.Index(s, ",")
+		if i >= 0 {
+			s, next = s[:i], s[i+1:]
+		}
+		if s == optionName {
+			return true
+		}
+		s = next
+	}
+	return false
+}
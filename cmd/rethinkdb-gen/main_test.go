@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate_Example(t *testing.T) {
+	src, pkg, err := (&generator{}).generate("internal/example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg != "example" {
+		t.Errorf("got package %q, want %q", pkg, "example")
+	}
+
+	want, err := os.ReadFile(filepath.Join("internal", "example", "example_rethinkdb.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Error("internal/example/example_rethinkdb.go is out of date, run go generate")
+	}
+}
+
+// generateSource returns the error generating the methods for a package
+// containing src.
+func generateSource(t *testing.T, src string) error {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "types.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := (&generator{}).generate(dir)
+	return err
+}
+
+func TestGenerate_EmbeddedField(t *testing.T) {
+	err := generateSource(t, `package types
+
+type Base struct {
+	ID string
+}
+
+//rethinkdb:generate
+type User struct {
+	Base
+	Name string
+}
+`)
+	if err == nil || !strings.Contains(err.Error(), "embedded") {
+		t.Errorf("got error %v, want embedded field error", err)
+	}
+}
+
+func TestGenerate_InlineField(t *testing.T) {
+	err := generateSource(t, `package types
+
+//rethinkdb:generate
+type User struct {
+	Name  string
+	Extra map[string]interface{} `+"`rethinkdb:\",inline\"`"+`
+}
+`)
+	if err == nil || !strings.Contains(err.Error(), "inline") {
+		t.Errorf("got error %v, want inline field error", err)
+	}
+}
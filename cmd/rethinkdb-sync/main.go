@@ -0,0 +1,149 @@
+// Command rethinkdb-sync copies a RethinkDB table to another table, on the
+// same or another cluster, and keeps it up to date until interrupted.
+//
+// Copy the users table of the app database to another cluster, saving the
+// progress of the copy so an interrupted copy resumes where it stopped:
+//
+//	rethinkdb-sync -source old:28015 -target new:28015 -table app.users -checkpoint users.checkpoint
+//
+// The target table must already exist. The passwords are read from the
+// RETHINKDB_SOURCE_PASSWORD and RETHINKDB_TARGET_PASSWORD environment
+// variables if the -source-password and -target-password flags are not set.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/tablesync"
+)
+
+var errUsage = errors.New("invalid arguments")
+
+func main() {
+	log.SetFlags(log.LstdFlags)
+	log.SetPrefix("rethinkdb-sync: ")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stderr); err != nil {
+		switch err {
+		case flag.ErrHelp:
+			return
+		case errUsage:
+			os.Exit(2)
+		}
+		log.Fatal(err)
+	}
+}
+
+// clusterFlags are the flags used to connect to the source or the target.
+type clusterFlags struct {
+	addr     string
+	user     string
+	password string
+}
+
+func (c *clusterFlags) register(fs *flag.FlagSet, name string) {
+	env := "RETHINKDB_" + strings.ToUpper(name) + "_PASSWORD"
+	fs.StringVar(&c.addr, name, "localhost:28015", "address of the "+name+" server")
+	fs.StringVar(&c.user, name+"-user", "admin", "user name on the "+name+" server")
+	fs.StringVar(&c.password, name+"-password", os.Getenv(env), "password on the "+name+" server, by default $"+env)
+}
+
+func (c *clusterFlags) connect() (*r.Session, error) {
+	return r.Connect(r.ConnectOpts{
+		Address:  c.addr,
+		Username: c.user,
+		Password: c.password,
+	})
+}
+
+// parseTable parses a db.table name.
+func parseTable(name string) (r.Term, error) {
+	db, table, ok := strings.Cut(name, ".")
+	if !ok || db == "" || table == "" || strings.Contains(table, ".") {
+		return r.Term{}, fmt.Errorf("invalid table name %q, expected db.table", name)
+	}
+	return r.DB(db).Table(table), nil
+}
+
+func run(ctx context.Context, args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("rethinkdb-sync", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var source, target clusterFlags
+	source.register(fs, "source")
+	target.register(fs, "target")
+	table := fs.String("table", "", "db.table name of the source table")
+	targetTable := fs.String("target-table", "", "db.table name of the target table, by default the name of the source table")
+	conflict := fs.String("conflict", "replace", "conflict policy, replace, update, keep or error")
+	checkpoint := fs.String("checkpoint", "", "file the progress of the sync is saved in, by default the progress is not saved")
+	batchSize := fs.Int("batch-size", 200, "number of documents inserted by each query while copying the table")
+	prune := fs.Bool("prune", false, "delete the documents of the target table which are not in the source table")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() > 0 || *table == "" {
+		fs.Usage()
+		return errUsage
+	}
+	if *targetTable == "" {
+		*targetTable = *table
+	}
+
+	sourceTerm, err := parseTable(*table)
+	if err != nil {
+		return err
+	}
+	targetTerm, err := parseTable(*targetTable)
+	if err != nil {
+		return err
+	}
+
+	opts := tablesync.SyncOpts{
+		Conflict:  tablesync.ConflictPolicy(*conflict),
+		BatchSize: *batchSize,
+		Prune:     *prune,
+		OnReady: func(stats tablesync.Stats) {
+			log.Printf("copied %d documents, applying changes", stats.Copied)
+		},
+	}
+	if *checkpoint != "" {
+		opts.Checkpoint = tablesync.FileCheckpoint(*checkpoint)
+	}
+
+	sourceSession, err := source.connect()
+	if err != nil {
+		return err
+	}
+	defer sourceSession.Close()
+	targetSession, err := target.connect()
+	if err != nil {
+		return err
+	}
+	defer targetSession.Close()
+
+	stats, err := tablesync.Sync(ctx,
+		tablesync.Table{Session: sourceSession, Term: sourceTerm},
+		tablesync.Table{Session: targetSession, Term: targetTerm},
+		opts,
+	)
+	log.Printf("copied %d, inserted %d, updated %d, deleted %d, pruned %d documents, %d conflicts",
+		stats.Copied, stats.Inserted, stats.Updated, stats.Deleted, stats.Pruned, stats.Conflicts)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
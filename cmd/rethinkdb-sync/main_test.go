@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+func TestRun_Usage(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"-source", "old:28015"},
+		{"-table", "app.users", "extra"},
+		{"-unknown"},
+	} {
+		if err := run(context.Background(), args, io.Discard); err != errUsage {
+			t.Errorf("run(%q) got error %v, want %v", args, err, errUsage)
+		}
+	}
+}
+
+func TestParseTable(t *testing.T) {
+	if term, err := parseTable("app.users"); err != nil || term.String() != `r.DB("app").Table("users")` {
+		t.Errorf("got %v, %v, want r.DB(\"app\").Table(\"users\")", term, err)
+	}
+	for _, name := range []string{"users", "app.", ".users", "a.b.c"} {
+		if _, err := parseTable(name); err == nil {
+			t.Errorf("parseTable(%q) got nil, expected an error", name)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package rethinkdb
 
 import (
-	"crypto/tls"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -9,7 +8,6 @@ import (
 	"sync/atomic"
 	"time"
 
-	"bytes"
 	"context"
 	"sync"
 
@@ -82,20 +80,8 @@ type tokenAndPromise struct {
 
 // NewConnection creates a new connection to the database server
 func NewConnection(address string, opts *ConnectOpts) (*Connection, error) {
-	keepAlivePeriod := defaultKeepAlivePeriod
-	if opts.KeepAlivePeriod > 0 {
-		keepAlivePeriod = opts.KeepAlivePeriod
-	}
-
 	// Connect to Server
-	var err error
-	var conn net.Conn
-	nd := net.Dialer{Timeout: opts.Timeout, KeepAlive: keepAlivePeriod}
-	if opts.TLSConfig == nil {
-		conn, err = nd.Dial("tcp", address)
-	} else {
-		conn, err = tls.DialWithDialer(&nd, "tcp", address, opts.TLSConfig)
-	}
+	conn, err := dial(address, opts)
 	if err != nil {
 		return nil, RQLConnectionError{rqlError(err.Error())}
 	}
@@ -362,25 +348,14 @@ func (c *Connection) Server() (ServerResponse, error) {
 
 // sendQuery marshals the Query and sends the JSON to the server.
 func (c *Connection) sendQuery(q Query) error {
-	buf := &bytes.Buffer{}
-	buf.Grow(respHeaderLen)
-	buf.Write(buf.Bytes()[:respHeaderLen]) // reserve for header
-	enc := json.NewEncoder(buf)
-
-	// Build query
-	err := enc.Encode(q.Build())
+	buf, err := encodeQuery(q)
 	if err != nil {
 		return RQLDriverError{rqlError(fmt.Sprintf("Error building query: %s", err.Error()))}
 	}
-
-	b := buf.Bytes()
-
-	// Write header
-	binary.LittleEndian.PutUint64(b, uint64(q.Token))
-	binary.LittleEndian.PutUint32(b[8:], uint32(len(b)-respHeaderLen))
+	defer releaseQueryBuffer(buf)
 
 	// Send the JSON encoding of the query itself.
-	if err = c.writeData(b); err != nil {
+	if err = c.writeData(buf.Bytes()); err != nil {
 		c.setBad()
 		return RQLConnectionError{rqlError(err.Error())}
 	}
@@ -0,0 +1,79 @@
+package rethinkdb
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+// DialFunc is used to open the network connection to a server, it has the
+// same signature as net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// DialWithAddressMap returns a DialFunc which replaces any address found in
+// addresses with the mapped address before calling dial, addresses not found
+// in the map are dialed unchanged. If dial is nil a net.Dialer is used.
+//
+// This is useful when the addresses of the servers, including those reported
+// by the cluster when DiscoverHosts is enabled, are not reachable directly
+// by the application, for example when running behind NAT or in Kubernetes.
+//
+//	session, err := r.Connect(r.ConnectOpts{
+//		Address: "10.0.0.1:28015",
+//		Dialer: r.DialWithAddressMap(map[string]string{
+//			"10.0.0.1:28015": "rethinkdb-0.example.com:28015",
+//			"10.0.0.2:28015": "rethinkdb-1.example.com:28015",
+//		}, nil),
+//	})
+func DialWithAddressMap(addresses map[string]string, dial DialFunc) DialFunc {
+	if dial == nil {
+		dial = (&net.Dialer{KeepAlive: defaultKeepAlivePeriod}).DialContext
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if mapped, ok := addresses[address]; ok {
+			address = mapped
+		}
+
+		return dial(ctx, network, address)
+	}
+}
+
+// dial opens the network connection to the server at address using the
+// Dialer from the connection options if set and then performs the TLS
+// handshake if required.
+func dial(address string, opts *ConnectOpts) (net.Conn, error) {
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	dialer := opts.Dialer
+	if dialer == nil {
+		keepAlivePeriod := defaultKeepAlivePeriod
+		if opts.KeepAlivePeriod > 0 {
+			keepAlivePeriod = opts.KeepAlivePeriod
+		}
+
+		nd := &net.Dialer{Timeout: opts.Timeout, KeepAlive: keepAlivePeriod}
+		dialer = nd.DialContext
+	}
+
+	conn, err := dialer(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if opts.TLSConfig == nil {
+		return conn, nil
+	}
+
+	tlsConn := tls.Client(conn, tlsConfigForAddress(opts, address))
+	if err = tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
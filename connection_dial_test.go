@@ -0,0 +1,69 @@
+package rethinkdb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	test "gopkg.in/check.v1"
+)
+
+type DialSuite struct{}
+
+var _ = test.Suite(&DialSuite{})
+
+// servePipeHandshake accepts a v0.4 handshake on the server side of a pipe.
+func servePipeHandshake(conn net.Conn) {
+	// magic number, key length (empty key) and protocol type
+	req := make([]byte, 12)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return
+	}
+	_, _ = conn.Write([]byte("SUCCESS\x00"))
+}
+
+func (s *DialSuite) TestNewConnection_CustomDialer(c *test.C) {
+	var dialed []string
+	opts := &ConnectOpts{
+		HandshakeVersion: HandshakeV0_4,
+		Dialer: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialed = append(dialed, network+" "+address)
+
+			client, server := net.Pipe()
+			go servePipeHandshake(server)
+			return client, nil
+		},
+	}
+
+	conn, err := NewConnection("host1:28015", opts)
+	c.Assert(err, test.IsNil)
+	c.Assert(conn.Close(), test.IsNil)
+	c.Assert(dialed, test.DeepEquals, []string{"tcp host1:28015"})
+}
+
+func (s *DialSuite) TestNewConnection_CustomDialerError(c *test.C) {
+	opts := &ConnectOpts{
+		Dialer: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, errors.New("proxy unavailable")
+		},
+	}
+
+	_, err := NewConnection("host1:28015", opts)
+	c.Assert(err, test.FitsTypeOf, RQLConnectionError{})
+	c.Assert(err, test.ErrorMatches, "rethinkdb: proxy unavailable")
+}
+
+func (s *DialSuite) TestDialWithAddressMap(c *test.C) {
+	var dialed []string
+	dial := DialWithAddressMap(map[string]string{
+		"10.0.0.1:28015": "rethinkdb-0:28015",
+	}, func(ctx context.Context, network, address string) (net.Conn, error) {
+		dialed = append(dialed, address)
+		return nil, io.EOF
+	})
+
+	_, _ = dial(context.Background(), "tcp", "10.0.0.1:28015")
+	_, _ = dial(context.Background(), "tcp", "10.0.0.2:28015")
+	c.Assert(dialed, test.DeepEquals, []string{"rethinkdb-0:28015", "10.0.0.2:28015"})
+}
@@ -0,0 +1,233 @@
+package rethinkdb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSReloader loads a CA bundle and a client key pair from PEM files and
+// reloads them whenever the files change on disk, allowing certificates to
+// be rotated without restarting the application. The files are checked for
+// modifications each time a new TLS connection is established.
+type TLSReloader struct {
+	caFile   string
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	roots   *x509.CertPool
+	cert    *tls.Certificate
+	caMod   time.Time
+	certMod time.Time
+	lastErr error
+}
+
+// NewTLSReloader creates a TLSReloader for the given files and loads them
+// once, returning an error if any of them are invalid. caFile may be empty to
+// use the system root CAs, certFile and keyFile may both be empty if the server
+// does not require client certificates.
+func NewTLSReloader(caFile, certFile, keyFile string) (*TLSReloader, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("rethinkdb: both certificate and key files must be provided")
+	}
+
+	r := &TLSReloader{
+		caFile:   caFile,
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// TLSFromFiles builds a TLS configuration for mutual TLS from PEM encoded
+// files. The returned configuration presents the client certificate using
+// GetClientCertificate and verifies the server against the CA bundle using
+// VerifyConnection, both of which pick up any changes made to the files
+// since the last connection was established.
+//
+//	tlsConfig, err := r.TLSFromFiles("ca.pem", "client.pem", "client-key.pem")
+//	if err != nil {
+//		// error
+//	}
+//
+//	session, err := r.Connect(r.ConnectOpts{
+//		Address:   "localhost:28015",
+//		TLSConfig: tlsConfig,
+//	})
+func TLSFromFiles(caFile, certFile, keyFile string) (*tls.Config, error) {
+	r, err := NewTLSReloader(caFile, certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Config(), nil
+}
+
+// Config returns a new TLS configuration backed by the reloader.
+func (r *TLSReloader) Config() *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if r.certFile != "" {
+		config.GetClientCertificate = r.getClientCertificate
+	}
+	if r.caFile != "" {
+		// Certificate verification is done by VerifyConnection so that the
+		// root CAs can be swapped after the configuration has been created.
+		config.InsecureSkipVerify = true
+		config.VerifyConnection = r.verifyConnection
+	}
+
+	return config
+}
+
+// Reload reads the CA bundle and key pair from disk if they were modified
+// since they were last loaded. If the files are invalid the previously loaded
+// certificates are kept and the error is returned.
+func (r *TLSReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastErr = r.reloadLocked()
+
+	return r.lastErr
+}
+
+// Err returns the error encountered during the last reload, if any.
+func (r *TLSReloader) Err() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.lastErr
+}
+
+func (r *TLSReloader) reloadLocked() error {
+	if r.caFile != "" {
+		mod, err := fileModTime(r.caFile)
+		if err != nil {
+			return err
+		}
+		if !mod.Equal(r.caMod) {
+			pem, err := os.ReadFile(r.caFile)
+			if err != nil {
+				return fmt.Errorf("rethinkdb: error reading CA file: %w", err)
+			}
+			roots := x509.NewCertPool()
+			if !roots.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("rethinkdb: no valid certificates found in CA file %s", r.caFile)
+			}
+			r.roots = roots
+			r.caMod = mod
+		}
+	}
+
+	if r.certFile != "" {
+		certMod, err := fileModTime(r.certFile)
+		if err != nil {
+			return err
+		}
+		keyMod, err := fileModTime(r.keyFile)
+		if err != nil {
+			return err
+		}
+		if keyMod.After(certMod) {
+			certMod = keyMod
+		}
+		if !certMod.Equal(r.certMod) {
+			cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+			if err != nil {
+				return fmt.Errorf("rethinkdb: error loading key pair: %w", err)
+			}
+			r.cert = &cert
+			r.certMod = certMod
+		}
+	}
+
+	return nil
+}
+
+func (r *TLSReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	// Errors are kept available through Err, the last valid certificate is
+	// used until the files on disk are fixed.
+	_ = r.Reload()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.cert == nil {
+		return nil, r.lastErr
+	}
+
+	return r.cert, nil
+}
+
+func (r *TLSReloader) verifyConnection(cs tls.ConnectionState) error {
+	_ = r.Reload()
+
+	r.mu.RLock()
+	roots := r.roots
+	r.mu.RUnlock()
+
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("rethinkdb: server did not present a certificate")
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       cs.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+func fileModTime(name string) (time.Time, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("rethinkdb: error reading %s: %w", name, err)
+	}
+
+	return info.ModTime(), nil
+}
+
+// tlsConfigForAddress returns the TLS configuration used when dialing address.
+// If the connection options contain a TLSServerName function it is used to
+// pick the server name for the host, otherwise the server name is inferred
+// from the address when not set in the configuration.
+func tlsConfigForAddress(opts *ConnectOpts, address string) *tls.Config {
+	config := opts.TLSConfig
+	if opts.TLSServerName == nil && config.ServerName != "" {
+		return config
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+
+	config = config.Clone()
+	if opts.TLSServerName != nil {
+		if name := opts.TLSServerName(host); name != "" {
+			config.ServerName = name
+		}
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+
+	return config
+}
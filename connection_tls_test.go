@@ -0,0 +1,208 @@
+package rethinkdb
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	test "gopkg.in/check.v1"
+)
+
+type TLSSuite struct{}
+
+var _ = test.Suite(&TLSSuite{})
+
+type testCertAuthority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCertAuthority(c *test.C) *testCertAuthority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, test.IsNil)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	c.Assert(err, test.IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, test.IsNil)
+
+	return &testCertAuthority{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func (ca *testCertAuthority) issue(c *test.C, serial int64, name string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, test.IsNil)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	c.Assert(err, test.IsNil)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	c.Assert(err, test.IsNil)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeTestFile(c *test.C, name string, data []byte, mod time.Time) {
+	c.Assert(os.WriteFile(name, data, 0600), test.IsNil)
+	c.Assert(os.Chtimes(name, mod, mod), test.IsNil)
+}
+
+// startTLSServer accepts TLS connections requiring a client certificate and
+// sends the serial number of each client certificate received on the channel.
+func startTLSServer(c *test.C, ca *testCertAuthority, certPEM, keyPEM []byte) (net.Listener, <-chan int64) {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	c.Assert(err, test.IsNil)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	c.Assert(err, test.IsNil)
+
+	serials := make(chan int64, 4)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				close(serials)
+				return
+			}
+			tlsConn := conn.(*tls.Conn)
+			if err := tlsConn.Handshake(); err == nil {
+				serials <- tlsConn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	return l, serials
+}
+
+func (s *TLSSuite) TestTLSFromFiles_Reload(c *test.C) {
+	dir := c.MkDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+
+	ca := newTestCertAuthority(c)
+	serverCert, serverKey := ca.issue(c, 2, "rethinkdb.test", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(c, 3, "client", x509.ExtKeyUsageClientAuth)
+
+	mod := time.Now().Add(-time.Minute)
+	writeTestFile(c, caFile, ca.pem, mod)
+	writeTestFile(c, certFile, clientCert, mod)
+	writeTestFile(c, keyFile, clientKey, mod)
+
+	l, serials := startTLSServer(c, ca, serverCert, serverKey)
+	defer l.Close()
+
+	config, err := TLSFromFiles(caFile, certFile, keyFile)
+	c.Assert(err, test.IsNil)
+	config.ServerName = "rethinkdb.test"
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), config)
+	c.Assert(err, test.IsNil)
+	_ = conn.Close()
+	c.Assert(<-serials, test.Equals, int64(3))
+
+	// Rotate the client certificate, the next connection should use it
+	clientCert, clientKey = ca.issue(c, 4, "client", x509.ExtKeyUsageClientAuth)
+	writeTestFile(c, certFile, clientCert, mod.Add(time.Second))
+	writeTestFile(c, keyFile, clientKey, mod.Add(time.Second))
+
+	conn, err = tls.Dial("tcp", l.Addr().String(), config)
+	c.Assert(err, test.IsNil)
+	_ = conn.Close()
+	c.Assert(<-serials, test.Equals, int64(4))
+}
+
+func (s *TLSSuite) TestTLSFromFiles_VerifyServerName(c *test.C) {
+	dir := c.MkDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+
+	ca := newTestCertAuthority(c)
+	serverCert, serverKey := ca.issue(c, 2, "rethinkdb.test", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(c, 3, "client", x509.ExtKeyUsageClientAuth)
+
+	mod := time.Now()
+	writeTestFile(c, caFile, ca.pem, mod)
+	writeTestFile(c, certFile, clientCert, mod)
+	writeTestFile(c, keyFile, clientKey, mod)
+
+	l, _ := startTLSServer(c, ca, serverCert, serverKey)
+	defer l.Close()
+
+	config, err := TLSFromFiles(caFile, certFile, keyFile)
+	c.Assert(err, test.IsNil)
+	config.ServerName = "other.test"
+
+	_, err = tls.Dial("tcp", l.Addr().String(), config)
+	c.Assert(err, test.NotNil)
+}
+
+func (s *TLSSuite) TestTLSFromFiles_InvalidFiles(c *test.C) {
+	dir := c.MkDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	writeTestFile(c, caFile, []byte("not a certificate"), time.Now())
+
+	_, err := TLSFromFiles(caFile, "", "")
+	c.Assert(err, test.NotNil)
+
+	_, err = TLSFromFiles("", filepath.Join(dir, "client.pem"), "")
+	c.Assert(err, test.NotNil)
+}
+
+func (s *TLSSuite) TestTLSConfigForAddress(c *test.C) {
+	opts := &ConnectOpts{TLSConfig: &tls.Config{}}
+	c.Assert(tlsConfigForAddress(opts, "10.0.0.1:28015").ServerName, test.Equals, "10.0.0.1")
+
+	opts.TLSConfig.ServerName = "rethinkdb.test"
+	c.Assert(tlsConfigForAddress(opts, "10.0.0.1:28015"), test.Equals, opts.TLSConfig)
+
+	opts.TLSServerName = func(host string) string {
+		if host == "10.0.0.2" {
+			return "node2.rethinkdb.test"
+		}
+		return ""
+	}
+	c.Assert(tlsConfigForAddress(opts, "10.0.0.1:28015").ServerName, test.Equals, "rethinkdb.test")
+	c.Assert(tlsConfigForAddress(opts, "10.0.0.2:28015").ServerName, test.Equals, "node2.rethinkdb.test")
+	c.Assert(opts.TLSConfig.ServerName, test.Equals, "rethinkdb.test")
+}
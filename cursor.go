@@ -29,16 +29,23 @@ func newCursor(ctx context.Context, conn *Connection, cursorType string, token i
 		connOpts = conn.opts
 	}
 
+	decodeOpts, _ := opts["decode_opts"].(encoding.DecodeOpts)
+	prefetch, _ := opts["prefetch"].(int)
+	decodeWorkers, _ := opts["decode_workers"].(int)
+
 	cursor := &Cursor{
-		conn:       conn,
-		connOpts:   connOpts,
-		token:      token,
-		cursorType: cursorType,
-		term:       term,
-		opts:       opts,
-		buffer:     make([]interface{}, 0),
-		responses:  make([]json.RawMessage, 0),
-		ctx:        ctx,
+		conn:          conn,
+		connOpts:      connOpts,
+		token:         token,
+		cursorType:    cursorType,
+		term:          term,
+		opts:          opts,
+		decodeOpts:    decodeOpts,
+		prefetch:      prefetch,
+		decodeWorkers: decodeWorkers,
+		buffer:        make([]interface{}, 0),
+		responses:     make([]json.RawMessage, 0),
+		ctx:           ctx,
 	}
 
 	return cursor
@@ -61,6 +68,9 @@ func newCursor(ctx context.Context, conn *Connection, cursorType string, token i
 //	...
 type Cursor struct {
 	releaseConn func() error
+	// onDone are called once the query of the cursor is no longer running on
+	// the server, when the cursor is finished or closed.
+	onDone []func()
 
 	conn       *Connection
 	connOpts   *ConnectOpts
@@ -68,6 +78,7 @@ type Cursor struct {
 	cursorType string
 	term       *Term
 	opts       map[string]interface{}
+	decodeOpts encoding.DecodeOpts
 	ctx        context.Context
 
 	mu            sync.RWMutex
@@ -81,6 +92,33 @@ type Cursor struct {
 	buffer        []interface{}
 	responses     []json.RawMessage
 	profile       interface{}
+
+	// prefetch is the number of batches read ahead, batchSize is the number
+	// of rows in the last batch. prefetchDone is closed once the continue
+	// query sent in the background completes, prefetchErr is its error.
+	prefetch     int
+	batchSize    int
+	prefetchDone chan struct{}
+	prefetchErr  error
+
+	// decoded contains the documents decoded into values of decodedType by
+	// the decode workers, in the order of the responses.
+	decodeWorkers int
+	decoded       []decodedRow
+	decodedType   reflect.Type
+
+	// rows and bytes are the number of rows and bytes of the responses
+	// received, they are logged when the query is done.
+	rows  int
+	bytes int
+}
+
+// decodedRow is a document decoded by the decode workers, the response is
+// kept so the document can be decoded again into a value of another type.
+type decodedRow struct {
+	response json.RawMessage
+	value    reflect.Value
+	err      error
 }
 
 // Profile returns the information returned from the query profiler.
@@ -137,6 +175,7 @@ func (c *Cursor) Close() error {
 	if closed {
 		return nil
 	}
+	defer c.doneLocked()
 
 	// Get connection and check its valid, don't need to lock as this is only
 	// set when the cursor is created
@@ -167,6 +206,7 @@ func (c *Cursor) Close() error {
 	c.conn = nil
 	c.buffer = nil
 	c.responses = nil
+	c.decoded = nil
 
 	return err
 }
@@ -174,7 +214,7 @@ func (c *Cursor) Close() error {
 // Next retrieves the next document from the result set, blocking if necessary.
 // This method will also automatically retrieve another batch of documents from
 // the server when the current one is exhausted, or before that in background
-// if possible.
+// if prefetching is enabled (see SetPrefetch).
 //
 // Next returns true if a document was successfully unmarshalled onto result,
 // and false at the end of the result set or if an error happened.
@@ -188,13 +228,28 @@ func (c *Cursor) Next(dest interface{}) bool {
 		return false
 	}
 
+	return c.NextContext(c.ctx, dest)
+}
+
+// NextContext behaves like Next, but if the context is done while waiting
+// for the next batch of documents it returns false, the query is stopped and
+// Err returns ErrQueryTimeout. The context is used instead of the context the
+// query was run with.
+func (c *Cursor) NextContext(ctx context.Context, dest interface{}) bool {
+	if c == nil {
+		return false
+	}
+	if ctx == nil {
+		ctx = c.ctx
+	}
+
 	c.mu.Lock()
 	if c.closed {
 		c.mu.Unlock()
 		return false
 	}
 
-	hasMore, err := c.nextLocked(dest, true)
+	hasMore, err := c.nextLocked(ctx, dest, true)
 	if c.handleErrorLocked(err) != nil {
 		c.mu.Unlock()
 		c.Close()
@@ -209,9 +264,28 @@ func (c *Cursor) Next(dest interface{}) bool {
 	return hasMore
 }
 
-func (c *Cursor) nextLocked(dest interface{}, progressCursor bool) (bool, error) {
+func (c *Cursor) nextLocked(ctx context.Context, dest interface{}, progressCursor bool) (bool, error) {
+	if progressCursor && c.decodeWorkers > 1 && !c.isAtom && len(c.buffer) == 0 && isNonNilPointer(dest) {
+		return c.nextDecodedLocked(ctx, dest)
+	}
+	c.resetDecodedLocked()
+
+	if progressCursor && c.canDecodeJSON(dest) {
+		if err := c.seekCursor(ctx, false); err != nil {
+			return false, err
+		}
+
+		if !c.closed && len(c.buffer) == 0 && len(c.responses) > 0 && c.canDecodeNextResponse() {
+			if err := c.decodeNextResponse(dest); err != nil {
+				return false, err
+			}
+
+			return true, nil
+		}
+	}
+
 	for {
-		if err := c.seekCursor(true); err != nil {
+		if err := c.seekCursor(ctx, true); err != nil {
 			return false, err
 		}
 
@@ -228,7 +302,7 @@ func (c *Cursor) nextLocked(dest interface{}, progressCursor bool) (bool, error)
 			if progressCursor {
 				c.buffer = c.buffer[1:]
 			}
-			err := encoding.Decode(dest, data)
+			err := c.connOpts.codec().DecodeWithOpts(dest, data, c.decodeOpts)
 			if err != nil {
 				return false, err
 			}
@@ -238,6 +312,210 @@ func (c *Cursor) nextLocked(dest interface{}, progressCursor bool) (bool, error)
 	}
 }
 
+// isDecodeError returns true if the error was returned when decoding a row
+// into the destination value.
+func isDecodeError(err error) bool {
+	switch err.(type) {
+	case *encoding.DecodeTypeError, *encoding.UnknownFieldError, *encoding.LossyConversionError:
+		return true
+	default:
+		return false
+	}
+}
+
+// canDecodeJSON returns true if dest is a pointer to a struct which can be
+// decoded directly from the JSON of a response.
+func (c *Cursor) canDecodeJSON(dest interface{}) bool {
+	t := reflect.TypeOf(dest)
+	return t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct && c.connOpts.codec().CanDecodeJSON(t.Elem())
+}
+
+// canDecodeNextResponse returns true if the next response contains a single
+// document, atom responses containing an array are split into documents when
+// they are buffered.
+func (c *Cursor) canDecodeNextResponse() bool {
+	if !c.isAtom {
+		return true
+	}
+
+	response := bytes.TrimLeft(c.responses[0], " \t\r\n")
+	return len(response) > 0 && response[0] == '{'
+}
+
+// decodeNextResponse decodes the next response directly into dest, without
+// first unmarshaling it into an interface{} value.
+func (c *Cursor) decodeNextResponse(dest interface{}) error {
+	response := c.responses[0]
+	c.responses = c.responses[1:]
+	if c.isAtom {
+		c.isSingleValue = true
+	}
+
+	return c.decodeJSON(dest, response)
+}
+
+// decodeJSON decodes a document directly into dest.
+func (c *Cursor) decodeJSON(dest interface{}, response json.RawMessage) error {
+	return c.connOpts.codec().DecodeJSON(dest, response, encoding.JSONOpts{
+		DecodeOpts: c.decodeOpts,
+		UseNumber:  c.connOpts.UseJSONNumber,
+		ConvertPseudoTypes: func(v interface{}) (interface{}, error) {
+			return recursivelyConvertPseudotype(v, c.opts)
+		},
+	})
+}
+
+// nextDecodedLocked returns the next document decoded by the decode workers,
+// the buffered responses are decoded when there are no decoded documents.
+func (c *Cursor) nextDecodedLocked(ctx context.Context, dest interface{}) (bool, error) {
+	t := reflect.TypeOf(dest).Elem()
+	if c.decodedType != t {
+		c.resetDecodedLocked()
+		c.decodedType = t
+	}
+
+	for {
+		for c.pendingSkips > 0 && len(c.decoded) > 0 {
+			c.decoded = c.decoded[1:]
+			c.pendingSkips--
+		}
+		if len(c.decoded) > 0 {
+			break
+		}
+
+		if err := c.seekCursor(ctx, false); err != nil {
+			return false, err
+		}
+		if c.closed || len(c.responses) == 0 && c.finished {
+			return false, nil
+		}
+		c.decodeResponsesLocked(t)
+	}
+
+	row := c.decoded[0]
+	c.decoded = c.decoded[1:]
+	if row.err != nil {
+		return false, row.err
+	}
+	reflect.ValueOf(dest).Elem().Set(row.value)
+
+	return true, nil
+}
+
+// decodeResponsesLocked decodes the buffered responses into values of type t,
+// the documents are split between the decode workers and the order of the
+// responses is kept.
+func (c *Cursor) decodeResponsesLocked(t reflect.Type) {
+	rows := make([]decodedRow, len(c.responses))
+	for i, response := range c.responses {
+		rows[i].response = response
+	}
+	c.responses = nil
+
+	workers := c.decodeWorkers
+	if workers > len(rows) {
+		workers = len(rows)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(rows); i += workers {
+				v := reflect.New(t)
+				rows[i].err = c.decodeResponse(v.Interface(), rows[i].response)
+				rows[i].value = v.Elem()
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	c.decoded = append(c.decoded, rows...)
+}
+
+// resetDecodedLocked returns the documents decoded by the decode workers to
+// the buffered responses, so they are decoded again by the next call.
+func (c *Cursor) resetDecodedLocked() {
+	if len(c.decoded) == 0 {
+		return
+	}
+
+	responses := make([]json.RawMessage, 0, len(c.decoded)+len(c.responses))
+	for _, row := range c.decoded {
+		responses = append(responses, row.response)
+	}
+	c.responses = append(responses, c.responses...)
+	c.decoded = nil
+}
+
+// decodeResponse decodes a single document into dest.
+func (c *Cursor) decodeResponse(dest interface{}, response json.RawMessage) error {
+	if c.canDecodeJSON(dest) {
+		return c.decodeJSON(dest, response)
+	}
+
+	value, err := c.unmarshalResponse(response)
+	if err != nil {
+		return err
+	}
+
+	return c.connOpts.codec().DecodeWithOpts(dest, value, c.decodeOpts)
+}
+
+// isNonNilPointer returns true if v is a pointer which is not nil.
+func isNonNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && !rv.IsNil()
+}
+
+// SetDecodeOpts sets the options used to decode the remaining rows of the
+// cursor, such as rejecting rows with unknown fields. By default the
+// DecodeOpts of the RunOpts the query was run with are used.
+func (c *Cursor) SetDecodeOpts(opts encoding.DecodeOpts) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.decodeOpts = opts
+	c.resetDecodedLocked()
+	c.mu.Unlock()
+}
+
+// SetDecodeWorkers sets the number of goroutines used to decode each batch of
+// documents, the same as the DecodeWorkers run option. When n is greater than
+// one Next, and the methods using it such as All, decode the documents of a
+// batch in parallel into values of the type of the destination while still
+// returning them in order. This is useful when decoding large result sets is
+// limited by a single CPU.
+func (c *Cursor) SetDecodeWorkers(n int) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.decodeWorkers = n
+	c.mu.Unlock()
+}
+
+// SetPrefetch sets the number of batches of results read ahead of the rows
+// returned by the cursor, the same as the Prefetch run option. While fewer
+// than depth batches are buffered the next batch is requested in the
+// background, so reading a batch from the network overlaps with decoding the
+// previous one. At most one batch is requested at a time. If depth is zero
+// the next batch is only requested once the buffered rows have been read.
+func (c *Cursor) SetPrefetch(depth int) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.prefetch = depth
+	c.prefetchLocked()
+	c.mu.Unlock()
+}
+
 // Peek behaves similarly to Next, retrieving the next document from the result set
 // and blocking if necessary. Peek, however, does not progress the position of the cursor.
 // This can be useful for expressions which can return different types to attempt to
@@ -267,8 +545,8 @@ func (c *Cursor) Peek(dest interface{}) (bool, error) {
 		return false, nil
 	}
 
-	hasMore, err := c.nextLocked(dest, false)
-	if _, isDecodeErr := err.(*encoding.DecodeTypeError); isDecodeErr {
+	hasMore, err := c.nextLocked(c.ctx, dest, false)
+	if isDecodeError(err) {
 		c.mu.Unlock()
 		return false, err
 	}
@@ -302,6 +580,22 @@ func (c *Cursor) Skip() {
 // NextResponse returns false (and a nil byte slice) at the end of the result
 // set or if an error happened.
 func (c *Cursor) NextResponse() ([]byte, bool) {
+	return c.nextRaw(false)
+}
+
+// NextDocument retrieves the raw JSON of the next document in the result set,
+// blocking if necessary. It is the same as NextResponse except that when the
+// query returned a single array, for example a query using CoerceTo("array"),
+// the elements of the array are returned one at a time as they would be by
+// Next.
+//
+// NextDocument returns false (and a nil byte slice) at the end of the result
+// set or if an error happened.
+func (c *Cursor) NextDocument() ([]byte, bool) {
+	return c.nextRaw(true)
+}
+
+func (c *Cursor) nextRaw(splitAtom bool) ([]byte, bool) {
 	if c == nil {
 		return nil, false
 	}
@@ -312,7 +606,7 @@ func (c *Cursor) NextResponse() ([]byte, bool) {
 		return nil, false
 	}
 
-	b, hasMore, err := c.nextResponseLocked()
+	b, hasMore, err := c.nextResponseLocked(c.ctx, splitAtom)
 	if c.handleErrorLocked(err) != nil {
 		c.mu.Unlock()
 		c.Close()
@@ -327,9 +621,12 @@ func (c *Cursor) NextResponse() ([]byte, bool) {
 	return b, hasMore
 }
 
-func (c *Cursor) nextResponseLocked() ([]byte, bool, error) {
+// nextResponseLocked returns the next raw response, if splitAtom is true an
+// atom response containing an array is replaced by its elements.
+func (c *Cursor) nextResponseLocked(ctx context.Context, splitAtom bool) ([]byte, bool, error) {
+	c.resetDecodedLocked()
 	for {
-		if err := c.seekCursor(false); err != nil {
+		if err := c.seekCursor(ctx, false); err != nil {
 			return nil, false, err
 		}
 
@@ -341,6 +638,19 @@ func (c *Cursor) nextResponseLocked() ([]byte, bool, error) {
 			var response json.RawMessage
 			response, c.responses = c.responses[0], c.responses[1:]
 
+			if splitAtom && c.isAtom {
+				trimmed := bytes.TrimLeft(response, " \t\r\n")
+				if len(trimmed) > 0 && trimmed[0] == '[' {
+					var elems []json.RawMessage
+					if err := json.Unmarshal(response, &elems); err != nil {
+						return nil, false, err
+					}
+					c.responses = append(elems, c.responses...)
+					c.isAtom = false
+					continue
+				}
+			}
+
 			return []byte(response), true, nil
 		}
 	}
@@ -482,6 +792,9 @@ func (c *Cursor) Interface() (interface{}, error) {
 //	<- ch // 1
 //	<- ch // 2
 //	<- ch // 3
+//
+// Deprecated: Listen cannot be cancelled without closing the cursor and
+// errors are only returned by Err, use Stream instead.
 func (c *Cursor) Listen(channel interface{}) {
 	go func() {
 		channelv := reflect.ValueOf(channel)
@@ -502,6 +815,61 @@ func (c *Cursor) Listen(channel interface{}) {
 	}()
 }
 
+// StreamRow is a row of a result set sent by Stream, Err is set if the row
+// could not be read or decoded.
+type StreamRow[T any] struct {
+	Value T
+	Err   error
+}
+
+// Stream reads the rows of the cursor into values of type T and sends them
+// onto the returned channel, which is closed once every row has been sent.
+// If an error happens it is sent as the last row of the channel.
+//
+// When the context is done the channel is closed without sending any more
+// rows and the cursor is closed, stopping the query. The cursor is always
+// closed once the stream ends.
+//
+//	cursor, err := r.Table("users").Changes().Run(session)
+//	if err != nil {
+//	    return err
+//	}
+//
+//	for row := range r.Stream[User](ctx, cursor) {
+//	    if row.Err != nil {
+//	        return row.Err
+//	    }
+//	    ...
+//	}
+func Stream[T any](ctx context.Context, c *Cursor) <-chan StreamRow[T] {
+	ch := make(chan StreamRow[T])
+	go func() {
+		defer close(ch)
+		defer c.Close()
+
+		for {
+			var row StreamRow[T]
+			if !c.NextContext(ctx, &row.Value) {
+				row.Err = c.Err()
+				if row.Err == nil || ctx.Err() != nil {
+					return
+				}
+			}
+
+			select {
+			case ch <- row:
+			case <-ctx.Done():
+				return
+			}
+			if row.Err != nil {
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
 // IsNil tests if the current row is nil.
 func (c *Cursor) IsNil() bool {
 	if c == nil {
@@ -515,8 +883,13 @@ func (c *Cursor) IsNil() bool {
 		return c.buffer[0] == nil
 	}
 
-	if len(c.responses) > 0 {
-		response := c.responses[0]
+	responses := c.responses
+	if len(c.decoded) > 0 {
+		responses = []json.RawMessage{c.decoded[0].response}
+	}
+
+	if len(responses) > 0 {
+		response := responses[0]
 		if response == nil {
 			return true
 		}
@@ -535,9 +908,30 @@ func (c *Cursor) IsNil() bool {
 //
 // If wait is true then it will wait for the database to reply otherwise it
 // will return after sending the continue query.
-func (c *Cursor) fetchMore() error {
+func (c *Cursor) fetchMore(ctx context.Context) error {
 	var err error
 
+	// Wait for the continue query sent in the background
+	if done := c.prefetchDone; done != nil {
+		c.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			err = ErrQueryTimeout
+		}
+		c.mu.Lock()
+		if err != nil {
+			return err
+		}
+		// The batch received in the background may be all that was needed
+		if c.prefetchErr == nil && (len(c.responses) > 0 || c.finished) {
+			return nil
+		}
+	}
+	if c.prefetchErr != nil {
+		return c.prefetchErr
+	}
+
 	if !c.fetching {
 		c.fetching = true
 
@@ -551,7 +945,7 @@ func (c *Cursor) fetchMore() error {
 		}
 
 		c.mu.Unlock()
-		_, _, err = c.conn.Query(c.ctx, q)
+		_, _, err = c.conn.Query(ctx, q)
 		c.mu.Lock()
 	}
 
@@ -584,15 +978,85 @@ func (c *Cursor) extend(response *Response) {
 
 func (c *Cursor) extendLocked(response *Response) {
 	c.responses = append(c.responses, response.Responses...)
+	c.rows += len(response.Responses)
+	for _, r := range response.Responses {
+		c.bytes += len(r)
+	}
 	c.finished = response.Type != p.Response_SUCCESS_PARTIAL
 	c.fetching = false
 	c.isAtom = response.Type == p.Response_SUCCESS_ATOM
+	if len(response.Responses) > 0 {
+		c.batchSize = len(response.Responses)
+	}
+	if c.finished {
+		c.doneLocked()
+	}
+
+	c.prefetchLocked()
+}
+
+// addOnDone adds a function called with the cursor locked once the query of
+// the cursor is no longer running, it is called immediately if the cursor is
+// already finished.
+func (c *Cursor) addOnDone(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.finished || c.closed {
+		f()
+		return
+	}
+	c.onDone = append(c.onDone, f)
+}
+
+func (c *Cursor) doneLocked() {
+	onDone := c.onDone
+	c.onDone = nil
+	for _, f := range onDone {
+		f()
+	}
+}
+
+// prefetchLocked requests the next batch in the background if fewer than the
+// prefetch depth batches are buffered, the response is added to the cursor by
+// extend.
+func (c *Cursor) prefetchLocked() {
+	if c.prefetch <= 0 || c.fetching || c.finished || c.closed || c.conn == nil || c.prefetchErr != nil {
+		return
+	}
+	if len(c.buffer)+len(c.responses) >= c.prefetch*c.batchSize {
+		return
+	}
+
+	c.fetching = true
+	done := make(chan struct{})
+	c.prefetchDone = done
+
+	conn := c.conn
+	q := Query{
+		Type:  p.Query_CONTINUE,
+		Token: c.token,
+	}
+	go func() {
+		_, _, err := conn.Query(c.ctx, q)
+
+		c.mu.Lock()
+		if err != nil {
+			c.fetching = false
+			c.prefetchErr = err
+		}
+		if c.prefetchDone == done {
+			c.prefetchDone = nil
+		}
+		c.mu.Unlock()
+		close(done)
+	}()
 }
 
 // seekCursor takes care of loading more data if needed and applying pending skips
 //
 // bufferResponse determines whether the response will be parsed into the buffer
-func (c *Cursor) seekCursor(bufferResponse bool) error {
+func (c *Cursor) seekCursor(ctx context.Context, bufferResponse bool) error {
 	if c.lastErr != nil {
 		return c.lastErr
 	}
@@ -614,7 +1078,7 @@ func (c *Cursor) seekCursor(bufferResponse bool) error {
 			continue // go around the loop again to re-apply pending skips
 		} else if len(c.buffer) == 0 && len(c.responses) == 0 && !c.finished {
 			//  We skipped all of our data, load some more
-			if err := c.fetchMore(); err != nil {
+			if err := c.fetchMore(ctx); err != nil {
 				return err
 			}
 			if c.closed {
@@ -622,6 +1086,8 @@ func (c *Cursor) seekCursor(bufferResponse bool) error {
 			}
 			continue // go around the loop again to re-apply pending skips
 		}
+
+		c.prefetchLocked()
 		return nil
 	}
 }
@@ -674,17 +1140,7 @@ func (c *Cursor) bufferNextResponse() error {
 	response := c.responses[0]
 	c.responses = c.responses[1:]
 
-	var value interface{}
-	decoder := json.NewDecoder(bytes.NewBuffer(response))
-	if c.connOpts.UseJSONNumber {
-		decoder.UseNumber()
-	}
-	err := decoder.Decode(&value)
-	if err != nil {
-		return err
-	}
-
-	value, err = recursivelyConvertPseudotype(value, c.opts)
+	value, err := c.unmarshalResponse(response)
 	if err != nil {
 		return err
 	}
@@ -705,3 +1161,19 @@ func (c *Cursor) bufferNextResponse() error {
 	}
 	return nil
 }
+
+// unmarshalResponse unmarshals a response into an interface{} value and
+// converts any pseudo-types.
+func (c *Cursor) unmarshalResponse(response json.RawMessage) (interface{}, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewBuffer(response))
+	if c.connOpts.UseJSONNumber {
+		decoder.UseNumber()
+	}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+
+	return recursivelyConvertPseudotype(value, c.opts)
+}
@@ -1,8 +1,18 @@
 package rethinkdb
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
 	test "gopkg.in/check.v1"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
 	"gopkg.in/rethinkdb/rethinkdb-go.v6/internal/integration/tests"
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
 type CursorSuite struct{}
@@ -32,3 +42,384 @@ func (s *CursorSuite) TestCursor_One_Ok(c *test.C) {
 	c.Assert(response, tests.JsonEquals, data)
 	mock.AssertExpectations(c)
 }
+
+func (s *CursorSuite) TestCursor_Next_Struct(c *test.C) {
+	type row struct {
+		ID      string                 `rethinkdb:"id"`
+		Count   int                    `rethinkdb:"count"`
+		Created time.Time              `rethinkdb:"created"`
+		Raw     map[string]interface{} `rethinkdb:"raw"`
+	}
+
+	created := map[string]interface{}{"$reql_type$": "TIME", "epoch_time": 1375147296.5, "timezone": "+00:00"}
+	rows := []interface{}{
+		map[string]interface{}{"id": "a", "count": 1, "created": created},
+		map[string]interface{}{"id": "b", "count": 2, "raw": map[string]interface{}{"created": created}},
+	}
+
+	mock := NewMock()
+	mock.On(DB("test").Table("test")).Return(rows, nil)
+	res, err := DB("test").Table("test").Run(mock)
+	c.Assert(err, test.IsNil)
+
+	var r row
+	c.Assert(res.Next(&r), test.Equals, true)
+	c.Assert(r.ID, test.Equals, "a")
+	c.Assert(r.Created.Equal(time.Unix(1375147296, 5e8)), test.Equals, true)
+
+	c.Assert(res.Next(&r), test.Equals, true)
+	c.Assert(r.Count, test.Equals, 2)
+	c.Assert(r.Created.IsZero(), test.Equals, true)
+	c.Assert(r.Raw["created"], test.FitsTypeOf, time.Time{})
+
+	c.Assert(res.Next(&r), test.Equals, false)
+	c.Assert(res.Err(), test.IsNil)
+	mock.AssertExpectations(c)
+}
+
+func (s *CursorSuite) TestCursor_Next_DecodeOpts(c *test.C) {
+	type row struct {
+		ID    string `rethinkdb:"id"`
+		Count int    `rethinkdb:"count"`
+	}
+
+	rows := []interface{}{
+		map[string]interface{}{"id": "a", "count": 1},
+		map[string]interface{}{"id": "b", "count": 2.5},
+		map[string]interface{}{"id": "c", "extra": true},
+	}
+
+	mock := NewMock()
+	mock.On(DB("test").Table("test")).Return(rows, nil)
+	res, err := DB("test").Table("test").Run(mock, RunOpts{
+		DecodeOpts: encoding.DecodeOpts{DisallowLossyConversion: true},
+	})
+	c.Assert(err, test.IsNil)
+
+	var r row
+	c.Assert(res.Next(&r), test.Equals, true)
+	c.Assert(r.Count, test.Equals, 1)
+
+	// Peek does not keep decoding errors
+	ok, err := res.Peek(&r)
+	c.Assert(ok, test.Equals, false)
+	c.Assert(err, test.FitsTypeOf, &encoding.LossyConversionError{})
+	c.Assert(err.(*encoding.LossyConversionError).Path, test.Equals, "count")
+
+	res.SetDecodeOpts(encoding.DecodeOpts{DisallowUnknownFields: true})
+	c.Assert(res.Next(&r), test.Equals, true)
+	c.Assert(r.Count, test.Equals, 2)
+
+	c.Assert(res.Next(&r), test.Equals, false)
+	c.Assert(res.Err(), test.ErrorMatches, `rethinkdb: unknown field "extra" .*`)
+	mock.AssertExpectations(c)
+}
+
+func (s *CursorSuite) TestCursor_Next_Prefetch(c *test.C) {
+	mock := NewMock()
+	ch := make(chan []interface{})
+	mock.On(DB("test").Table("test")).Return(ch, nil)
+	go func() {
+		ch <- []interface{}{1, 2}
+		ch <- []interface{}{3, 4}
+		ch <- []interface{}{5}
+		close(ch)
+	}()
+	res, err := DB("test").Table("test").Run(mock, RunOpts{Prefetch: 2})
+	c.Assert(err, test.IsNil)
+
+	// The second batch is read before any rows, no more batches are requested
+	// once two batches are buffered
+	buffered := func() (int, bool) {
+		res.mu.RLock()
+		defer res.mu.RUnlock()
+		return len(res.buffer) + len(res.responses), res.fetching
+	}
+	for i := 0; i < 1000; i++ {
+		if n, _ := buffered(); n == 4 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	n, fetching := buffered()
+	c.Assert(n, test.Equals, 4)
+	c.Assert(fetching, test.Equals, false)
+
+	var rows []int
+	c.Assert(res.All(&rows), test.IsNil)
+	c.Assert(rows, test.DeepEquals, []int{1, 2, 3, 4, 5})
+	mock.AssertExpectations(c)
+}
+
+func (s *CursorSuite) TestCursor_Next_PrefetchContinues(c *test.C) {
+	release := make(chan struct{})
+	var calls int32
+	mock := NewMock()
+	mock.On(DB("test").Table("test")).Return(func() []interface{} {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			return []interface{}{1, 2}
+		case 2:
+			// The prefetched batch arrives while the rows are read
+			time.Sleep(50 * time.Millisecond)
+			return []interface{}{3}
+		default:
+			<-release
+			return nil
+		}
+	}, nil)
+	res, err := DB("test").Table("test").Run(mock, RunOpts{Prefetch: 1})
+	c.Assert(err, test.IsNil)
+	conn := res.conn.Conn.(*mockConn)
+
+	// The row of the prefetched batch is read without sending another
+	// continue query
+	var row int
+	for _, want := range []int{1, 2} {
+		c.Assert(res.Next(&row), test.Equals, true)
+		c.Assert(row, test.Equals, want)
+	}
+	next := make(chan bool)
+	go func() {
+		next <- res.Next(&row)
+	}()
+	select {
+	case ok := <-next:
+		c.Assert(ok, test.Equals, true)
+		c.Assert(row, test.Equals, 3)
+	case <-time.After(time.Second):
+		close(release)
+		c.Fatal("waited for the response to another continue query")
+	}
+	c.Assert(conn.queries.Load(), test.Equals, int32(2))
+
+	close(release)
+	c.Assert(res.Next(&row), test.Equals, false)
+	c.Assert(res.Err(), test.IsNil)
+	c.Assert(conn.queries.Load(), test.Equals, int32(3))
+	mock.AssertExpectations(c)
+}
+
+func (s *CursorSuite) TestCursor_NextContext_Cancel(c *test.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mock := NewMock()
+	ch := make(chan []interface{})
+	mock.On(DB("test").Table("test")).Return(ch, nil)
+	go func() {
+		ch <- []interface{}{1}
+		// Respond to the continue and stop queries once the context is done
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		close(ch)
+	}()
+	res, err := DB("test").Table("test").Run(mock)
+	c.Assert(err, test.IsNil)
+
+	var v int
+	c.Assert(res.NextContext(ctx, &v), test.Equals, true)
+	c.Assert(v, test.Equals, 1)
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	c.Assert(res.NextContext(ctx, &v), test.Equals, false)
+	c.Assert(res.Err(), test.Equals, ErrQueryTimeout)
+	mock.AssertExpectations(c)
+}
+
+func (s *CursorSuite) TestCursor_NextDocument(c *test.C) {
+	mock := NewMock()
+	mock.On(DB("test").Table("test")).Return([]interface{}{
+		map[string]interface{}{"id": 1},
+		[]interface{}{1, 2},
+	}, nil)
+	res, err := DB("test").Table("test").Run(mock)
+	c.Assert(err, test.IsNil)
+
+	var docs []string
+	for {
+		b, ok := res.NextDocument()
+		if !ok {
+			break
+		}
+		docs = append(docs, string(b))
+	}
+	c.Assert(docs, test.DeepEquals, []string{`{"id":1}`, `[1,2]`})
+	c.Assert(res.Err(), test.IsNil)
+	mock.AssertExpectations(c)
+}
+
+func (s *CursorSuite) TestCursor_NextDocument_Atom(c *test.C) {
+	res := newCursor(context.Background(), nil, "", 0, nil, nil)
+	res.extend(&Response{
+		Type:      p.Response_SUCCESS_ATOM,
+		Responses: []json.RawMessage{json.RawMessage(`[{"id": 1}, {"id": 2}]`)},
+	})
+
+	b, ok := res.NextDocument()
+	c.Assert(ok, test.Equals, true)
+	c.Assert(string(b), test.Equals, `{"id": 1}`)
+	b, ok = res.NextDocument()
+	c.Assert(ok, test.Equals, true)
+	c.Assert(string(b), test.Equals, `{"id": 2}`)
+	_, ok = res.NextDocument()
+	c.Assert(ok, test.Equals, false)
+	c.Assert(res.Err(), test.IsNil)
+}
+
+func (s *CursorSuite) TestCursor_Stream(c *test.C) {
+	mock := NewMock()
+	mock.On(DB("test").Table("test")).Return([]interface{}{1, 2, 3}, nil)
+	res, err := DB("test").Table("test").Run(mock)
+	c.Assert(err, test.IsNil)
+
+	var values []int
+	for row := range Stream[int](context.Background(), res) {
+		c.Assert(row.Err, test.IsNil)
+		values = append(values, row.Value)
+	}
+	c.Assert(values, test.DeepEquals, []int{1, 2, 3})
+	c.Assert(res.Err(), test.IsNil)
+	mock.AssertExpectations(c)
+}
+
+func (s *CursorSuite) TestCursor_Stream_Error(c *test.C) {
+	mock := NewMock()
+	mock.On(DB("test").Table("test")).Return([]interface{}{1, "a", 3}, nil)
+	res, err := DB("test").Table("test").Run(mock)
+	c.Assert(err, test.IsNil)
+
+	var rows []StreamRow[int]
+	for row := range Stream[int](context.Background(), res) {
+		rows = append(rows, row)
+	}
+	c.Assert(rows, test.HasLen, 2)
+	c.Assert(rows[0], test.DeepEquals, StreamRow[int]{Value: 1})
+	c.Assert(rows[1].Err, test.FitsTypeOf, &encoding.DecodeTypeError{})
+	mock.AssertExpectations(c)
+}
+
+func (s *CursorSuite) TestCursor_Stream_Cancel(c *test.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mock := NewMock()
+	ch := make(chan []interface{})
+	mock.On(DB("test").Table("test")).Return(ch, nil)
+	go func() {
+		ch <- []interface{}{1, 2}
+		close(ch)
+	}()
+	res, err := DB("test").Table("test").Run(mock)
+	c.Assert(err, test.IsNil)
+
+	stream := Stream[int](ctx, res)
+	row := <-stream
+	c.Assert(row, test.DeepEquals, StreamRow[int]{Value: 1})
+
+	cancel()
+	for range stream {
+	}
+	res.mu.RLock()
+	closed := res.closed
+	res.mu.RUnlock()
+	c.Assert(closed, test.Equals, true)
+}
+
+func (s *CursorSuite) TestCursor_Next_DecodeWorkers(c *test.C) {
+	type row struct {
+		ID      int       `rethinkdb:"id"`
+		Created time.Time `rethinkdb:"created"`
+	}
+
+	created := map[string]interface{}{"$reql_type$": "TIME", "epoch_time": 1375147296.5, "timezone": "+00:00"}
+	rows := make([]interface{}, 100)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i, "created": created}
+	}
+	rows[50] = map[string]interface{}{"id": []interface{}{}}
+
+	mock := NewMock()
+	mock.On(DB("test").Table("test")).Return(rows, nil)
+	res, err := DB("test").Table("test").Run(mock, RunOpts{DecodeWorkers: 4})
+	c.Assert(err, test.IsNil)
+
+	var r row
+	for i := 0; i < 49; i++ {
+		c.Assert(res.Next(&r), test.Equals, true)
+		c.Assert(r.ID, test.Equals, i)
+		c.Assert(r.Created.Equal(time.Unix(1375147296, 5e8)), test.Equals, true)
+	}
+
+	// Documents decoded into another type are decoded again
+	var m map[string]interface{}
+	ok, err := res.Peek(&m)
+	c.Assert(ok, test.Equals, true)
+	c.Assert(err, test.IsNil)
+	c.Assert(m["id"], test.Equals, float64(49))
+	c.Assert(res.Next(&r), test.Equals, true)
+	c.Assert(r.ID, test.Equals, 49)
+
+	// Errors are returned in order
+	c.Assert(res.Next(&r), test.Equals, false)
+	c.Assert(res.Err(), test.FitsTypeOf, &encoding.DecodeTypeError{})
+	mock.AssertExpectations(c)
+}
+
+func (s *CursorSuite) TestCursor_All_DecodeWorkers(c *test.C) {
+	rows := make([]interface{}, 1000)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i}
+	}
+
+	mock := NewMock()
+	mock.On(DB("test").Table("test")).Return(rows, nil)
+	res, err := DB("test").Table("test").Run(mock)
+	c.Assert(err, test.IsNil)
+	res.SetDecodeWorkers(8)
+
+	var all []struct {
+		ID int `rethinkdb:"id"`
+	}
+	c.Assert(res.All(&all), test.IsNil)
+	c.Assert(all, test.HasLen, len(rows))
+	for i := range all {
+		c.Assert(all[i].ID, test.Equals, i)
+	}
+	mock.AssertExpectations(c)
+}
+
+func benchmarkCursorDecodeWorkers(b *testing.B, workers int) {
+	type row struct {
+		ID      int                    `rethinkdb:"id"`
+		Name    string                 `rethinkdb:"name"`
+		Tags    []string               `rethinkdb:"tags"`
+		Created time.Time              `rethinkdb:"created"`
+		Meta    map[string]interface{} `rethinkdb:"meta"`
+	}
+
+	responses := make([]json.RawMessage, 1000)
+	for i := range responses {
+		responses[i] = json.RawMessage(fmt.Sprintf(`{"id": %d, "name": "row %d", "tags": ["a", "b", "c"], `+
+			`"created": {"$reql_type$": "TIME", "epoch_time": 1375147296.5, "timezone": "+00:00"}, `+
+			`"meta": {"score": 1.5, "active": true}}`, i, i))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cursor := newCursor(context.Background(), nil, "", 0, nil, map[string]interface{}{"decode_workers": workers})
+		cursor.responses = append(cursor.responses, responses...)
+		cursor.finished = true
+
+		var rows []row
+		if err := cursor.All(&rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCursorDecode(b *testing.B) {
+	benchmarkCursorDecodeWorkers(b, 0)
+}
+
+func BenchmarkCursorDecode_Workers(b *testing.B) {
+	benchmarkCursorDecodeWorkers(b, runtime.GOMAXPROCS(0))
+}
@@ -5,7 +5,6 @@ package encoding
 import (
 	"reflect"
 	"sort"
-	"sync"
 	"time"
 )
 
@@ -19,6 +18,8 @@ type field struct {
 	index         []int
 	typ           reflect.Type
 	omitEmpty     bool
+	omitZero      bool
+	inline        bool
 	reference     bool
 	refName       string
 	compound      bool
@@ -76,7 +77,7 @@ func (x byIndex) Less(i, j int) bool {
 // typeFields returns a list of fields that should be recognized for the given type.
 // The algorithm is breadth-first search over the set of structs to include - the top struct
 // and then any reachable anonymous structs.
-func typeFields(t reflect.Type) []field {
+func (c *Codec) typeFields(t reflect.Type) []field {
 	// Anonymous fields to explore at the current level and the next.
 	current := []field{}
 	next := []field{{typ: t}}
@@ -90,6 +91,7 @@ func typeFields(t reflect.Type) []field {
 
 	// Fields found.
 	var fields []field
+	var inline []field
 
 	for len(next) > 0 {
 		current, next = next, current[:0]
@@ -108,7 +110,7 @@ func typeFields(t reflect.Type) []field {
 					continue
 				}
 				// Extract field name from tag
-				tag := getTag(sf)
+				tag := c.getTag(sf)
 				if tag == "-" {
 					continue
 				}
@@ -134,11 +136,28 @@ func typeFields(t reflect.Type) []field {
 					ft = ft.Elem()
 				}
 
+				// Record the map of the unknown fields of the struct.
+				if opts.Contains("inline") && sf.Type.Kind() == reflect.Map && sf.Type.Key().Kind() == reflect.String {
+					inline = append(inline, field{
+						name:   sf.Name,
+						index:  index,
+						typ:    sf.Type,
+						inline: true,
+					})
+					if count[f.typ] > 1 {
+						inline = append(inline, inline[len(inline)-1])
+					}
+					continue
+				}
+
 				// Record found field and index sequence.
 				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct || isPseudoType(ft) {
 					tagged := name != ""
 					if name == "" {
 						name = sf.Name
+						if naming := c.namingStrategy(); naming != nil {
+							name = naming(name)
+						}
 					}
 					fields = append(fields, fillField(field{
 						name:          name,
@@ -146,6 +165,7 @@ func typeFields(t reflect.Type) []field {
 						index:         index,
 						typ:           ft,
 						omitEmpty:     opts.Contains("omitempty"),
+						omitZero:      opts.Contains("omitzero"),
 						reference:     opts.Contains("reference"),
 						refName:       ref,
 						compound:      isCompound,
@@ -204,6 +224,13 @@ func typeFields(t reflect.Type) []field {
 	}
 
 	fields = out
+
+	// The shallowest inline field is used, as with other fields there is a
+	// conflict if there are multiple inline fields at the same depth.
+	if len(inline) == 1 || len(inline) > 1 && len(inline[0].index) < len(inline[1].index) {
+		fields = append(fields, inline[0])
+	}
+
 	sort.Sort(byIndex(fields))
 
 	return fields
@@ -251,32 +278,24 @@ func dominantField(fields []field) (field, bool) {
 	return fields[0], true
 }
 
-var fieldCache struct {
-	sync.RWMutex
-	m map[reflect.Type][]field
-}
-
 // cachedTypeFields is like typeFields but uses a cache to avoid repeated work.
-func cachedTypeFields(t reflect.Type) []field {
-	fieldCache.RLock()
-	f := fieldCache.m[t]
-	fieldCache.RUnlock()
+func (c *Codec) cachedTypeFields(t reflect.Type) []field {
+	c.fieldCache.RLock()
+	f := c.fieldCache.m[t]
+	c.fieldCache.RUnlock()
 	if f != nil {
 		return f
 	}
 
 	// Compute fields without lock.
 	// Might duplicate effort but won't hold other computations back.
-	f = typeFields(t)
+	f = c.typeFields(t)
 	if f == nil {
 		f = []field{}
 	}
 
-	fieldCache.Lock()
-	if fieldCache.m == nil {
-		fieldCache.m = map[reflect.Type][]field{}
-	}
-	fieldCache.m[t] = f
-	fieldCache.Unlock()
+	c.fieldCache.Lock()
+	c.fieldCache.m[t] = f
+	c.fieldCache.Unlock()
 	return f
 }
@@ -0,0 +1,206 @@
+package encoding
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// Codec encodes and decodes values using its own struct tags, naming strategy
+// and custom type encodings. The encoders, decoders and struct fields of each
+// type are cached by the codec, so codecs with different configurations can
+// be used in the same program without affecting each other.
+//
+// The package level functions, such as Encode, Decode and SetTypeEncoding,
+// use the default codec returned by DefaultCodec which is configured using
+// the Tags and NamingStrategy variables.
+type Codec struct {
+	tags   []string
+	naming func(name string) string
+	// global is true for the default codec, which reads the package level
+	// configuration variables instead of tags and naming.
+	global bool
+
+	encoderCache struct {
+		sync.RWMutex
+		m map[reflect.Type]encoderFunc
+	}
+	decoderCache struct {
+		sync.RWMutex
+		m map[decoderCacheKey]decoderFunc
+	}
+	// customDecoders contains the decoders set by SetTypeEncoding, they are
+	// used with any decoding options.
+	customDecoders struct {
+		sync.RWMutex
+		types map[reflect.Type]bool
+		m     map[decoderCacheKey]decoderFunc
+	}
+	fieldCache struct {
+		sync.RWMutex
+		m map[reflect.Type][]field
+	}
+}
+
+// CodecOpts contains the configuration of a Codec.
+type CodecOpts struct {
+	// Tags are the names of the struct tags field names and options are read
+	// from, in order of precedence. Like SetTags in the rethinkdb package the
+	// rethinkdb and gorethink tags are always read after these tags, so that
+	// the types of the driver, such as WriteResponse, are decoded correctly.
+	Tags []string
+	// NamingStrategy returns the name used to encode and decode struct fields
+	// which do not set a name in their tag, such as SnakeCase. By default the
+	// name of the Go field is used.
+	NamingStrategy func(name string) string
+}
+
+var defaultCodec = newCodec(CodecOpts{}, true)
+
+// NewCodec returns a Codec using the configuration in opts, the configuration
+// cannot be changed once the codec is created.
+func NewCodec(opts CodecOpts) *Codec {
+	return newCodec(opts, false)
+}
+
+func newCodec(opts CodecOpts, global bool) *Codec {
+	var tags []string
+	if len(opts.Tags) > 0 {
+		tags = append(append(tags, opts.Tags...), TagName, OldTagName)
+	}
+	c := &Codec{
+		tags:   tags,
+		naming: opts.NamingStrategy,
+		global: global,
+	}
+	c.encoderCache.m = make(map[reflect.Type]encoderFunc)
+	c.decoderCache.m = make(map[decoderCacheKey]decoderFunc)
+	c.customDecoders.types = make(map[reflect.Type]bool)
+	c.customDecoders.m = make(map[decoderCacheKey]decoderFunc)
+	c.fieldCache.m = make(map[reflect.Type][]field)
+
+	return c
+}
+
+// DefaultCodec returns the codec used by the package level functions.
+func DefaultCodec() *Codec {
+	return defaultCodec
+}
+
+// tagNames returns the struct tags read by the codec, nil if the default
+// tags are used.
+func (c *Codec) tagNames() []string {
+	if c.global {
+		return Tags
+	}
+	return c.tags
+}
+
+// namingStrategy returns the naming strategy of the codec, if any.
+func (c *Codec) namingStrategy() func(name string) string {
+	if c.global {
+		return NamingStrategy
+	}
+	return c.naming
+}
+
+// Encode returns the encoded value of v, see the package level Encode
+// function.
+func (c *Codec) Encode(v interface{}) (ev interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			if v, ok := r.(string); ok {
+				err = errors.New(v)
+			} else {
+				err = r.(error)
+			}
+		}
+	}()
+
+	return c.encode(reflect.ValueOf(v))
+}
+
+// Decode decodes src into the value pointed to by dst, see the package level
+// Decode function.
+func (c *Codec) Decode(dst interface{}, src interface{}) (err error) {
+	return c.decode(dst, src, decoderOpts{blank: true})
+}
+
+// DecodeWithOpts decodes src into dst in the same way as Decode, returning
+// an error for the values rejected by opts.
+func (c *Codec) DecodeWithOpts(dst interface{}, src interface{}, opts DecodeOpts) (err error) {
+	return c.decode(dst, src, decoderOpts{DecodeOpts: opts, blank: true})
+}
+
+// Merge decodes src into dst without first resetting the values of dst.
+func (c *Codec) Merge(dst interface{}, src interface{}) (err error) {
+	return c.decode(dst, src, decoderOpts{})
+}
+
+// IgnoreType causes the codec to ignore a type when encoding
+func (c *Codec) IgnoreType(t reflect.Type) {
+	c.encoderCache.Lock()
+	c.encoderCache.m[t] = doNothingEncoder
+	c.encoderCache.Unlock()
+}
+
+// SetTypeEncoding sets the functions used by the codec to encode and decode
+// values of the type t.
+func (c *Codec) SetTypeEncoding(
+	t reflect.Type,
+	encode func(value interface{}) (interface{}, error),
+	decode func(encoded interface{}, value reflect.Value) error,
+) {
+	c.encoderCache.Lock()
+	c.encoderCache.m[t] = func(v reflect.Value) (interface{}, error) {
+		return encode(v.Interface())
+	}
+	c.encoderCache.Unlock()
+
+	dec := func(dv reflect.Value, sv reflect.Value) error {
+		return decode(sv.Interface(), dv)
+	}
+	c.customDecoders.Lock()
+	// decode as pointer
+	c.customDecoders.m[decoderCacheKey{dt: t, st: emptyInterfaceType}] = dec
+	// decode as value
+	c.customDecoders.m[decoderCacheKey{dt: t, st: mapInterfaceType}] = dec
+	c.customDecoders.types[t] = true
+
+	if t.Kind() == reflect.Ptr {
+		c.customDecoders.m[decoderCacheKey{dt: t.Elem(), st: emptyInterfaceType}] = dec
+		c.customDecoders.m[decoderCacheKey{dt: t.Elem(), st: mapInterfaceType}] = dec
+		c.customDecoders.types[t.Elem()] = true
+	}
+	c.customDecoders.Unlock()
+
+	// Remove any decoders already cached for the type
+	c.decoderCache.Lock()
+	for k := range c.decoderCache.m {
+		if c.customDecoder(k.dt, k.st) != nil {
+			delete(c.decoderCache.m, k)
+		}
+	}
+	c.decoderCache.Unlock()
+}
+
+// HasTypeEncoding returns true if SetTypeEncoding was called for the type t.
+func (c *Codec) HasTypeEncoding(t reflect.Type) bool {
+	return c.isCustomDecoderType(t)
+}
+
+func (c *Codec) isCustomDecoderType(t reflect.Type) bool {
+	c.customDecoders.RLock()
+	defer c.customDecoders.RUnlock()
+	return c.customDecoders.types[t]
+}
+
+func (c *Codec) customDecoder(dt, st reflect.Type) decoderFunc {
+	c.customDecoders.RLock()
+	defer c.customDecoders.RUnlock()
+	return c.customDecoders.m[decoderCacheKey{dt: dt, st: st}]
+}
@@ -0,0 +1,152 @@
+package encoding
+
+import (
+	"reflect"
+	"testing"
+)
+
+type codecTagged struct {
+	ID      string `rethinkdb:"id" json:"_id"`
+	Name    string `json:"full_name"`
+	Ignored string `rethinkdb:"-" json:"ignored"`
+}
+
+func TestCodecTags(t *testing.T) {
+	jsonCodec := NewCodec(CodecOpts{Tags: []string{JSONTagName}})
+	in := codecTagged{ID: "1", Name: "a", Ignored: "b"}
+
+	out, err := Encode(in)
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	want := map[string]interface{}{"id": "1", "Name": "a"}
+	if !jsonEqual(out, want) {
+		t.Errorf("got %v, want %v", out, want)
+	}
+
+	out, err = jsonCodec.Encode(in)
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	want = map[string]interface{}{"_id": "1", "full_name": "a", "ignored": "b"}
+	if !jsonEqual(out, want) {
+		t.Errorf("got %v, want %v", out, want)
+	}
+
+	var dec codecTagged
+	if err := jsonCodec.Decode(&dec, want); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	if dec != in {
+		t.Errorf("got %+v, want %+v", dec, in)
+	}
+
+	// The default codec does not read the fields cached by the other codec
+	dec = codecTagged{}
+	if err := Decode(&dec, map[string]interface{}{"id": "1", "_id": "2"}); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	if dec.ID != "1" {
+		t.Errorf("got %q, want %q", dec.ID, "1")
+	}
+}
+
+func TestCodecDefaultTags(t *testing.T) {
+	type response struct {
+		Inserted int `rethinkdb:"inserted"`
+		Old      int `gorethink:"old"`
+	}
+
+	c := NewCodec(CodecOpts{Tags: []string{JSONTagName}})
+	var dec response
+	if err := c.Decode(&dec, map[string]interface{}{"inserted": 1, "old": 2}); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	if want := (response{Inserted: 1, Old: 2}); dec != want {
+		t.Errorf("got %+v, want %+v", dec, want)
+	}
+}
+
+func TestCodecNamingStrategy(t *testing.T) {
+	type named struct {
+		UserID int
+	}
+
+	c := NewCodec(CodecOpts{NamingStrategy: SnakeCase})
+	out, err := c.Encode(named{UserID: 1})
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	want := map[string]interface{}{"user_id": int64(1)}
+	if !jsonEqual(out, want) {
+		t.Errorf("got %v, want %v", out, want)
+	}
+
+	out, err = Encode(named{UserID: 1})
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	want = map[string]interface{}{"UserID": int64(1)}
+	if !jsonEqual(out, want) {
+		t.Errorf("got %v, want %v", out, want)
+	}
+}
+
+func TestCodecSetTypeEncoding(t *testing.T) {
+	type cType struct {
+		Val int
+	}
+
+	c := NewCodec(CodecOpts{})
+	c.SetTypeEncoding(reflect.TypeOf(cType{}),
+		func(v interface{}) (interface{}, error) {
+			return map[string]interface{}{"someval": v.(cType).Val}, nil
+		},
+		func(enc interface{}, val reflect.Value) error {
+			m := enc.(map[string]interface{})
+			val.Set(reflect.ValueOf(cType{Val: m["someval"].(int)}))
+			return nil
+		})
+
+	out, err := c.Encode(cType{Val: 5})
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	want := map[string]interface{}{"someval": 5}
+	if !jsonEqual(out, want) {
+		t.Errorf("got %v, want %v", out, want)
+	}
+	var dec cType
+	if err := c.Decode(&dec, want); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	if dec.Val != 5 {
+		t.Errorf("got %+v, want %+v", dec, cType{Val: 5})
+	}
+
+	// Other codecs still encode the type as a struct
+	for _, other := range []*Codec{DefaultCodec(), NewCodec(CodecOpts{})} {
+		out, err := other.Encode(cType{Val: 5})
+		if err != nil {
+			t.Fatalf("got error %v, expected nil", err)
+		}
+		want := map[string]interface{}{"Val": int64(5)}
+		if !jsonEqual(out, want) {
+			t.Errorf("got %v, want %v", out, want)
+		}
+	}
+}
+
+func TestCodecDecodeJSON(t *testing.T) {
+	c := NewCodec(CodecOpts{Tags: []string{JSONTagName}})
+
+	var dec codecTagged
+	err := c.DecodeJSON(&dec, []byte(`{"_id": "1", "full_name": "a", "ignored": "b"}`), JSONOpts{})
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	want := codecTagged{ID: "1", Name: "a", Ignored: "b"}
+	if dec != want {
+		t.Errorf("got %+v, want %+v", dec, want)
+	}
+}
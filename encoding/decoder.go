@@ -9,17 +9,51 @@ import (
 
 type decoderFunc func(dv reflect.Value, sv reflect.Value) error
 
+// DecodeOpts contains the options used by DecodeWithOpts to reject values
+// which would otherwise be ignored or converted. The errors returned include
+// the path of the field the value was decoded into.
+//
+// Values decoded by an Unmarshaler or by a decoder set using SetTypeEncoding
+// are not checked, except by the methods generated by cmd/rethinkdb-gen which
+// implement StateUnmarshaler.
+type DecodeOpts struct {
+	// DisallowUnknownFields returns an UnknownFieldError if an object has a
+	// field which does not match any field of the struct it is decoded into,
+	// unless the struct has an inline map.
+	DisallowUnknownFields bool
+	// DisallowLossyConversion returns a LossyConversionError if a value
+	// cannot be stored without changing it: numbers with a fractional part or
+	// out of the range of an integer, integers which cannot be represented
+	// exactly by a float, floats out of the range of a float32 and values
+	// converted between booleans, numbers and strings.
+	DisallowLossyConversion bool
+}
+
+// decoderOpts are the options decoders are constructed with.
+type decoderOpts struct {
+	DecodeOpts
+	// blank is true if values are reset before being decoded, it is false
+	// when merging values.
+	blank bool
+}
+
 // Decode decodes map[string]interface{} into a struct. The first parameter
 // must be a pointer.
 func Decode(dst interface{}, src interface{}) (err error) {
-	return decode(dst, src, true)
+	return defaultCodec.Decode(dst, src)
+}
+
+// DecodeWithOpts decodes src into dst in the same way as Decode, returning
+// an error for the values rejected by opts.
+func DecodeWithOpts(dst interface{}, src interface{}, opts DecodeOpts) (err error) {
+	return defaultCodec.DecodeWithOpts(dst, src, opts)
 }
 
 func Merge(dst interface{}, src interface{}) (err error) {
-	return decode(dst, src, false)
+	return defaultCodec.Merge(dst, src)
 }
 
-func decode(dst interface{}, src interface{}, blank bool) (err error) {
+func (c *Codec) decode(dst interface{}, src interface{}, opts decoderOpts) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			if _, ok := r.(runtime.Error); ok {
@@ -52,24 +86,20 @@ func decode(dst interface{}, src interface{}, blank bool) (err error) {
 		}
 	}
 
-	return decodeValue(dv, sv, blank)
+	return c.decodeValue(dv, sv, opts)
 }
 
 // decodeValue decodes the source value into the destination value
-func decodeValue(dv, sv reflect.Value, blank bool) error {
-	return valueDecoder(dv, sv, blank)(dv, sv)
+func (c *Codec) decodeValue(dv, sv reflect.Value, opts decoderOpts) error {
+	return c.valueDecoder(dv, sv, opts)(dv, sv)
 }
 
 type decoderCacheKey struct {
 	dt, st reflect.Type
+	opts   decoderOpts
 }
 
-var decoderCache struct {
-	sync.RWMutex
-	m map[decoderCacheKey]decoderFunc
-}
-
-func valueDecoder(dv, sv reflect.Value, blank bool) decoderFunc {
+func (c *Codec) valueDecoder(dv, sv reflect.Value, opts decoderOpts) decoderFunc {
 	if !sv.IsValid() {
 		return invalidValueDecoder
 	}
@@ -79,42 +109,49 @@ func valueDecoder(dv, sv reflect.Value, blank bool) decoderFunc {
 		if sv.Kind() == reflect.Ptr {
 			sv = indirect(sv, false)
 			dv.Set(sv)
-		} else if blank {
+		} else if opts.blank {
 			dv.Set(reflect.Zero(dv.Type()))
 		}
 	}
 
-	return typeDecoder(dv.Type(), sv.Type(), blank)
+	return c.typeDecoder(dv.Type(), sv.Type(), opts)
 }
 
-func typeDecoder(dt, st reflect.Type, blank bool) decoderFunc {
-	decoderCache.RLock()
-	f := decoderCache.m[decoderCacheKey{dt, st}]
-	decoderCache.RUnlock()
+func (c *Codec) typeDecoder(dt, st reflect.Type, opts decoderOpts) decoderFunc {
+	key := decoderCacheKey{dt, st, opts}
+	c.decoderCache.RLock()
+	f := c.decoderCache.m[key]
+	c.decoderCache.RUnlock()
 	if f != nil {
 		return f
 	}
+	if f = c.customDecoder(dt, st); f != nil {
+		c.decoderCache.Lock()
+		c.decoderCache.m[key] = f
+		c.decoderCache.Unlock()
+		return f
+	}
 
 	// To deal with recursive types, populate the map with an
 	// indirect func before we build it. This type waits on the
 	// real func (f) to be ready and then calls it.  This indirect
 	// func is only used for recursive types.
-	decoderCache.Lock()
+	c.decoderCache.Lock()
 	var wg sync.WaitGroup
 	wg.Add(1)
-	decoderCache.m[decoderCacheKey{dt, st}] = func(dv, sv reflect.Value) error {
+	c.decoderCache.m[key] = func(dv, sv reflect.Value) error {
 		wg.Wait()
 		return f(dv, sv)
 	}
-	decoderCache.Unlock()
+	c.decoderCache.Unlock()
 
 	// Compute fields without lock.
 	// Might duplicate effort but won't hold other computations back.
-	f = newTypeDecoder(dt, st, blank)
+	f = c.newTypeDecoder(dt, st, opts)
 	wg.Done()
-	decoderCache.Lock()
-	decoderCache.m[decoderCacheKey{dt, st}] = f
-	decoderCache.Unlock()
+	c.decoderCache.Lock()
+	c.decoderCache.m[key] = f
+	c.decoderCache.Unlock()
 	return f
 }
 
@@ -0,0 +1,598 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"runtime"
+	"strconv"
+	"unicode/utf8"
+)
+
+// JSONOpts contains the options used by DecodeJSON.
+type JSONOpts struct {
+	DecodeOpts
+
+	// UseNumber causes numbers to be decoded as json.Number, the same as
+	// json.Decoder.UseNumber.
+	UseNumber bool
+	// ConvertPseudoTypes is called with any value which may contain
+	// pseudo-types before it is decoded, for example to convert TIME objects
+	// to time.Time.
+	ConvertPseudoTypes func(v interface{}) (interface{}, error)
+}
+
+var (
+	// errJSONFallback is returned when the document cannot be decoded
+	// directly, either because it contains a pseudo-type where a plain object
+	// is expected or because it is not valid JSON.
+	errJSONFallback = errors.New("rethinkdb: JSON document must be decoded using an interface value")
+)
+
+// DecodeJSON decodes a JSON document directly into the value pointed to by
+// dst, without first unmarshaling it into an interface{} value. The result is
+// the same as unmarshaling the document into an interface{}, converting any
+// pseudo-types using opts.ConvertPseudoTypes and calling Decode.
+//
+// Structs, maps, slices and pointers are populated as the document is read.
+// Values which need the intermediate representation, such as interface
+// values, types implementing Unmarshaler, time.Time and []byte, are decoded
+// from an interface{} value holding only their part of the document.
+func DecodeJSON(dst interface{}, data []byte, opts JSONOpts) (err error) {
+	return defaultCodec.DecodeJSON(dst, data, opts)
+}
+
+// DecodeJSON decodes a JSON document directly into the value pointed to by
+// dst, see the package level DecodeJSON function.
+func (c *Codec) DecodeJSON(dst interface{}, data []byte, opts JSONOpts) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			if v, ok := r.(string); ok {
+				err = errors.New(v)
+			} else {
+				err = r.(error)
+			}
+		}
+	}()
+
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(dst)}
+	}
+
+	dv = indirect(dv.Elem(), false)
+	dv.Set(reflect.Zero(dv.Type()))
+
+	d := &jsonDecoder{codec: c, data: data, opts: opts}
+	err = d.value(dv)
+	if err == nil {
+		d.skipSpace()
+		if d.off < len(d.data) {
+			err = errJSONFallback
+		}
+	}
+	if err != errJSONFallback {
+		return err
+	}
+
+	// Decode the whole document using the intermediate representation, the
+	// decoder only reads the first value so check the document is valid first
+	if !json.Valid(data) {
+		var v interface{}
+		return json.Unmarshal(data, &v)
+	}
+	v, err := d.unmarshal(data)
+	if err != nil {
+		return err
+	}
+
+	return c.decode(dst, v, d.decoderOpts())
+}
+
+// CanDecodeJSON returns true if values of the type are decoded directly by
+// DecodeJSON instead of using an interface{} value.
+func CanDecodeJSON(t reflect.Type) bool {
+	return defaultCodec.CanDecodeJSON(t)
+}
+
+// CanDecodeJSON returns true if values of the type are decoded directly by
+// the DecodeJSON method of the codec.
+func (c *Codec) CanDecodeJSON(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice:
+		return !c.needsInterface(t)
+	case reflect.Ptr:
+		return !c.needsInterface(t) && c.CanDecodeJSON(t.Elem())
+	}
+	return false
+}
+
+// needsInterface returns true if values of the type must be decoded from an
+// interface{} value.
+func (c *Codec) needsInterface(t reflect.Type) bool {
+	if t.Implements(unmarshalerType) || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return true
+	}
+	if isPseudoType(t) || c.isCustomDecoderType(t) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Interface, reflect.Array:
+		return true
+	case reflect.Map:
+		return t.Key().Kind() != reflect.String
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+	return false
+}
+
+type jsonDecoder struct {
+	codec *Codec
+	data  []byte
+	off   int
+	opts  JSONOpts
+}
+
+// decoderOpts returns the options of the decoders used for values decoded
+// from an interface{} value.
+func (d *jsonDecoder) decoderOpts() decoderOpts {
+	return decoderOpts{DecodeOpts: d.opts.DecodeOpts, blank: true}
+}
+
+func (d *jsonDecoder) value(dv reflect.Value) error {
+	d.skipSpace()
+	if d.off >= len(d.data) {
+		return errJSONFallback
+	}
+
+	dt := dv.Type()
+	if d.codec.needsInterface(dt) {
+		return d.interfaceValue(dv)
+	}
+
+	c := d.data[d.off]
+	switch dt.Kind() {
+	case reflect.Ptr:
+		if c == 'n' {
+			return d.interfaceValue(dv)
+		}
+		if dv.IsNil() {
+			dv.Set(reflect.New(dt.Elem()))
+		}
+		return d.value(dv.Elem())
+	case reflect.Struct:
+		if c == '{' {
+			return d.structValue(dv)
+		}
+	case reflect.Map:
+		if c == '{' {
+			return d.mapValue(dv)
+		}
+	case reflect.Slice:
+		if c == '[' {
+			return d.sliceValue(dv)
+		}
+	case reflect.String:
+		if c == '"' {
+			s, err := d.key()
+			if err != nil {
+				return err
+			}
+			dv.SetString(string(s))
+			return nil
+		}
+	case reflect.Bool:
+		if c == 't' || c == 'f' {
+			b, err := d.literal()
+			if err != nil {
+				return err
+			}
+			dv.SetBool(b.(bool))
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if isNumberStart(c) && !d.opts.UseNumber {
+			f, err := d.float()
+			if err != nil {
+				return err
+			}
+			if d.opts.DisallowLossyConversion {
+				return exactFloatAsIntDecoder(dv, reflect.ValueOf(f))
+			}
+			dv.SetInt(int64(f))
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if isNumberStart(c) && !d.opts.UseNumber {
+			f, err := d.float()
+			if err != nil {
+				return err
+			}
+			if d.opts.DisallowLossyConversion {
+				return exactFloatAsUintDecoder(dv, reflect.ValueOf(f))
+			}
+			dv.SetUint(uint64(f))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if isNumberStart(c) && !d.opts.UseNumber {
+			f, err := d.float()
+			if err != nil {
+				return err
+			}
+			if d.opts.DisallowLossyConversion {
+				return exactFloatAsFloatDecoder(dv, reflect.ValueOf(f))
+			}
+			dv.SetFloat(f)
+			return nil
+		}
+	}
+
+	return d.interfaceValue(dv)
+}
+
+// interfaceValue decodes the next value into an interface{} value, converts
+// any pseudo-types and then decodes it into dv in the same way as a field
+// decoded by Decode.
+func (d *jsonDecoder) interfaceValue(dv reflect.Value) error {
+	start := d.off
+	if err := d.skipValue(); err != nil {
+		return err
+	}
+
+	v, err := d.unmarshal(d.data[start:d.off])
+	if err != nil {
+		return err
+	}
+
+	sv := reflect.ValueOf(&v).Elem()
+	return d.codec.typeDecoder(dv.Type(), sv.Type(), d.decoderOpts())(dv, sv)
+}
+
+func (d *jsonDecoder) structValue(dv reflect.Value) error {
+	fields := d.codec.cachedTypeFields(dv.Type())
+
+	return d.object(func(key []byte) error {
+		var f, inline *field
+		compound := false
+		for i := range fields {
+			ff := &fields[i]
+			if ff.inline {
+				inline = ff
+				continue
+			}
+			if bytes.Equal(ff.nameBytes, key) || f == nil && ff.equalFold(ff.nameBytes, key) {
+				f = ff
+				compound = compound || ff.compound
+			}
+		}
+
+		if compound {
+			// Compound fields are decoded from an array by Decode
+			start := d.off
+			if err := d.skipValue(); err != nil {
+				return err
+			}
+			v, err := d.unmarshal(d.data[start:d.off])
+			if err != nil {
+				return err
+			}
+			sv := reflect.ValueOf(map[string]interface{}{string(key): v})
+			return d.codec.typeDecoder(dv.Type(), sv.Type(), d.decoderOpts())(dv, sv)
+		}
+		if f == nil && inline != nil {
+			return withFieldPath(d.inlineValue(dv, inline, key), string(key))
+		}
+		if f == nil {
+			if d.opts.DisallowUnknownFields {
+				return &UnknownFieldError{Path: string(key), Type: dv.Type()}
+			}
+			return d.skipValue()
+		}
+
+		fv := fieldByIndex(dv, f.index)
+		if !fv.CanSet() {
+			return d.skipValue()
+		}
+		return withFieldPath(d.value(fv), f.name)
+	})
+}
+
+// inlineValue decodes a value which does not match any of the fields of the
+// struct into the inline map.
+func (d *jsonDecoder) inlineValue(dv reflect.Value, f *field, key []byte) error {
+	mv := fieldByIndex(dv, f.index)
+	if !mv.CanSet() {
+		return d.skipValue()
+	}
+	if mv.IsNil() {
+		mv.Set(reflect.MakeMap(f.typ))
+	}
+
+	mapElem := reflect.New(f.typ.Elem()).Elem()
+	if err := d.value(mapElem); err != nil {
+		return err
+	}
+
+	mv.SetMapIndex(reflect.ValueOf(string(key)).Convert(f.typ.Key()), mapElem)
+	return nil
+}
+
+func (d *jsonDecoder) mapValue(dv reflect.Value) error {
+	dt := dv.Type()
+	dv.Set(reflect.MakeMap(dt))
+
+	keyType, elemType := dt.Key(), dt.Elem()
+	return d.object(func(key []byte) error {
+		mapKey := reflect.New(keyType).Elem()
+		mapKey.SetString(string(key))
+		mapElem := reflect.New(elemType).Elem()
+		if err := d.value(mapElem); err != nil {
+			return withFieldPath(err, string(key))
+		}
+
+		dv.SetMapIndex(mapKey, mapElem)
+		return nil
+	})
+}
+
+func (d *jsonDecoder) sliceValue(dv reflect.Value) error {
+	dv.Set(reflect.MakeSlice(dv.Type(), 0, 0))
+
+	d.off++ // [
+	d.skipSpace()
+	if d.off < len(d.data) && d.data[d.off] == ']' {
+		d.off++
+		return nil
+	}
+
+	for i := 0; ; i++ {
+		if i >= dv.Cap() {
+			newcap := dv.Cap() + dv.Cap()/2
+			if newcap < 4 {
+				newcap = 4
+			}
+			newdv := reflect.MakeSlice(dv.Type(), dv.Len(), newcap)
+			reflect.Copy(newdv, dv)
+			dv.Set(newdv)
+		}
+		dv.SetLen(i + 1)
+
+		if err := d.value(dv.Index(i)); err != nil {
+			return withFieldPath(err, "["+strconv.Itoa(i)+"]")
+		}
+
+		d.skipSpace()
+		if d.off >= len(d.data) {
+			return errJSONFallback
+		}
+		switch d.data[d.off] {
+		case ',':
+			d.off++
+		case ']':
+			d.off++
+			return nil
+		default:
+			return errJSONFallback
+		}
+	}
+}
+
+// object reads the members of an object, calling fn for each key with the
+// decoder positioned at the start of the value. Objects containing a
+// pseudo-type must be converted before being decoded so cause the decoder to
+// fall back to using an interface{} value.
+func (d *jsonDecoder) object(fn func(key []byte) error) error {
+	d.off++ // {
+	d.skipSpace()
+	if d.off < len(d.data) && d.data[d.off] == '}' {
+		d.off++
+		return nil
+	}
+
+	for {
+		d.skipSpace()
+		if d.off >= len(d.data) || d.data[d.off] != '"' {
+			return errJSONFallback
+		}
+		key, err := d.key()
+		if err != nil {
+			return err
+		}
+		if string(key) == "$reql_type$" {
+			return errJSONFallback
+		}
+
+		d.skipSpace()
+		if d.off >= len(d.data) || d.data[d.off] != ':' {
+			return errJSONFallback
+		}
+		d.off++
+
+		if err := fn(key); err != nil {
+			return err
+		}
+
+		d.skipSpace()
+		if d.off >= len(d.data) {
+			return errJSONFallback
+		}
+		switch d.data[d.off] {
+		case ',':
+			d.off++
+		case '}':
+			d.off++
+			return nil
+		default:
+			return errJSONFallback
+		}
+	}
+}
+
+// key returns the next string, without copying it if it does not need to be
+// unescaped.
+func (d *jsonDecoder) key() ([]byte, error) {
+	start := d.off
+	end, plain := d.scanString()
+	if end < 0 {
+		return nil, errJSONFallback
+	}
+	d.off = end
+	if plain {
+		return d.data[start+1 : end-1], nil
+	}
+
+	var s string
+	if err := json.Unmarshal(d.data[start:end], &s); err != nil {
+		return nil, errJSONFallback
+	}
+	return []byte(s), nil
+}
+
+func (d *jsonDecoder) literal() (interface{}, error) {
+	for _, lit := range []struct {
+		text  string
+		value interface{}
+	}{{"true", true}, {"false", false}, {"null", nil}} {
+		if bytes.HasPrefix(d.data[d.off:], []byte(lit.text)) {
+			d.off += len(lit.text)
+			return lit.value, nil
+		}
+	}
+	return nil, errJSONFallback
+}
+
+func (d *jsonDecoder) float() (float64, error) {
+	start := d.off
+	d.scanNumber()
+	f, err := strconv.ParseFloat(string(d.data[start:d.off]), 64)
+	if err != nil {
+		return 0, errJSONFallback
+	}
+	return f, nil
+}
+
+// unmarshal decodes data into an interface{} value and converts any
+// pseudo-types.
+func (d *jsonDecoder) unmarshal(data []byte) (interface{}, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if d.opts.UseNumber {
+		dec.UseNumber()
+	}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	if d.opts.ConvertPseudoTypes != nil {
+		return d.opts.ConvertPseudoTypes(v)
+	}
+	return v, nil
+}
+
+func (d *jsonDecoder) skipSpace() {
+	for d.off < len(d.data) {
+		switch d.data[d.off] {
+		case ' ', '\t', '\n', '\r':
+			d.off++
+		default:
+			return
+		}
+	}
+}
+
+// skipValue moves the decoder past the next value.
+func (d *jsonDecoder) skipValue() error {
+	d.skipSpace()
+	if d.off >= len(d.data) {
+		return errJSONFallback
+	}
+
+	switch c := d.data[d.off]; {
+	case c == '{':
+		return d.object(func(key []byte) error {
+			return d.skipValue()
+		})
+	case c == '[':
+		d.off++
+		d.skipSpace()
+		if d.off < len(d.data) && d.data[d.off] == ']' {
+			d.off++
+			return nil
+		}
+		for {
+			if err := d.skipValue(); err != nil {
+				return err
+			}
+			d.skipSpace()
+			if d.off >= len(d.data) {
+				return errJSONFallback
+			}
+			switch d.data[d.off] {
+			case ',':
+				d.off++
+			case ']':
+				d.off++
+				return nil
+			default:
+				return errJSONFallback
+			}
+		}
+	case c == '"':
+		end, _ := d.scanString()
+		if end < 0 {
+			return errJSONFallback
+		}
+		d.off = end
+		return nil
+	case isNumberStart(c):
+		d.scanNumber()
+		return nil
+	default:
+		_, err := d.literal()
+		return err
+	}
+}
+
+// scanString returns the offset following the string starting at the
+// current offset, or -1 if the string is not terminated. plain is true if the
+// string can be used without being unescaped.
+func (d *jsonDecoder) scanString() (end int, plain bool) {
+	plain = true
+	for i := d.off + 1; i < len(d.data); i++ {
+		switch c := d.data[i]; {
+		case c == '"':
+			if !plain {
+				return i + 1, false
+			}
+			return i + 1, utf8.Valid(d.data[d.off+1 : i])
+		case c == '\\':
+			plain = false
+			i++
+		case c < 0x20:
+			return -1, false
+		}
+	}
+	return -1, false
+}
+
+func (d *jsonDecoder) scanNumber() {
+	for d.off < len(d.data) {
+		switch c := d.data[d.off]; {
+		case c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E' || (c >= '0' && c <= '9'):
+			d.off++
+		default:
+			return
+		}
+	}
+}
+
+func isNumberStart(c byte) bool {
+	return c == '-' || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,226 @@
+package encoding
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeJSONEquivalent decodes data using DecodeJSON and by unmarshaling the
+// document into an interface{} and calling Decode, returning both results.
+func decodeJSONEquivalent(typ reflect.Type, data []byte, opts JSONOpts) (direct, indirect reflect.Value, directErr, indirectErr error) {
+	direct = reflect.New(typ)
+	directErr = DecodeJSON(direct.Interface(), data, opts)
+
+	indirect = reflect.New(typ)
+	var v interface{}
+	if indirectErr = json.Unmarshal(data, &v); indirectErr == nil {
+		if opts.ConvertPseudoTypes != nil {
+			v, indirectErr = opts.ConvertPseudoTypes(v)
+		}
+		if indirectErr == nil {
+			indirectErr = Decode(indirect.Interface(), v)
+		}
+	}
+
+	return direct, indirect, directErr, indirectErr
+}
+
+func TestDecodeJSON(t *testing.T) {
+	for i, tt := range decodeTests {
+		data, err := json.Marshal(tt.in)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+
+		direct, indirect, directErr, indirectErr := decodeJSONEquivalent(reflect.TypeOf(tt.ptr).Elem(), data, JSONOpts{})
+		if !jsonEqual(directErr, indirectErr) {
+			t.Errorf("#%d: got error %v want %v", i, directErr, indirectErr)
+			continue
+		}
+		if directErr == nil && !reflect.DeepEqual(direct.Elem().Interface(), indirect.Elem().Interface()) {
+			t.Errorf("#%d: mismatch\nhave: %#+v\nwant: %#+v", i, direct.Elem().Interface(), indirect.Elem().Interface())
+		}
+	}
+}
+
+type jsonRow struct {
+	ID       string          `rethinkdb:"id"`
+	Name     string          `rethinkdb:"name,omitempty"`
+	Age      int             `rethinkdb:"age"`
+	Score    float32         `rethinkdb:"score"`
+	Admin    bool            `rethinkdb:"admin"`
+	Created  time.Time       `rethinkdb:"created"`
+	Data     []byte          `rethinkdb:"data"`
+	Tags     []string        `rethinkdb:"tags"`
+	Extra    map[string]int  `rethinkdb:"extra"`
+	Meta     interface{}     `rethinkdb:"meta"`
+	Parent   *jsonRow        `rethinkdb:"parent"`
+	Children []jsonRow       `rethinkdb:"children"`
+	Attrs    map[string]bool `rethinkdb:"attrs"`
+}
+
+// convertTestPseudoTypes converts TIME objects in the same way as the driver.
+func convertTestPseudoTypes(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if v["$reql_type$"] == "TIME" {
+			return time.Unix(int64(v["epoch_time"].(float64)), 0).UTC(), nil
+		}
+		for k, e := range v {
+			e, err := convertTestPseudoTypes(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = e
+		}
+	case []interface{}:
+		for i, e := range v {
+			e, err := convertTestPseudoTypes(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = e
+		}
+	}
+	return v, nil
+}
+
+var decodeJSONTests = []struct {
+	in  string
+	ptr interface{}
+}{
+	{in: `{"id":"a","NAME":"Bob","age":30.9,"score":1.5,"admin":true,"tags":["x","y"],"extra":{"a":1},"meta":{"k":[1,"2",null]}}`, ptr: new(jsonRow)},
+	{in: `{"id":"a","created":{"$reql_type$":"TIME","epoch_time":1375147296,"timezone":"+00:00"}}`, ptr: new(jsonRow)},
+	{in: `{"id":"a","meta":{"$reql_type$":"TIME","epoch_time":1375147296,"timezone":"+00:00"}}`, ptr: new(jsonRow)},
+	{in: `{"id":"a","parent":{"id":"b","created":{"$reql_type$":"TIME","epoch_time":1,"timezone":"+00:00"}}}`, ptr: new(jsonRow)},
+	{in: `{"id":"a","parent":null,"children":[{"id":"b"},null,{"id":"c","age":"4"}]}`, ptr: new(jsonRow)},
+	{in: `{"id":"a","attrs":{"$reql_type$":"x"}}`, ptr: new(jsonRow)},
+	{in: `{"id":"a","data":"AAEC","tags":null,"extra":{}}`, ptr: new(jsonRow)},
+	{in: ` { "id" : "é\n" , "name":"a\"b", "age" : -1e2 } `, ptr: new(jsonRow)},
+	{in: `{"id":1,"age":"x"}`, ptr: new(jsonRow)},
+	{in: `{"id":"a","age":[1]}`, ptr: new(jsonRow)},
+	{in: `{"id":"a",}`, ptr: new(jsonRow)},
+	{in: `{"id":"a"} x`, ptr: new(jsonRow)},
+	{in: `{"id":"a"`, ptr: new(jsonRow)},
+	{in: `null`, ptr: new(jsonRow)},
+	{in: `[{"id":"a"},{"id":"b","parent":{"id":"c"}}]`, ptr: new([]*jsonRow)},
+	{in: `{"a":{"id":"a"},"b":null}`, ptr: new(map[string]jsonRow)},
+	{in: `{"id":["1","2"],"err_a[]":"3","err_b[":"4","err_c]":"5"}`, ptr: new(Compound)},
+	{in: `{"id":"1","NAME":"a","age":3,"tags":["x"],"meta":{"$reql_type$":"TIME","epoch_time":1,"timezone":"+00:00"}}`, ptr: new(Inline)},
+}
+
+func TestDecodeJSON_Documents(t *testing.T) {
+	opts := JSONOpts{ConvertPseudoTypes: convertTestPseudoTypes}
+	for i, tt := range decodeJSONTests {
+		direct, indirect, directErr, indirectErr := decodeJSONEquivalent(reflect.TypeOf(tt.ptr).Elem(), []byte(tt.in), opts)
+		if (directErr == nil) != (indirectErr == nil) {
+			t.Errorf("#%d: got error %v want %v", i, directErr, indirectErr)
+			continue
+		}
+		if directErr == nil && !reflect.DeepEqual(direct.Elem().Interface(), indirect.Elem().Interface()) {
+			t.Errorf("#%d: mismatch\nhave: %#+v\nwant: %#+v", i, direct.Elem().Interface(), indirect.Elem().Interface())
+		}
+	}
+}
+
+func TestDecodeJSON_Strict(t *testing.T) {
+	for i, tt := range decodeStrictTests {
+		opts := JSONOpts{DecodeOpts: tt.opts}
+		direct, indirect := new(strictRow), new(strictRow)
+		directErr := DecodeJSON(direct, []byte(tt.in), opts)
+
+		var v interface{}
+		if err := json.Unmarshal([]byte(tt.in), &v); err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		indirectErr := DecodeWithOpts(indirect, v, tt.opts)
+
+		if !jsonEqual(directErr, indirectErr) {
+			t.Errorf("#%d: got error %v want %v", i, directErr, indirectErr)
+			continue
+		}
+		if directErr == nil && !reflect.DeepEqual(direct, indirect) {
+			t.Errorf("#%d: mismatch\nhave: %#+v\nwant: %#+v", i, direct, indirect)
+		}
+	}
+
+	// Numbers are decoded exactly when using UseNumber
+	opts := JSONOpts{UseNumber: true, DecodeOpts: DecodeOpts{DisallowLossyConversion: true}}
+	var row strictRow
+	err := DecodeJSON(&row, []byte(`{"count":1e2,"score":0.5,"size":4294967297}`), opts)
+	if err != nil || row.Count != 100 || row.Score != 0.5 || strconv.IntSize == 64 && row.Size != 4294967297 {
+		t.Errorf("got %+v, %v", row, err)
+	}
+	err = DecodeJSON(&row, []byte(`{"count":1.5}`), opts)
+	if err == nil || !strings.Contains(err.Error(), `json.Number value 1.5 into Go value of type int8 without loss for field "count"`) {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestDecodeJSON_UseNumber(t *testing.T) {
+	var row struct {
+		Int    int64       `rethinkdb:"int"`
+		String string      `rethinkdb:"string"`
+		Number interface{} `rethinkdb:"number"`
+	}
+	err := DecodeJSON(&row, []byte(`{"int":9007199254740993,"string":1.50,"number":2}`), JSONOpts{UseNumber: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.Int != 9007199254740993 || row.String != "1.50" || row.Number != json.Number("2") {
+		t.Errorf("got %+v", row)
+	}
+}
+
+func TestCanDecodeJSON(t *testing.T) {
+	tests := []struct {
+		v  interface{}
+		ok bool
+	}{
+		{jsonRow{}, true},
+		{&jsonRow{}, true},
+		{[]jsonRow{}, true},
+		{map[string]interface{}{}, true},
+		{time.Time{}, false},
+		{[]byte{}, false},
+		{UnmarshalerValue{}, false},
+		{new(interface{}), false},
+		{1, false},
+	}
+	for _, tt := range tests {
+		if ok := CanDecodeJSON(reflect.TypeOf(tt.v)); ok != tt.ok {
+			t.Errorf("CanDecodeJSON(%T) = %v, want %v", tt.v, ok, tt.ok)
+		}
+	}
+}
+
+var benchmarkJSONRow = []byte(`{"id":"7c3c5a3e-1f5c-4d7a-9e0f-8a4c2f3c1b2a","name":"Bob","age":30,"score":1.5,"admin":true,` +
+	`"tags":["a","b","c"],"extra":{"a":1,"b":2},"attrs":{"x":true},"children":[{"id":"a"},{"id":"b"}]}`)
+
+func BenchmarkDecodeJSON(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var row jsonRow
+		if err := DecodeJSON(&row, benchmarkJSONRow, JSONOpts{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeJSON_Interface(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var v interface{}
+		if err := json.Unmarshal(benchmarkJSONRow, &v); err != nil {
+			b.Fatal(err)
+		}
+		var row jsonRow
+		if err := Decode(&row, v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
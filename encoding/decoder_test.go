@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"image"
+	"math"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -459,6 +461,213 @@ func TestDecodeCompoundKey(t *testing.T) {
 	}
 }
 
+func TestDecodeInline(t *testing.T) {
+	input := map[string]interface{}{"id": "1", "NAME": "a", "age": 3, "tags": []interface{}{"x"}}
+	want := Inline{ID: "1", Name: "a", Extra: map[string]interface{}{"age": 3, "tags": []interface{}{"x"}}}
+
+	out := Inline{}
+	err := Decode(&out, input)
+	if err != nil {
+		t.Errorf("got error %v, expected nil", err)
+	}
+	if !jsonEqual(out, want) {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	// Unknown fields are kept when the document is encoded again
+	encoded, err := Encode(out)
+	if err != nil {
+		t.Errorf("got error %v, expected nil", err)
+	}
+	if !jsonEqual(encoded, map[string]interface{}{"id": "1", "name": "a", "age": 3, "tags": []interface{}{"x"}}) {
+		t.Errorf("got %q", encoded)
+	}
+}
+
+func TestDecodeInlineTyped(t *testing.T) {
+	var out struct {
+		ID     string         `rethinkdb:"id"`
+		Counts map[string]int `rethinkdb:",inline"`
+	}
+	err := Decode(&out, map[string]interface{}{"id": "1", "a": 1.0, "b": nil})
+	if err != nil {
+		t.Errorf("got error %v, expected nil", err)
+	}
+	if out.ID != "1" || !reflect.DeepEqual(out.Counts, map[string]int{"a": 1, "b": 0}) {
+		t.Errorf("got %+v", out)
+	}
+
+	err = Decode(&out, map[string]interface{}{"c": "x"})
+	if err == nil {
+		t.Errorf("got nil error, expected error decoding string into int")
+	}
+}
+
+func TestNamingStrategy(t *testing.T) {
+	NamingStrategy = SnakeCase
+	defer func() { NamingStrategy = nil }()
+
+	type snakeCase struct {
+		UserID    string
+		HTTPPort  int
+		FirstName string `rethinkdb:",omitempty"`
+		Tagged    string `rethinkdb:"Tagged"`
+	}
+
+	input := snakeCase{"1", 80, "", "t"}
+	want := map[string]interface{}{"user_id": "1", "http_port": 80, "Tagged": "t"}
+	out, err := Encode(input)
+	if err != nil {
+		t.Errorf("got error %v, expected nil", err)
+	}
+	if !jsonEqual(out, want) {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	var decoded snakeCase
+	err = Decode(&decoded, map[string]interface{}{"user_id": "2", "first_name": "a"})
+	if err != nil {
+		t.Errorf("got error %v, expected nil", err)
+	}
+	if decoded.UserID != "2" || decoded.FirstName != "a" {
+		t.Errorf("got %+v", decoded)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"ID", "id"},
+		{"Name", "name"},
+		{"UserID", "user_id"},
+		{"HTTPServer", "http_server"},
+		{"Field1", "field1"},
+		{"Field1Name", "field1_name"},
+		{"already_snake", "already_snake"},
+		{"Snake_Case", "snake_case"},
+		{"ÉtéAB", "été_ab"},
+	}
+	for _, tt := range tests {
+		if got := SnakeCase(tt.in); got != tt.want {
+			t.Errorf("SnakeCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+type strictRow struct {
+	ID       string         `rethinkdb:"id"`
+	Count    int8           `rethinkdb:"count"`
+	Size     uint           `rethinkdb:"size"`
+	Ratio    float32        `rethinkdb:"ratio"`
+	Score    float64        `rethinkdb:"score"`
+	Active   bool           `rethinkdb:"active"`
+	Ignored  string         `rethinkdb:"-"`
+	Children []strictRow    `rethinkdb:"children"`
+	Counts   map[string]int `rethinkdb:"counts"`
+	Parent   *strictRow     `rethinkdb:"parent"`
+	Any      interface{}    `rethinkdb:"any"`
+	Key      int            `rethinkdb:"key[1]"`
+}
+
+var decodeStrictTests = []struct {
+	in   string
+	opts DecodeOpts
+	err  string
+}{
+	{in: `{"id":"a","count":1,"size":2,"ratio":1.5,"score":0.1,"active":true,"children":[{"id":"b"}],"any":{"x":1.5}}`, opts: DecodeOpts{true, true}},
+	{in: `{"id":"a","extra":1}`, opts: DecodeOpts{DisallowLossyConversion: true}},
+	{in: `{"id":"a","extra":1}`, opts: DecodeOpts{DisallowUnknownFields: true}, err: `rethinkdb: unknown field "extra" decoding Go value of type encoding.strictRow`},
+	{in: `{"ID":"a","Ignored":"b"}`, opts: DecodeOpts{DisallowUnknownFields: true}, err: `unknown field "Ignored"`},
+	{in: `{"children":[{"id":"b"},{"id":"c","x":1}]}`, opts: DecodeOpts{DisallowUnknownFields: true}, err: `unknown field "children[1].x"`},
+	{in: `{"parent":{"parent":{"x":1}}}`, opts: DecodeOpts{DisallowUnknownFields: true}, err: `unknown field "parent.parent.x"`},
+	{in: `{"count":1.5}`, opts: DecodeOpts{DisallowUnknownFields: true}},
+	{in: `{"count":1.5}`, opts: DecodeOpts{DisallowLossyConversion: true}, err: `rethinkdb: cannot decode float64 value 1.5 into Go value of type int8 without loss for field "count"`},
+	{in: `{"count":128}`, opts: DecodeOpts{DisallowLossyConversion: true}, err: `type int8 without loss for field "count"`},
+	{in: `{"count":-128}`, opts: DecodeOpts{DisallowLossyConversion: true}},
+	{in: `{"size":-1}`, opts: DecodeOpts{DisallowLossyConversion: true}, err: `for field "size"`},
+	{in: `{"ratio":1e39}`, opts: DecodeOpts{DisallowLossyConversion: true}, err: `for field "ratio"`},
+	{in: `{"id":1}`, opts: DecodeOpts{DisallowLossyConversion: true}, err: `cannot decode float64 value 1 into Go value of type string`},
+	{in: `{"active":"true"}`, opts: DecodeOpts{DisallowLossyConversion: true}, err: `cannot decode string value true into Go value of type bool`},
+	{in: `{"score":true}`, opts: DecodeOpts{DisallowLossyConversion: true}, err: `for field "score"`},
+	{in: `{"children":[{},{"count":0.5}]}`, opts: DecodeOpts{DisallowLossyConversion: true}, err: `for field "children[1].count"`},
+	{in: `{"counts":{"a":1,"b":2.5}}`, opts: DecodeOpts{DisallowLossyConversion: true}, err: `for field "counts.b"`},
+	{in: `{"key":[1,2.5]}`, opts: DecodeOpts{DisallowLossyConversion: true}, err: `for field "key[1]"`},
+	{in: `{"score":{"a":1}}`, opts: DecodeOpts{DisallowLossyConversion: true}, err: `could not decode type map[string]interface {}`},
+}
+
+func TestDecodeWithOpts(t *testing.T) {
+	for i, tt := range decodeStrictTests {
+		var src interface{}
+		if err := json.Unmarshal([]byte(tt.in), &src); err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+
+		var out strictRow
+		err := DecodeWithOpts(&out, src, tt.opts)
+		if tt.err == "" && err != nil {
+			t.Errorf("#%d: got error %v, expected nil", i, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("#%d: got error %v, expected %q", i, err, tt.err)
+		}
+
+		// The strict options are ignored by Decode
+		if err := Decode(&out, src); err != nil && tt.err != "" && !strings.Contains(tt.err, "could not decode") {
+			t.Errorf("#%d: Decode returned error %v", i, err)
+		}
+	}
+}
+
+func TestDecodeWithOpts_Exact(t *testing.T) {
+	opts := DecodeOpts{DisallowLossyConversion: true}
+	tests := []struct {
+		ptr interface{}
+		in  interface{}
+		ok  bool
+	}{
+		{new(int64), int32(-5), true},
+		{new(int8), int64(1000), false},
+		{new(uint8), int(255), true},
+		{new(uint8), int(-1), false},
+		{new(int64), uint64(1 << 63), false},
+		{new(int64), uint64(1<<63 - 1), true},
+		{new(uint16), uint64(1 << 16), false},
+		{new(float64), int64(1 << 53), true},
+		{new(float64), int64(1<<53 + 1), false},
+		{new(float64), int64(1<<63 - 1), false},
+		{new(float32), int(1 << 24), true},
+		{new(float32), int(1<<24 + 1), false},
+		{new(float64), uint64(1<<64 - 1), false},
+		{new(int64), 1e300, false},
+		{new(uint64), -0.0, true},
+		{new(int), math.NaN(), false},
+		{new(int64), json.Number("9007199254740993"), true},
+		{new(uint64), json.Number("18446744073709551615"), true},
+		{new(int), json.Number("1e3"), true},
+		{new(int), json.Number("1.5"), false},
+		{new(float32), json.Number("1e39"), false},
+		{new(string), json.Number("1"), false},
+		{new(interface{}), json.Number("1.5"), true},
+	}
+	for _, tt := range tests {
+		err := DecodeWithOpts(tt.ptr, tt.in, opts)
+		if (err == nil) != tt.ok {
+			t.Errorf("decoding %T %v into %T: got error %v", tt.in, tt.in, tt.ptr, err)
+		}
+		if err != nil {
+			if _, ok := err.(*LossyConversionError); !ok {
+				t.Errorf("decoding %T %v into %T: got error %T, expected LossyConversionError", tt.in, tt.in, tt.ptr, err)
+			}
+		}
+	}
+
+	var i int64
+	if err := DecodeWithOpts(&i, json.Number("9007199254740993"), opts); err != nil || i != 9007199254740993 {
+		t.Errorf("got %d, %v", i, err)
+	}
+}
+
 func TestDecodeNilSlice(t *testing.T) {
 	input := map[string]interface{}{"X": nil}
 	want := SliceStruct{}
@@ -3,19 +3,36 @@ package encoding
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"reflect"
 	"strconv"
 )
 
 // newTypeDecoder constructs an decoderFunc for a type.
-func newTypeDecoder(dt, st reflect.Type, blank bool) decoderFunc {
+func (c *Codec) newTypeDecoder(dt, st reflect.Type, opts decoderOpts) decoderFunc {
+	if reflect.PointerTo(dt).Implements(stateUnmarshalerType) ||
+		dt.Implements(stateUnmarshalerType) {
+		return newStateUnmarshalerDecoder(&DecodeState{codec: c, opts: opts})
+	}
 	if reflect.PointerTo(dt).Implements(unmarshalerType) ||
 		dt.Implements(unmarshalerType) {
 		return unmarshalerDecoder
 	}
 
+	return c.newValueDecoder(dt, st, opts)
+}
+
+// newValueDecoder constructs an decoderFunc for a type without checking if
+// the type implements Unmarshaler.
+func (c *Codec) newValueDecoder(dt, st reflect.Type, opts decoderOpts) decoderFunc {
 	if st.Kind() == reflect.Interface {
-		return newInterfaceAsTypeDecoder(blank)
+		return c.newInterfaceAsTypeDecoder(opts)
+	}
+
+	if opts.DisallowLossyConversion {
+		if dec := newExactDecoder(dt, st); dec != nil {
+			return dec
+		}
 	}
 
 	switch dt.Kind() {
@@ -101,7 +118,7 @@ func newTypeDecoder(dt, st reflect.Type, blank bool) decoderFunc {
 
 		return interfaceDecoder
 	case reflect.Ptr:
-		return newPtrDecoder(dt, st, blank)
+		return c.newPtrDecoder(dt, st, opts)
 	case reflect.Map:
 		if st.AssignableTo(dt) {
 			return interfaceDecoder
@@ -109,7 +126,7 @@ func newTypeDecoder(dt, st reflect.Type, blank bool) decoderFunc {
 
 		switch st.Kind() {
 		case reflect.Map:
-			return newMapAsMapDecoder(dt, st, blank)
+			return c.newMapAsMapDecoder(dt, st, opts)
 		default:
 			return decodeTypeError
 		}
@@ -124,7 +141,7 @@ func newTypeDecoder(dt, st reflect.Type, blank bool) decoderFunc {
 				return newDecodeTypeError(fmt.Errorf("map needs string keys"))
 			}
 
-			return newMapAsStructDecoder(dt, st, blank)
+			return c.newMapAsStructDecoder(dt, st, opts)
 		default:
 			return decodeTypeError
 		}
@@ -135,7 +152,7 @@ func newTypeDecoder(dt, st reflect.Type, blank bool) decoderFunc {
 
 		switch st.Kind() {
 		case reflect.Array, reflect.Slice:
-			return newSliceDecoder(dt, st)
+			return c.newSliceDecoder(dt, st, opts)
 		default:
 			return decodeTypeError
 		}
@@ -146,7 +163,7 @@ func newTypeDecoder(dt, st reflect.Type, blank bool) decoderFunc {
 
 		switch st.Kind() {
 		case reflect.Array, reflect.Slice:
-			return newArrayDecoder(dt, st)
+			return c.newArrayDecoder(dt, st, opts)
 		default:
 			return decodeTypeError
 		}
@@ -186,14 +203,14 @@ func interfaceDecoder(dv, sv reflect.Value) error {
 	return nil
 }
 
-func newInterfaceAsTypeDecoder(blank bool) decoderFunc {
+func (c *Codec) newInterfaceAsTypeDecoder(opts decoderOpts) decoderFunc {
 	return func(dv, sv reflect.Value) error {
 		if !sv.IsNil() {
 			dv = indirect(dv, false)
-			if blank {
+			if opts.blank {
 				dv.Set(reflect.Zero(dv.Type()))
 			}
-			return decodeValue(dv, sv.Elem(), blank)
+			return c.decodeValue(dv, sv.Elem(), opts)
 		}
 		return nil
 	}
@@ -210,8 +227,8 @@ func (d *ptrDecoder) decode(dv, sv reflect.Value) error {
 	return err
 }
 
-func newPtrDecoder(dt, st reflect.Type, blank bool) decoderFunc {
-	dec := &ptrDecoder{typeDecoder(dt.Elem(), st, blank)}
+func (c *Codec) newPtrDecoder(dt, st reflect.Type, opts decoderOpts) decoderFunc {
+	dec := &ptrDecoder{c.typeDecoder(dt.Elem(), st, opts)}
 
 	return dec.decode
 }
@@ -234,6 +251,29 @@ func unmarshalerDecoder(dv, sv reflect.Value) error {
 	return nil
 }
 
+// newStateUnmarshalerDecoder returns the decoder of the types implementing
+// StateUnmarshaler, the errors returned by strict decoding are returned as is
+// so that they have the path of the field.
+func newStateUnmarshalerDecoder(d *DecodeState) decoderFunc {
+	return func(dv, sv reflect.Value) error {
+		if dv.Kind() != reflect.Ptr && dv.Type().Name() != "" && dv.CanAddr() {
+			dv = dv.Addr()
+		}
+
+		if dv.IsNil() {
+			dv.Set(reflect.New(dv.Type().Elem()))
+		}
+
+		u := dv.Interface().(StateUnmarshaler)
+		err := u.UnmarshalRQLState(sv.Interface(), d)
+		switch err.(type) {
+		case nil, *UnknownFieldError, *LossyConversionError:
+			return err
+		}
+		return &DecodeTypeError{dv.Type(), sv.Type(), err.Error()}
+	}
+}
+
 // Boolean decoders
 
 func boolAsBoolDecoder(dv, sv reflect.Value) error {
@@ -407,8 +447,8 @@ func (d *sliceDecoder) decode(dv, sv reflect.Value) error {
 	return nil
 }
 
-func newSliceDecoder(dt, st reflect.Type) decoderFunc {
-	dec := &sliceDecoder{newArrayDecoder(dt, st)}
+func (c *Codec) newSliceDecoder(dt, st reflect.Type, opts decoderOpts) decoderFunc {
+	dec := &sliceDecoder{c.newArrayDecoder(dt, st, opts)}
 	return dec.decode
 }
 
@@ -441,7 +481,7 @@ func (d *arrayDecoder) decode(dv, sv reflect.Value) error {
 			// Decode into element.
 			err := d.elemDec(dv.Index(i), sv.Index(i))
 			if err != nil {
-				return err
+				return withFieldPath(err, "["+strconv.Itoa(i)+"]")
 			}
 		}
 
@@ -463,8 +503,10 @@ func (d *arrayDecoder) decode(dv, sv reflect.Value) error {
 	return nil
 }
 
-func newArrayDecoder(dt, st reflect.Type) decoderFunc {
-	dec := &arrayDecoder{typeDecoder(dt.Elem(), st.Elem(), true)}
+func (c *Codec) newArrayDecoder(dt, st reflect.Type, opts decoderOpts) decoderFunc {
+	// The elements are always reset before being decoded
+	opts.blank = true
+	dec := &arrayDecoder{c.typeDecoder(dt.Elem(), st.Elem(), opts)}
 	return dec.decode
 }
 
@@ -511,7 +553,7 @@ func (d *mapAsMapDecoder) decode(dv, sv reflect.Value) error {
 		}
 		err = d.elemDec(dElemVal, sv.MapIndex(sElemKey))
 		if err != nil {
-			return err
+			return withFieldPath(err, fmt.Sprint(sElemKey.Interface()))
 		}
 
 		dv.SetMapIndex(dElemKey, dElemVal)
@@ -519,15 +561,17 @@ func (d *mapAsMapDecoder) decode(dv, sv reflect.Value) error {
 	return nil
 }
 
-func newMapAsMapDecoder(dt, st reflect.Type, blank bool) decoderFunc {
-	d := &mapAsMapDecoder{typeDecoder(dt.Key(), st.Key(), blank), typeDecoder(dt.Elem(), st.Elem(), blank), blank}
+func (c *Codec) newMapAsMapDecoder(dt, st reflect.Type, opts decoderOpts) decoderFunc {
+	d := &mapAsMapDecoder{c.typeDecoder(dt.Key(), st.Key(), opts), c.typeDecoder(dt.Elem(), st.Elem(), opts), opts.blank}
 	return d.decode
 }
 
 type mapAsStructDecoder struct {
+	codec     *Codec
 	fields    []field
 	fieldDecs []decoderFunc
-	blank     bool
+	inline    int
+	opts      decoderOpts
 }
 
 func (d *mapAsStructDecoder) decode(dv, sv reflect.Value) error {
@@ -539,6 +583,9 @@ func (d *mapAsStructDecoder) decode(dv, sv reflect.Value) error {
 		for i := range d.fields {
 			ff := &d.fields[i]
 			ffd := d.fieldDecs[i]
+			if ff.inline {
+				continue
+			}
 
 			if bytes.Equal(ff.nameBytes, key) {
 				f = ff
@@ -565,7 +612,7 @@ func (d *mapAsStructDecoder) decode(dv, sv reflect.Value) error {
 					sElemVal = sElemVal.Elem()
 				}
 				sElemVal = sElemVal.Index(compoundField.compoundIndex)
-				fieldDec = typeDecoder(dElemVal.Type(), sElemVal.Type(), d.blank)
+				fieldDec = d.codec.typeDecoder(dElemVal.Type(), sElemVal.Type(), d.opts)
 
 				if !sElemVal.IsValid() || !dElemVal.CanSet() {
 					continue
@@ -573,7 +620,7 @@ func (d *mapAsStructDecoder) decode(dv, sv reflect.Value) error {
 
 				err := fieldDec(dElemVal, sElemVal)
 				if err != nil {
-					return err
+					return withFieldPath(err, compoundField.name+"["+strconv.Itoa(compoundField.compoundIndex)+"]")
 				}
 			}
 		} else if f != nil {
@@ -586,22 +633,210 @@ func (d *mapAsStructDecoder) decode(dv, sv reflect.Value) error {
 
 			err := fieldDec(dElemVal, sElemVal)
 			if err != nil {
-				return err
+				return withFieldPath(err, f.name)
+			}
+		} else if d.inline >= 0 {
+			err := d.decodeInline(dv, kv, sv.MapIndex(kv))
+			if err != nil {
+				return withFieldPath(err, kv.String())
 			}
+		} else if d.opts.DisallowUnknownFields {
+			return &UnknownFieldError{Path: kv.String(), Type: dv.Type()}
 		}
 	}
 	return nil
 }
 
-func newMapAsStructDecoder(dt, st reflect.Type, blank bool) decoderFunc {
-	fields := cachedTypeFields(dt)
+// decodeInline decodes a value which does not match any of the fields of the
+// struct into the inline map.
+func (d *mapAsStructDecoder) decodeInline(dv, kv, sElemVal reflect.Value) error {
+	f := d.fields[d.inline]
+	dMap := fieldByIndex(dv, f.index)
+	if !dMap.CanSet() {
+		return nil
+	}
+	if dMap.IsNil() {
+		dMap.Set(reflect.MakeMap(f.typ))
+	}
+
+	dElemVal := reflect.New(f.typ.Elem()).Elem()
+	err := d.fieldDecs[d.inline](dElemVal, sElemVal)
+	if err != nil {
+		return err
+	}
+
+	dMap.SetMapIndex(reflect.ValueOf(kv.String()).Convert(f.typ.Key()), dElemVal)
+	return nil
+}
+
+func (c *Codec) newMapAsStructDecoder(dt, st reflect.Type, opts decoderOpts) decoderFunc {
+	fields := c.cachedTypeFields(dt)
 	se := &mapAsStructDecoder{
+		codec:     c,
 		fields:    fields,
 		fieldDecs: make([]decoderFunc, len(fields)),
-		blank:     blank,
+		inline:    -1,
+		opts:      opts,
 	}
 	for i, f := range fields {
-		se.fieldDecs[i] = typeDecoder(typeByIndex(dt, f.index), st.Elem(), blank)
+		if f.inline {
+			// The elements of the inline map are decoded from the values
+			// of the unknown fields
+			se.inline = i
+			se.fieldDecs[i] = c.typeDecoder(f.typ.Elem(), st.Elem(), opts)
+			continue
+		}
+		se.fieldDecs[i] = c.typeDecoder(typeByIndex(dt, f.index), st.Elem(), opts)
 	}
 	return se.decode
 }
+
+// Exact decoders, used when lossy conversions are disallowed
+
+// newExactDecoder returns the decoder for booleans, numbers and strings which
+// only decodes values which can be stored without changing them. It returns
+// nil if the destination is not a boolean, number or string.
+func newExactDecoder(dt, st reflect.Type) decoderFunc {
+	if st == jsonNumberType {
+		switch dt.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64:
+			return exactNumberDecoder
+		case reflect.Bool, reflect.String:
+			return lossyConversionError
+		}
+		return nil
+	}
+
+	switch dt.Kind() {
+	case reflect.Bool:
+		if st.Kind() == reflect.Bool {
+			return boolAsBoolDecoder
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch st.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return exactIntAsIntDecoder
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return exactUintAsIntDecoder
+		case reflect.Float32, reflect.Float64:
+			return exactFloatAsIntDecoder
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch st.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return exactIntAsUintDecoder
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return exactUintAsUintDecoder
+		case reflect.Float32, reflect.Float64:
+			return exactFloatAsUintDecoder
+		}
+	case reflect.Float32, reflect.Float64:
+		switch st.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return exactIntAsFloatDecoder
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return exactUintAsFloatDecoder
+		case reflect.Float32, reflect.Float64:
+			return exactFloatAsFloatDecoder
+		}
+	case reflect.String:
+		if st.Kind() == reflect.String {
+			return stringAsStringDecoder
+		}
+	default:
+		return nil
+	}
+
+	switch st.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return lossyConversionError
+	default:
+		return decodeTypeError
+	}
+}
+
+func lossyConversionError(dv, sv reflect.Value) error {
+	return &LossyConversionError{Value: sv.Interface(), Type: dv.Type()}
+}
+
+// exactNumberDecoder decodes a json.Number, as returned when decoding using
+// UseNumber, into a number.
+func exactNumberDecoder(dv, sv reflect.Value) error {
+	var nv reflect.Value
+	if i, err := strconv.ParseInt(sv.String(), 10, 64); err == nil {
+		nv = reflect.ValueOf(i)
+	} else if u, err := strconv.ParseUint(sv.String(), 10, 64); err == nil {
+		nv = reflect.ValueOf(u)
+	} else if f, err := strconv.ParseFloat(sv.String(), 64); err == nil {
+		nv = reflect.ValueOf(f)
+	} else {
+		return lossyConversionError(dv, sv)
+	}
+
+	err := newExactDecoder(dv.Type(), nv.Type())(dv, nv)
+	if err, ok := err.(*LossyConversionError); ok {
+		err.Value = sv.Interface()
+	}
+	return err
+}
+func exactIntAsIntDecoder(dv, sv reflect.Value) error {
+	if dv.OverflowInt(sv.Int()) {
+		return lossyConversionError(dv, sv)
+	}
+	return intAsIntDecoder(dv, sv)
+}
+func exactIntAsUintDecoder(dv, sv reflect.Value) error {
+	if i := sv.Int(); i < 0 || dv.OverflowUint(uint64(i)) {
+		return lossyConversionError(dv, sv)
+	}
+	return intAsUintDecoder(dv, sv)
+}
+func exactIntAsFloatDecoder(dv, sv reflect.Value) error {
+	i := sv.Int()
+	if f := float64(i); f >= math.MaxInt64 || int64(f) != i || dv.Kind() == reflect.Float32 && float64(float32(f)) != f {
+		return lossyConversionError(dv, sv)
+	}
+	return intAsFloatDecoder(dv, sv)
+}
+func exactUintAsIntDecoder(dv, sv reflect.Value) error {
+	if u := sv.Uint(); u > math.MaxInt64 || dv.OverflowInt(int64(u)) {
+		return lossyConversionError(dv, sv)
+	}
+	return uintAsIntDecoder(dv, sv)
+}
+func exactUintAsUintDecoder(dv, sv reflect.Value) error {
+	if dv.OverflowUint(sv.Uint()) {
+		return lossyConversionError(dv, sv)
+	}
+	return uintAsUintDecoder(dv, sv)
+}
+func exactUintAsFloatDecoder(dv, sv reflect.Value) error {
+	u := sv.Uint()
+	if f := float64(u); f >= math.MaxUint64 || uint64(f) != u || dv.Kind() == reflect.Float32 && float64(float32(f)) != f {
+		return lossyConversionError(dv, sv)
+	}
+	return uintAsFloatDecoder(dv, sv)
+}
+func exactFloatAsIntDecoder(dv, sv reflect.Value) error {
+	if f := sv.Float(); f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || dv.OverflowInt(int64(f)) {
+		return lossyConversionError(dv, sv)
+	}
+	return floatAsIntDecoder(dv, sv)
+}
+func exactFloatAsUintDecoder(dv, sv reflect.Value) error {
+	if f := sv.Float(); f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 || dv.OverflowUint(uint64(f)) {
+		return lossyConversionError(dv, sv)
+	}
+	return floatAsUintDecoder(dv, sv)
+}
+func exactFloatAsFloatDecoder(dv, sv reflect.Value) error {
+	if dv.OverflowFloat(sv.Float()) {
+		return lossyConversionError(dv, sv)
+	}
+	return floatAsFloatDecoder(dv, sv)
+}
@@ -3,9 +3,7 @@
 package encoding
 
 import (
-	"errors"
 	"reflect"
-	"runtime"
 	"sync"
 )
 
@@ -17,42 +15,24 @@ type encoderFunc func(v reflect.Value) (interface{}, error)
 // is found then it is checked for tagged fields and convert to
 // map[string]interface{}
 func Encode(v interface{}) (ev interface{}, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			if _, ok := r.(runtime.Error); ok {
-				panic(r)
-			}
-			if v, ok := r.(string); ok {
-				err = errors.New(v)
-			} else {
-				err = r.(error)
-			}
-		}
-	}()
-
-	return encode(reflect.ValueOf(v))
-}
-
-func encode(v reflect.Value) (interface{}, error) {
-	return valueEncoder(v)(v)
+	return defaultCodec.Encode(v)
 }
 
-var encoderCache struct {
-	sync.RWMutex
-	m map[reflect.Type]encoderFunc
+func (c *Codec) encode(v reflect.Value) (interface{}, error) {
+	return c.valueEncoder(v)(v)
 }
 
-func valueEncoder(v reflect.Value) encoderFunc {
+func (c *Codec) valueEncoder(v reflect.Value) encoderFunc {
 	if !v.IsValid() {
 		return invalidValueEncoder
 	}
-	return typeEncoder(v.Type())
+	return c.typeEncoder(v.Type())
 }
 
-func typeEncoder(t reflect.Type) encoderFunc {
-	encoderCache.RLock()
-	f := encoderCache.m[t]
-	encoderCache.RUnlock()
+func (c *Codec) typeEncoder(t reflect.Type) encoderFunc {
+	c.encoderCache.RLock()
+	f := c.encoderCache.m[t]
+	c.encoderCache.RUnlock()
 	if f != nil {
 		return f
 	}
@@ -62,21 +42,21 @@ func typeEncoder(t reflect.Type) encoderFunc {
 	// real func (f) to
 	//  be ready and then calls it.  This indirect
 	// func is only used for recursive types.
-	encoderCache.Lock()
+	c.encoderCache.Lock()
 	var wg sync.WaitGroup
 	wg.Add(1)
-	encoderCache.m[t] = func(v reflect.Value) (interface{}, error) {
+	c.encoderCache.m[t] = func(v reflect.Value) (interface{}, error) {
 		wg.Wait()
 		return f(v)
 	}
-	encoderCache.Unlock()
+	c.encoderCache.Unlock()
 
 	// Compute fields without lock.
 	// Might duplicate effort but won't hold other computations back.
-	f = newTypeEncoder(t, true)
+	f = c.newTypeEncoder(t, true)
 	wg.Done()
-	encoderCache.Lock()
-	encoderCache.m[t] = f
-	encoderCache.Unlock()
+	c.encoderCache.Lock()
+	c.encoderCache.m[t] = f
+	c.encoderCache.Unlock()
 	return f
 }
@@ -110,6 +110,62 @@ func TestOmitEmpty(t *testing.T) {
 	}
 }
 
+type OmitZero struct {
+	Sr string    `rethinkdb:"sr"`
+	Sz string    `rethinkdb:"sz,omitzero"`
+	Iz int       `rethinkdb:"iz,omitzero"`
+	Tz time.Time `rethinkdb:"tz,omitzero"`
+	Tl time.Time `rethinkdb:"tl,omitzero"`
+
+	Slz []string               `rethinkdb:"slz,omitzero"`
+	Sle []string               `rethinkdb:"sle,omitzero"`
+	Mz  map[string]interface{} `rethinkdb:"mz,omitzero"`
+	Me  map[string]interface{} `rethinkdb:"me,omitzero"`
+	Stz Point                  `rethinkdb:"stz,omitzero"`
+	Ste Point                  `rethinkdb:"ste,omitempty"`
+}
+
+var omitZeroExpected = map[string]interface{}{
+	"sr":  "",
+	"sle": []interface{}{},
+	"me":  map[string]interface{}{},
+	"ste": map[string]interface{}{"Z": int64(0)},
+}
+
+func TestOmitZero(t *testing.T) {
+	var o OmitZero
+	o.Tl = time.Time{}.In(time.FixedZone("", 3600)) // zero according to IsZero
+	o.Sle = []string{}
+	o.Me = map[string]interface{}{}
+
+	got, err := Encode(&o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !jsonEqual(got, omitZeroExpected) {
+		t.Errorf("\ngot:  %#v\nwant: %#v\n", got, omitZeroExpected)
+	}
+}
+
+type Inline struct {
+	ID    string                 `rethinkdb:"id"`
+	Name  string                 `rethinkdb:"name,omitempty"`
+	Extra map[string]interface{} `rethinkdb:",inline"`
+}
+
+func TestEncodeInline(t *testing.T) {
+	input := Inline{ID: "1", Extra: map[string]interface{}{"id": "2", "name": "a", "age": 3}}
+	want := map[string]interface{}{"id": "1", "name": "a", "age": 3}
+
+	out, err := Encode(input)
+	if err != nil {
+		t.Errorf("got error %v, expected nil", err)
+	}
+	if !jsonEqual(out, want) {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
 type IntType int
 
 type MyStruct struct {
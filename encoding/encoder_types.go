@@ -3,20 +3,27 @@ package encoding
 import (
 	"encoding/base64"
 	"fmt"
-	"math"
 	"reflect"
 	"time"
 )
 
 // newTypeEncoder constructs an encoderFunc for a type.
 // The returned encoder only checks CanAddr when allowAddr is true.
-func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
+func (c *Codec) newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
+	if t.Implements(stateMarshalerType) {
+		return newStateMarshalerEncoder(&EncodeState{codec: c}, false)
+	}
+	if t.Kind() != reflect.Ptr && allowAddr {
+		if reflect.PointerTo(t).Implements(stateMarshalerType) {
+			return newCondAddrEncoder(newStateMarshalerEncoder(&EncodeState{codec: c}, true), c.newTypeEncoder(t, false))
+		}
+	}
 	if t.Implements(marshalerType) {
 		return marshalerEncoder
 	}
 	if t.Kind() != reflect.Ptr && allowAddr {
 		if reflect.PointerTo(t).Implements(marshalerType) {
-			return newCondAddrEncoder(addrMarshalerEncoder, newTypeEncoder(t, false))
+			return newCondAddrEncoder(addrMarshalerEncoder, c.newTypeEncoder(t, false))
 		}
 	}
 
@@ -40,17 +47,17 @@ func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
 	case reflect.String:
 		return stringEncoder
 	case reflect.Interface:
-		return interfaceEncoder
+		return c.interfaceEncoder
 	case reflect.Struct:
-		return newStructEncoder(t)
+		return c.newStructEncoder(t)
 	case reflect.Map:
-		return newMapEncoder(t)
+		return c.newMapEncoder(t)
 	case reflect.Slice:
-		return newSliceEncoder(t)
+		return c.newSliceEncoder(t)
 	case reflect.Array:
-		return newArrayEncoder(t)
+		return c.newArrayEncoder(t)
 	case reflect.Ptr:
-		return newPtrEncoder(t)
+		return c.newPtrEncoder(t)
 	case reflect.Func:
 		// functions are a special case as they can be used internally for
 		// optional arguments. Just return the raw function, if somebody tries
@@ -97,6 +104,27 @@ func addrMarshalerEncoder(v reflect.Value) (interface{}, error) {
 	return ev, nil
 }
 
+// newStateMarshalerEncoder returns the encoder of the types implementing
+// StateMarshaler, if addr is true the method of the address of the value is
+// called.
+func newStateMarshalerEncoder(e *EncodeState, addr bool) encoderFunc {
+	return func(v reflect.Value) (interface{}, error) {
+		if addr {
+			v = v.Addr()
+		}
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil, nil
+		}
+		m := v.Interface().(StateMarshaler)
+		ev, err := m.MarshalRQLState(e)
+		if err != nil {
+			return nil, &MarshalerError{v.Type(), err}
+		}
+
+		return ev, nil
+	}
+}
+
 func boolEncoder(v reflect.Value) (interface{}, error) {
 	if v.Bool() {
 		return true, nil
@@ -125,11 +153,11 @@ func stringEncoder(v reflect.Value) (interface{}, error) {
 	return v.String(), nil
 }
 
-func interfaceEncoder(v reflect.Value) (interface{}, error) {
+func (c *Codec) interfaceEncoder(v reflect.Value) (interface{}, error) {
 	if v.IsNil() {
 		return nil, nil
 	}
-	return encode(v.Elem())
+	return c.encode(v.Elem())
 }
 
 func funcEncoder(v reflect.Value) (interface{}, error) {
@@ -154,9 +182,15 @@ type structEncoder struct {
 
 func (se *structEncoder) encode(v reflect.Value) (interface{}, error) {
 	m := make(map[string]interface{})
+	inline := -1
 	for i, f := range se.fields {
+		if f.inline {
+			inline = i
+			continue
+		}
+
 		fv := fieldByIndex(v, f.index)
-		if !fv.IsValid() || f.omitEmpty && se.isEmptyValue(fv) {
+		if !fv.IsValid() || f.omitEmpty && isEmptyField(fv) || f.omitZero && isZeroField(fv) {
 			continue
 		}
 
@@ -188,6 +222,21 @@ func (se *structEncoder) encode(v reflect.Value) (interface{}, error) {
 		m[f.name] = encField
 	}
 
+	if inline >= 0 {
+		// The unknown fields are added to the document unless they have the
+		// same name as one of the fields of the struct
+		encField, err := se.fieldEncs[inline](fieldByIndex(v, se.fields[inline].index))
+		if err != nil {
+			return nil, err
+		}
+		inlineFields, _ := encField.(map[string]interface{})
+		for k, e := range inlineFields {
+			if _, ok := m[k]; !ok {
+				m[k] = e
+			}
+		}
+	}
+
 	return m, nil
 }
 
@@ -225,7 +274,7 @@ func extractValue(encField interface{}, v reflect.Value, name string, refName st
 	return refVal
 }
 
-func (se *structEncoder) isEmptyValue(v reflect.Value) bool {
+func isEmptyField(v reflect.Value) bool {
 	if v.Type() == timeType {
 		return v.Interface().(time.Time) == time.Time{}
 	}
@@ -233,14 +282,33 @@ func (se *structEncoder) isEmptyValue(v reflect.Value) bool {
 	return isEmptyValue(v)
 }
 
-func newStructEncoder(t reflect.Type) encoderFunc {
-	fields := cachedTypeFields(t)
+var zeroerType = reflect.TypeOf((*interface{ IsZero() bool })(nil)).Elem()
+
+// isZeroField returns true if the value is the zero value of its type, as
+// used by the omitzero tag option. Values with an IsZero method, such as
+// time.Time, are zero if the method returns true.
+func isZeroField(v reflect.Value) bool {
+	if v.Type().Implements(zeroerType) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return true
+		}
+		return v.Interface().(interface{ IsZero() bool }).IsZero()
+	}
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(zeroerType) {
+		return v.Addr().Interface().(interface{ IsZero() bool }).IsZero()
+	}
+
+	return v.IsZero()
+}
+
+func (c *Codec) newStructEncoder(t reflect.Type) encoderFunc {
+	fields := c.cachedTypeFields(t)
 	se := &structEncoder{
 		fields:    fields,
 		fieldEncs: make([]encoderFunc, len(fields)),
 	}
 	for i, f := range fields {
-		se.fieldEncs[i] = typeEncoder(typeByIndex(t, f.index))
+		se.fieldEncs[i] = c.typeEncoder(typeByIndex(t, f.index))
 	}
 	return se.encode
 }
@@ -271,7 +339,7 @@ func (me *mapEncoder) encode(v reflect.Value) (interface{}, error) {
 	return m, nil
 }
 
-func newMapEncoder(t reflect.Type) encoderFunc {
+func (c *Codec) newMapEncoder(t reflect.Type) encoderFunc {
 	var keyEnc encoderFunc
 	switch t.Key().Kind() {
 	case reflect.Bool:
@@ -290,7 +358,7 @@ func newMapEncoder(t reflect.Type) encoderFunc {
 		return unsupportedTypeEncoder
 	}
 
-	me := &mapEncoder{keyEnc, typeEncoder(t.Elem())}
+	me := &mapEncoder{keyEnc, c.typeEncoder(t.Elem())}
 	return me.encode
 }
 
@@ -306,12 +374,12 @@ func (se *sliceEncoder) encode(v reflect.Value) (interface{}, error) {
 	return se.arrayEnc(v)
 }
 
-func newSliceEncoder(t reflect.Type) encoderFunc {
+func (c *Codec) newSliceEncoder(t reflect.Type) encoderFunc {
 	// Byte slices get special treatment; arrays don't.
 	if t.Elem().Kind() == reflect.Uint8 {
 		return encodeByteSlice
 	}
-	enc := &sliceEncoder{newArrayEncoder(t)}
+	enc := &sliceEncoder{c.newArrayEncoder(t)}
 	return enc.encode
 }
 
@@ -334,11 +402,11 @@ func (ae *arrayEncoder) encode(v reflect.Value) (interface{}, error) {
 	return a, nil
 }
 
-func newArrayEncoder(t reflect.Type) encoderFunc {
+func (c *Codec) newArrayEncoder(t reflect.Type) encoderFunc {
 	if t.Elem().Kind() == reflect.Uint8 {
 		return encodeByteArray
 	}
-	enc := &arrayEncoder{typeEncoder(t.Elem())}
+	enc := &arrayEncoder{c.typeEncoder(t.Elem())}
 	return enc.encode
 }
 
@@ -353,8 +421,8 @@ func (pe *ptrEncoder) encode(v reflect.Value) (interface{}, error) {
 	return pe.elemEnc(v.Elem())
 }
 
-func newPtrEncoder(t reflect.Type) encoderFunc {
-	enc := &ptrEncoder{typeEncoder(t.Elem())}
+func (c *Codec) newPtrEncoder(t reflect.Type) encoderFunc {
+	enc := &ptrEncoder{c.typeEncoder(t.Elem())}
 	return enc.encode
 }
 
@@ -383,13 +451,9 @@ func newCondAddrEncoder(canAddrEnc, elseEnc encoderFunc) encoderFunc {
 func timePseudoTypeEncoder(v reflect.Value) (interface{}, error) {
 	t := v.Interface().(time.Time)
 
-	timeVal := float64(t.UnixNano()) / float64(time.Second)
-
-	// use seconds-since-epoch precision if time.Time `t`
-	// is before the oldest nanosecond time
-	if t.Before(time.Unix(0, math.MinInt64)) {
-		timeVal = float64(t.Unix())
-	}
+	// Add the fractional seconds separately to keep the sub-second precision
+	// of times far from the epoch
+	timeVal := float64(t.Unix()) + float64(t.Nanosecond())/float64(time.Second)
 
 	return map[string]interface{}{
 		"$reql_type$": "TIME",
@@ -1,6 +1,7 @@
 package encoding
 
 import (
+	"encoding/json"
 	"reflect"
 	"time"
 )
@@ -9,11 +10,14 @@ var (
 	// type constants
 	timeType = reflect.TypeOf(new(time.Time)).Elem()
 
-	marshalerType   = reflect.TypeOf(new(Marshaler)).Elem()
-	unmarshalerType = reflect.TypeOf(new(Unmarshaler)).Elem()
+	marshalerType        = reflect.TypeOf(new(Marshaler)).Elem()
+	stateMarshalerType   = reflect.TypeOf(new(StateMarshaler)).Elem()
+	unmarshalerType      = reflect.TypeOf(new(Unmarshaler)).Elem()
+	stateUnmarshalerType = reflect.TypeOf(new(StateUnmarshaler)).Elem()
 
 	emptyInterfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
 	mapInterfaceType   = reflect.TypeOf((map[string]interface{})(nil))
+	jsonNumberType     = reflect.TypeOf(json.Number(""))
 )
 
 // Marshaler is the interface implemented by objects that
@@ -28,41 +32,17 @@ type Unmarshaler interface {
 	UnmarshalRQL(interface{}) error
 }
 
-func init() {
-	encoderCache.m = make(map[reflect.Type]encoderFunc)
-	decoderCache.m = make(map[decoderCacheKey]decoderFunc)
-}
-
 // IgnoreType causes the encoder to ignore a type when encoding
 func IgnoreType(t reflect.Type) {
-	encoderCache.Lock()
-	encoderCache.m[t] = doNothingEncoder
-	encoderCache.Unlock()
+	defaultCodec.IgnoreType(t)
 }
 
+// SetTypeEncoding sets the functions used to encode and decode values of the
+// type t.
 func SetTypeEncoding(
 	t reflect.Type,
 	encode func(value interface{}) (interface{}, error),
 	decode func(encoded interface{}, value reflect.Value) error,
 ) {
-	encoderCache.Lock()
-	encoderCache.m[t] = func(v reflect.Value) (interface{}, error) {
-		return encode(v.Interface())
-	}
-	encoderCache.Unlock()
-
-	dec := func(dv reflect.Value, sv reflect.Value) error {
-		return decode(sv.Interface(), dv)
-	}
-	decoderCache.Lock()
-	// decode as pointer
-	decoderCache.m[decoderCacheKey{dt: t, st: emptyInterfaceType}] = dec
-	// decode as value
-	decoderCache.m[decoderCacheKey{dt: t, st: mapInterfaceType}] = dec
-
-	if t.Kind() == reflect.Ptr {
-		decoderCache.m[decoderCacheKey{dt: t.Elem(), st: emptyInterfaceType}] = dec
-		decoderCache.m[decoderCacheKey{dt: t.Elem(), st: mapInterfaceType}] = dec
-	}
-	decoderCache.Unlock()
+	defaultCodec.SetTypeEncoding(t, encode, decode)
 }
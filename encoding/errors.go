@@ -3,6 +3,7 @@ package encoding
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -90,3 +91,56 @@ func (e *Error) Error() string {
 		"%d error(s) decoding:\n\n%s",
 		len(e.Errors), strings.Join(points, "\n"))
 }
+
+// An UnknownFieldError is returned when decoding with DisallowUnknownFields
+// and an object has a field which does not match any field of the struct.
+type UnknownFieldError struct {
+	// Path is the path of the unknown field, such as "author.tags".
+	Path string
+	Type reflect.Type
+}
+
+func (e *UnknownFieldError) Error() string {
+	return "rethinkdb: unknown field " + strconv.Quote(e.Path) + " decoding Go value of type " + e.Type.String()
+}
+
+// A LossyConversionError is returned when decoding with
+// DisallowLossyConversion and a value cannot be stored in the Go value
+// without changing it.
+type LossyConversionError struct {
+	// Path is the path of the field the value was decoded into, it is empty
+	// if the value was not decoded into a field.
+	Path  string
+	Value interface{}
+	Type  reflect.Type
+}
+
+func (e *LossyConversionError) Error() string {
+	msg := fmt.Sprintf("rethinkdb: cannot decode %T value %v into Go value of type %s without loss", e.Value, e.Value, e.Type)
+	if e.Path != "" {
+		msg += " for field " + strconv.Quote(e.Path)
+	}
+	return msg
+}
+
+// withFieldPath adds the name of the field, or the index of the element, the
+// value was decoded into to the path of errors returned by strict decoding.
+func withFieldPath(err error, name string) error {
+	switch err := err.(type) {
+	case *UnknownFieldError:
+		err.Path = joinFieldPath(name, err.Path)
+	case *LossyConversionError:
+		err.Path = joinFieldPath(name, err.Path)
+	}
+	return err
+}
+
+func joinFieldPath(name, path string) string {
+	if path == "" {
+		return name
+	}
+	if path[0] == '[' {
+		return name + path
+	}
+	return name + "." + path
+}
@@ -0,0 +1,247 @@
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// The functions in this file are used by the MarshalRQL and UnmarshalRQL
+// methods generated by cmd/rethinkdb-gen, so that the generated methods behave
+// in the same way as the reflection based encoder and decoder. They should not
+// be needed by other code.
+
+// IsEmptyField returns true if the value pointed to by ptr is empty, as used
+// by the omitempty tag option.
+func IsEmptyField(ptr interface{}) bool {
+	return isEmptyField(reflect.ValueOf(ptr).Elem())
+}
+
+// IsZeroField returns true if the value pointed to by ptr is the zero value,
+// as used by the omitzero tag option.
+func IsZeroField(ptr interface{}) bool {
+	return isZeroField(reflect.ValueOf(ptr).Elem())
+}
+
+// StateMarshaler is implemented by the types with methods generated by
+// cmd/rethinkdb-gen. The encoder calls MarshalRQLState instead of MarshalRQL,
+// with the codec the value is encoded with.
+type StateMarshaler interface {
+	MarshalRQLState(e *EncodeState) (interface{}, error)
+}
+
+// EncodeState is the codec a value is encoded with, it is passed to
+// MarshalRQLState so that the values the generated methods do not encode
+// themselves are encoded in the same way as the rest of the document. A nil
+// EncodeState encodes using the default codec.
+type EncodeState struct {
+	codec *Codec
+}
+
+// Encode encodes the field pointed to by ptr in the same way as the
+// reflection based encoder, including the encoders set using
+// SetTypeEncoding.
+func (e *EncodeState) Encode(ptr interface{}) (interface{}, error) {
+	if e == nil {
+		return defaultCodec.Encode(ptr)
+	}
+	return e.codec.Encode(ptr)
+}
+
+// ReferenceField returns the value of the field refName of the encoded field
+// name of the struct pointed to by ptr, as used by the reference tag option.
+func ReferenceField(ptr interface{}, encField interface{}, name, refName string) (ref interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+	}()
+
+	return getReferenceField(field{name: name, refName: refName}, reflect.ValueOf(ptr).Elem(), encField), nil
+}
+
+// CompoundField sets the element at index of the encoded compound field s,
+// growing s if necessary.
+func CompoundField(s []interface{}, index int, v interface{}) []interface{} {
+	if len(s) < index+1 {
+		tmp := make([]interface{}, index+1)
+		copy(tmp, s)
+		s = tmp
+	}
+	s[index] = v
+
+	return s
+}
+
+// CompoundElem returns the element at index of the value of a compound field.
+func CompoundElem(v interface{}, index int) (interface{}, error) {
+	sv := reflect.ValueOf(v)
+	// Strings are indexed in the same way as the decoder, which uses
+	// reflect.Value.Index
+	if sv.Kind() != reflect.Slice && sv.Kind() != reflect.Array && sv.Kind() != reflect.String {
+		return nil, fmt.Errorf("rethinkdb: compound field must be an array, got %T", v)
+	}
+	if index >= sv.Len() {
+		return nil, fmt.Errorf("rethinkdb: compound field index %d out of range with length %d", index, sv.Len())
+	}
+
+	return sv.Index(index).Interface(), nil
+}
+
+// DecodeReflect decodes src into the struct pointed to by dst in the same way
+// as Decode without calling the UnmarshalRQL method of dst, it is used by
+// methods generated by older versions of cmd/rethinkdb-gen.
+func DecodeReflect(dst interface{}, src interface{}) error {
+	var d *DecodeState
+	return d.DecodeReflect(dst, src)
+}
+
+// StateUnmarshaler is implemented by the types with methods generated by
+// cmd/rethinkdb-gen. The decoder calls UnmarshalRQLState instead of
+// UnmarshalRQL, with the codec and options the document is decoded with.
+type StateUnmarshaler interface {
+	UnmarshalRQLState(data interface{}, d *DecodeState) error
+}
+
+// DecodeState is the codec and options a value is decoded with, it is passed
+// to UnmarshalRQLState so that the values the generated methods do not decode
+// themselves are decoded in the same way as the rest of the document. A nil
+// DecodeState decodes using the default codec without options.
+type DecodeState struct {
+	codec *Codec
+	opts  decoderOpts
+}
+
+func (d *DecodeState) state() (*Codec, decoderOpts) {
+	if d == nil {
+		return defaultCodec, decoderOpts{blank: true}
+	}
+	return d.codec, d.opts
+}
+
+// Decode decodes src into the field name of the struct being decoded, dst is
+// a pointer to the field. The field is decoded in the same way as by the
+// reflection based decoder, including the decoders set using
+// SetTypeEncoding.
+func (d *DecodeState) Decode(dst interface{}, src interface{}, name string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			if v, ok := r.(string); ok {
+				err = errors.New(v)
+			} else {
+				err = r.(error)
+			}
+		}
+		err = withFieldPath(err, name)
+	}()
+
+	codec, opts := d.state()
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(&src).Elem()
+	return codec.typeDecoder(dv.Type(), emptyInterfaceType, opts)(dv, sv)
+}
+
+// DecodeReflect decodes src into the struct pointed to by dst in the same way
+// as Decode without calling the UnmarshalRQLState method of dst, it is used
+// for values the generated method does not handle itself.
+func (d *DecodeState) DecodeReflect(dst interface{}, src interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			if v, ok := r.(string); ok {
+				err = errors.New(v)
+			} else {
+				err = r.(error)
+			}
+		}
+	}()
+
+	codec, opts := d.state()
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(src)
+	if !sv.IsValid() {
+		return invalidValueDecoder(dv, sv)
+	}
+	if sv.Kind() == reflect.Ptr {
+		sv = indirect(sv, false)
+	}
+
+	return codec.newValueDecoder(dv.Type(), sv.Type(), opts)(dv, sv)
+}
+
+// UnknownField returns an UnknownFieldError for the key of the object being
+// decoded into the struct pointed to by dst if DisallowUnknownFields is set,
+// and nil otherwise.
+func (d *DecodeState) UnknownField(dst interface{}, key string) error {
+	if _, opts := d.state(); opts.DisallowUnknownFields {
+		return &UnknownFieldError{Path: key, Type: reflect.TypeOf(dst).Elem()}
+	}
+	return nil
+}
+
+// FoldMatch returns true if the key of m, which matches the field name case
+// insensitively, is decoded into the field. A key matching the name exactly
+// is used first, otherwise the smallest matching key is used, so that the
+// result does not depend on the iteration order of m.
+func FoldMatch(m map[string]interface{}, key, name string) bool {
+	if _, ok := m[name]; ok {
+		return false
+	}
+	for k := range m {
+		if k < key && strings.EqualFold(k, name) {
+			return false
+		}
+	}
+	return true
+}
+
+type signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+type unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// DecodeInt stores the number f in the signed integer pointed to by dst, the
+// field name of the struct being decoded. The number is truncated in the same
+// way as by the reflection based decoder, unless DisallowLossyConversion is
+// set and a LossyConversionError is returned if f has a fractional part or is
+// out of the range of the integer.
+func DecodeInt[T signed](d *DecodeState, dst *T, f float64, name string) error {
+	i := int64(f)
+	if _, opts := d.state(); opts.DisallowLossyConversion {
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || int64(T(i)) != i {
+			return &LossyConversionError{Path: name, Value: f, Type: reflect.TypeOf(*dst)}
+		}
+	}
+	*dst = T(i)
+	return nil
+}
+
+// DecodeUint stores the number f in the unsigned integer pointed to by dst,
+// the field name of the struct being decoded. The number is truncated in the
+// same way as by the reflection based decoder, unless DisallowLossyConversion
+// is set and a LossyConversionError is returned if f has a fractional part or
+// is out of the range of the integer.
+func DecodeUint[T unsigned](d *DecodeState, dst *T, f float64, name string) error {
+	u := uint64(f)
+	if _, opts := d.state(); opts.DisallowLossyConversion {
+		if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 || uint64(T(u)) != u {
+			return &LossyConversionError{Path: name, Value: f, Type: reflect.TypeOf(*dst)}
+		}
+	}
+	*dst = T(u)
+	return nil
+}
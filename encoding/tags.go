@@ -10,7 +10,16 @@ import (
 )
 
 var (
+	// Tags are the struct tags read by the default codec, codecs created using
+	// NewCodec are configured using CodecOpts.Tags instead.
 	Tags []string
+
+	// NamingStrategy returns the name used to encode and decode struct fields
+	// which do not set a name in their tag, such as SnakeCase. By default the
+	// name of the Go field is used. Like Tags it only configures the default
+	// codec and must be set before any values are encoded or decoded as the
+	// fields of each type are cached.
+	NamingStrategy func(name string) string
 )
 
 const (
@@ -25,8 +34,9 @@ const (
 // tag, or the empty string. It does not include the leading comma.
 type tagOptions string
 
-func getTag(sf reflect.StructField) string {
-	if Tags == nil {
+func (c *Codec) getTag(sf reflect.StructField) string {
+	tags := c.tagNames()
+	if tags == nil {
 		value := sf.Tag.Get(TagName)
 		if value == "" {
 			return sf.Tag.Get(OldTagName)
@@ -34,7 +44,7 @@ func getTag(sf reflect.StructField) string {
 		return value
 	}
 
-	for _, tagName := range Tags {
+	for _, tagName := range tags {
 		if tag := sf.Tag.Get(tagName); tag != "" {
 			return tag
 		}
@@ -112,3 +122,25 @@ func (o tagOptions) Contains(optionName string) bool {
 	}
 	return false
 }
+
+// SnakeCase converts the name of a Go field to snake case, for example UserID
+// becomes user_id and HTTPServer becomes http_server.
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	b.Grow(len(name) + 2)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word at the start of a word after a lower case
+			// letter or digit, or at the last upper case letter of an acronym
+			// followed by a lower case letter
+			if i > 0 && runes[i-1] != '_' && (!unicode.IsUpper(runes[i-1]) ||
+				i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -27,9 +27,13 @@ var (
 	ErrConnectionClosed = errors.New("rethinkdb: the connection is closed")
 	// ErrQueryTimeout is returned when query context deadline exceeded.
 	ErrQueryTimeout = errors.New("rethinkdb: query timeout")
+	// ErrSessionShutdown is returned when trying to send a query with a session
+	// which is being shut down.
+	ErrSessionShutdown = errors.New("rethinkdb: the session is shutting down")
 )
 
 func printCarrots(t Term, frames []*p.Frame) string {
+	t = t.expand()
 	var frame *p.Frame
 	if len(frames) > 1 {
 		frame, frames = frames[0], frames[1:]
@@ -80,17 +84,22 @@ type rqlServerError struct {
 }
 
 func (e rqlServerError) Error() string {
+	if e.term == nil {
+		return e.message()
+	}
+
+	return fmt.Sprintf("%s in:\n%s", e.message(), e.term.String())
+
+}
+
+// message returns the error of the server without the term of the query.
+func (e rqlServerError) message() string {
 	var err = "An error occurred"
 	if e.response != nil {
 		json.Unmarshal(e.response.Responses[0], &err)
 	}
 
-	if e.term == nil {
-		return fmt.Sprintf("rethinkdb: %s", err)
-	}
-
-	return fmt.Sprintf("rethinkdb: %s in:\n%s", err, e.term.String())
-
+	return fmt.Sprintf("rethinkdb: %s", err)
 }
 
 func (e rqlServerError) String() string {
@@ -138,6 +147,26 @@ type RQLConnectionError struct {
 	rqlError
 }
 
+// RQLQueryRejectedError is returned when a QueryInterceptor rejects a query
+// before it is sent to the server.
+type RQLQueryRejectedError struct {
+	// Reason describes why the query was rejected.
+	Reason string
+	// Term is the term of the query, or the part of it which was rejected.
+	Term *Term
+}
+
+func (e RQLQueryRejectedError) Error() string {
+	if e.Term == nil {
+		return fmt.Sprintf("rethinkdb: query rejected: %s", e.Reason)
+	}
+	return fmt.Sprintf("rethinkdb: query rejected: %s in:\n%s", e.Reason, e.Term.String())
+}
+
+func (e RQLQueryRejectedError) String() string {
+	return e.Error()
+}
+
 func createClientError(response *Response, term *Term) error {
 	return RQLClientError{rqlServerError{response, term}}
 }
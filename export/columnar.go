@@ -0,0 +1,181 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// columnarBatch is a record batch of the columnar format, one JSON object per
+// line containing the values of each field of length documents.
+type columnarBatch struct {
+	Length  int              `json:"length"`
+	Columns []columnarColumn `json:"columns"`
+}
+
+type columnarColumn struct {
+	Name   string        `json:"name"`
+	Type   ColumnType    `json:"type"`
+	Values []interface{} `json:"values"`
+}
+
+// ColumnarWriter writes documents in a columnar format similar to Apache
+// Arrow record batches. Documents are buffered and written in batches, one
+// JSON object per line:
+//
+//	{"length":2,"columns":[{"name":"id","type":"number","values":[1,2]},{"name":"name","type":"string","values":["a",null]}]}
+//
+// Each column contains a top level field of the documents. The type of a
+// column is inferred from the values of the batch, pseudo-types are flattened
+// in the same way as CSVWriter and columns of mixed types have the json type
+// and contain the values as returned by the database. Missing fields are
+// written as null.
+type ColumnarWriter struct {
+	w         *bufio.Writer
+	batchSize int
+	docs      []map[string]interface{}
+}
+
+// NewColumnarWriter returns a Writer which writes record batches of up to
+// batchSize documents to w, 1000 if batchSize is not positive.
+func NewColumnarWriter(w io.Writer, batchSize int) *ColumnarWriter {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	return &ColumnarWriter{w: bufio.NewWriter(w), batchSize: batchSize}
+}
+
+// WriteDocument adds a document to the current batch, writing the batch once
+// it is full.
+func (w *ColumnarWriter) WriteDocument(doc []byte) error {
+	obj, err := decodeDocument(doc)
+	if err != nil {
+		return err
+	}
+
+	w.docs = append(w.docs, obj)
+	if len(w.docs) < w.batchSize {
+		return nil
+	}
+	return w.writeBatch()
+}
+
+func (w *ColumnarWriter) writeBatch() error {
+	var names []string
+	types := map[string]ColumnType{}
+	for _, doc := range w.docs {
+		for name, v := range doc {
+			typ, seen := types[name]
+			switch {
+			case !seen:
+				names = append(names, name)
+				types[name] = ""
+				if v != nil {
+					types[name] = valueType(v)
+				}
+			case v == nil:
+			case typ == "":
+				types[name] = valueType(v)
+			case typ != valueType(v):
+				types[name] = ColumnJSON
+			}
+		}
+	}
+	for name, typ := range types {
+		// Columns which only contain null values
+		if typ == "" {
+			types[name] = ColumnJSON
+		}
+	}
+	sort.Strings(names)
+
+	batch := columnarBatch{Length: len(w.docs), Columns: make([]columnarColumn, len(names))}
+	for i, name := range names {
+		column := columnarColumn{Name: name, Type: types[name], Values: make([]interface{}, len(w.docs))}
+		for j, doc := range w.docs {
+			v := doc[name]
+			if v != nil {
+				switch column.Type {
+				case ColumnTime, ColumnBinary, ColumnGeometry:
+					s, err := flatten(v)
+					if err != nil {
+						return err
+					}
+					v = s
+				}
+			}
+			column.Values[j] = v
+		}
+		batch.Columns[i] = column
+	}
+	w.docs = w.docs[:0]
+
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(b); err != nil {
+		return err
+	}
+	return w.w.WriteByte('\n')
+}
+
+// Close writes the remaining buffered documents and flushes the output.
+func (w *ColumnarWriter) Close() error {
+	if len(w.docs) > 0 {
+		if err := w.writeBatch(); err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
+
+// ColumnarReader reads documents written by ColumnarWriter. Null values are
+// omitted from the documents and flattened pseudo-types are converted in the
+// same way as CSVReader.
+type ColumnarReader struct {
+	dec   *json.Decoder
+	batch columnarBatch
+	row   int
+}
+
+// NewColumnarReader returns a Reader which reads record batches from r.
+func NewColumnarReader(r io.Reader) *ColumnarReader {
+	return &ColumnarReader{dec: json.NewDecoder(r)}
+}
+
+// ReadDocument returns the next document, or io.EOF at the end of the input.
+func (r *ColumnarReader) ReadDocument() (map[string]interface{}, error) {
+	for r.row >= r.batch.Length {
+		r.batch = columnarBatch{}
+		r.row = 0
+		if err := r.dec.Decode(&r.batch); err != nil {
+			return nil, err
+		}
+		for _, column := range r.batch.Columns {
+			if len(column.Values) != r.batch.Length {
+				return nil, fmt.Errorf("rethinkdb: export: column %s has %d values, expected %d", column.Name, len(column.Values), r.batch.Length)
+			}
+		}
+	}
+
+	doc := map[string]interface{}{}
+	for _, column := range r.batch.Columns {
+		v := column.Values[r.row]
+		if v == nil {
+			continue
+		}
+		if s, ok := v.(string); ok && column.Type != ColumnJSON {
+			var err error
+			if v, err = unflatten(column.Type, s); err != nil {
+				return nil, err
+			}
+		}
+		doc[column.Name] = v
+	}
+	r.row++
+
+	return doc, nil
+}
@@ -0,0 +1,108 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestColumnarWriter(t *testing.T) {
+	var buf bytes.Buffer
+	exportRows(t, NewColumnarWriter(&buf, 0))
+
+	var batch columnarBatch
+	if err := json.Unmarshal(buf.Bytes(), &batch); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	if batch.Length != 2 {
+		t.Errorf("got length %d, want 2", batch.Length)
+	}
+
+	want := map[string]columnarColumn{
+		"id":       {Name: "id", Type: ColumnNumber, Values: []interface{}{1.0, 2.0}},
+		"name":     {Name: "name", Type: ColumnString, Values: []interface{}{"Alice", "Bob"}},
+		"created":  {Name: "created", Type: ColumnTime, Values: []interface{}{"2013-07-30T01:21:36.5+02:00", nil}},
+		"avatar":   {Name: "avatar", Type: ColumnBinary, Values: []interface{}{"cG5n", nil}},
+		"location": {Name: "location", Type: ColumnGeometry, Values: []interface{}{"POINT (-122.423246 37.779388)", nil}},
+		"address":  {Name: "address", Type: ColumnJSON, Values: []interface{}{map[string]interface{}{"city": "San Francisco"}, nil}},
+		"tags":     {Name: "tags", Type: ColumnJSON, Values: []interface{}{nil, []interface{}{"a", "b"}}},
+	}
+	if len(batch.Columns) != len(want) {
+		t.Fatalf("got %d columns, want %d", len(batch.Columns), len(want))
+	}
+	for i, column := range batch.Columns {
+		if i > 0 && batch.Columns[i-1].Name > column.Name {
+			t.Errorf("column %s is not sorted", column.Name)
+		}
+		if !reflect.DeepEqual(column, want[column.Name]) {
+			t.Errorf("got %#v, want %#v", column, want[column.Name])
+		}
+	}
+}
+
+func TestColumnarWriter_Batches(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewColumnarWriter(&buf, 2)
+	for _, doc := range []string{`{"a": 1}`, `{"a": "b"}`, `{"a": 3}`} {
+		if err := w.WriteDocument([]byte(doc)); err != nil {
+			t.Fatalf("got error %v, expected nil", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+
+	want := `{"length":2,"columns":[{"name":"a","type":"json","values":[1,"b"]}]}
+{"length":1,"columns":[{"name":"a","type":"number","values":[3]}]}
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestColumnarReader(t *testing.T) {
+	var buf bytes.Buffer
+	exportRows(t, NewColumnarWriter(&buf, 1))
+
+	docs := readAll(t, NewColumnarReader(&buf))
+	want := []map[string]interface{}{
+		{
+			"id":       1.0,
+			"name":     "Alice",
+			"created":  created,
+			"avatar":   []byte("png"),
+			"location": office,
+			"address":  map[string]interface{}{"city": "San Francisco"},
+		},
+		{
+			"id":   2.0,
+			"name": "Bob",
+			"tags": []interface{}{"a", "b"},
+		},
+	}
+	if len(docs) != len(want) {
+		t.Fatalf("got %d documents, want %d", len(docs), len(want))
+	}
+	for i := range want {
+		got := docs[i]
+		if c, ok := got["created"].(time.Time); ok {
+			if !c.Equal(created) {
+				t.Errorf("got created %v, want %v", c, created)
+			}
+			got["created"] = created
+		}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("got %#v, want %#v", got, want[i])
+		}
+	}
+}
+
+func TestColumnarReader_InvalidBatch(t *testing.T) {
+	input := `{"length":2,"columns":[{"name":"a","type":"number","values":[1]}]}`
+	if _, err := NewColumnarReader(strings.NewReader(input)).ReadDocument(); err == nil {
+		t.Error("got nil, expected an error")
+	}
+}
@@ -0,0 +1,152 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Column maps a CSV column to a field of the documents.
+type Column struct {
+	// Name is the name of the column in the header row.
+	Name string
+	// Field is the path of the field, with the names of nested fields
+	// separated by dots such as "address.city". By default Name is used.
+	Field string
+	// Type is the type the values of the column are converted to when they
+	// are read, by default values are read as strings. It is not used when
+	// writing, values are written according to their own type.
+	Type ColumnType
+}
+
+func (c Column) path() []string {
+	if c.Field == "" {
+		return []string{c.Name}
+	}
+	return strings.Split(c.Field, ".")
+}
+
+// CSVWriter writes the fields of documents as CSV, the first row is a header
+// containing the names of the columns.
+type CSVWriter struct {
+	w       *csv.Writer
+	columns []Column
+	header  bool
+}
+
+// NewCSVWriter returns a Writer which writes CSV to w. If columns is nil the
+// columns are the top level fields of the first document, sorted by name.
+func NewCSVWriter(w io.Writer, columns []Column) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w), columns: columns}
+}
+
+// WriteDocument writes the fields of a document as a row, objects and arrays
+// are written as JSON and missing fields or null values as empty cells.
+func (w *CSVWriter) WriteDocument(doc []byte) error {
+	obj, err := decodeDocument(doc)
+	if err != nil {
+		return err
+	}
+
+	if w.columns == nil {
+		for name := range obj {
+			w.columns = append(w.columns, Column{Name: name})
+		}
+		sort.Slice(w.columns, func(i, j int) bool {
+			return w.columns[i].Name < w.columns[j].Name
+		})
+	}
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	record := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		record[i], err = flatten(lookup(obj, column.path()))
+		if err != nil {
+			return err
+		}
+	}
+	return w.w.Write(record)
+}
+
+func (w *CSVWriter) writeHeader() error {
+	if w.header {
+		return nil
+	}
+	w.header = true
+
+	names := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		names[i] = column.Name
+	}
+	return w.w.Write(names)
+}
+
+// Close writes the header row if no documents were written and flushes the
+// buffered rows.
+func (w *CSVWriter) Close() error {
+	if w.columns != nil {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// CSVReader reads documents from CSV, the first row must be a header
+// containing the names of the columns.
+type CSVReader struct {
+	r       *csv.Reader
+	columns []Column
+	header  []Column
+}
+
+// NewCSVReader returns a Reader which reads CSV from r. Each cell is converted
+// to the type of the column with the same name as its header, columns which
+// are not in columns are read as strings. Empty cells are omitted from the
+// documents.
+func NewCSVReader(r io.Reader, columns []Column) *CSVReader {
+	return &CSVReader{r: csv.NewReader(r), columns: columns}
+}
+
+// ReadDocument returns the document of the next row, or io.EOF at the end of
+// the input.
+func (r *CSVReader) ReadDocument() (map[string]interface{}, error) {
+	if r.header == nil {
+		names, err := r.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		r.header = make([]Column, len(names))
+		for i, name := range names {
+			r.header[i] = Column{Name: name}
+			for _, column := range r.columns {
+				if column.Name == name {
+					r.header[i] = column
+					break
+				}
+			}
+		}
+	}
+
+	record, err := r.r.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := map[string]interface{}{}
+	for i, cell := range record {
+		if cell == "" {
+			continue
+		}
+		v, err := unflatten(r.header[i].Type, cell)
+		if err != nil {
+			return nil, err
+		}
+		assign(doc, r.header[i].path(), v)
+	}
+	return doc, nil
+}
@@ -0,0 +1,102 @@
+package export
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	exportRows(t, NewCSVWriter(&buf, []Column{
+		{Name: "id"},
+		{Name: "name"},
+		{Name: "created"},
+		{Name: "avatar"},
+		{Name: "location"},
+		{Name: "city", Field: "address.city"},
+		{Name: "tags"},
+	}))
+
+	want := `id,name,created,avatar,location,city,tags
+1,Alice,2013-07-30T01:21:36.5+02:00,cG5n,POINT (-122.423246 37.779388),San Francisco,
+2,Bob,,,,,"[""a"",""b""]"
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestCSVWriter_DefaultColumns(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf, nil)
+	for _, doc := range []string{`{"b": 1, "a": {"c": true}}`, `{"a": null, "d": 2}`} {
+		if err := w.WriteDocument([]byte(doc)); err != nil {
+			t.Fatalf("got error %v, expected nil", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+
+	want := "a,b\n\"{\"\"c\"\":true}\",1\n,\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	input := `id,name,created,avatar,location,city,tags,extra
+1,Alice,2013-07-30T01:21:36.5+02:00,cG5n,POINT (-122.423246 37.779388),San Francisco,,
+2,Bob,,,,,"[""a"",""b""]",1
+`
+	docs := readAll(t, NewCSVReader(strings.NewReader(input), []Column{
+		{Name: "id", Type: ColumnNumber},
+		{Name: "created", Type: ColumnTime},
+		{Name: "avatar", Type: ColumnBinary},
+		{Name: "location", Type: ColumnGeometry},
+		{Name: "city", Field: "address.city"},
+		{Name: "tags", Type: ColumnJSON},
+	}))
+
+	want := []map[string]interface{}{
+		{
+			"id":       1.0,
+			"name":     "Alice",
+			"created":  created,
+			"avatar":   []byte("png"),
+			"location": office,
+			"address":  map[string]interface{}{"city": "San Francisco"},
+		},
+		{
+			"id":    2.0,
+			"name":  "Bob",
+			"tags":  []interface{}{"a", "b"},
+			"extra": "1",
+		},
+	}
+	if len(docs) != len(want) {
+		t.Fatalf("got %d documents, want %d", len(docs), len(want))
+	}
+	for i := range want {
+		got := docs[i]
+		if c, ok := got["created"].(time.Time); ok {
+			if !c.Equal(created) {
+				t.Errorf("got created %v, want %v", c, created)
+			}
+			got["created"] = created
+		}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("got %#v, want %#v", got, want[i])
+		}
+	}
+}
+
+func TestCSVReader_InvalidCell(t *testing.T) {
+	rd := NewCSVReader(strings.NewReader("id\nabc\n"), []Column{{Name: "id", Type: ColumnNumber}})
+	if _, err := rd.ReadDocument(); err == nil {
+		t.Error("got nil, expected an error")
+	}
+}
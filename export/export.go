@@ -0,0 +1,198 @@
+// Package export writes the results of RethinkDB queries to NDJSON, CSV and
+// columnar files, and reads those files back to insert the documents into a
+// table.
+//
+// Exporters write the raw JSON returned by the database, so documents are
+// not decoded into Go values unless the format requires it. Pseudo-types are
+// kept in NDJSON files, and flattened to strings in CSV and columnar files:
+// TIME values are written as RFC3339 timestamps, BINARY values as base64 and
+// GEOMETRY values as WKT.
+//
+//	cursor, err := r.Table("users").Run(session)
+//	...
+//	n, err := export.Export(cursor, export.NewNDJSONWriter(file))
+//
+// Importers read the documents of a file and insert them into a table in
+// batches:
+//
+//	res, err := export.Import(ctx, session, r.Table("users"), export.NewNDJSONReader(file), export.ImportOpts{})
+package export
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	rethinkdb "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// Writer writes the documents of a query result to a file.
+type Writer interface {
+	// WriteDocument writes the raw JSON of a document.
+	WriteDocument(doc []byte) error
+	// Close flushes any buffered documents, it does not close the underlying
+	// io.Writer.
+	Close() error
+}
+
+// Reader reads the documents of a file.
+type Reader interface {
+	// ReadDocument returns the next document, or io.EOF at the end of the
+	// file.
+	ReadDocument() (map[string]interface{}, error)
+}
+
+// Export writes every document of the cursor to w and returns the number of
+// documents written. The cursor and w are closed once the result set has been
+// written or an error happens.
+func Export(c *rethinkdb.Cursor, w Writer) (int, error) {
+	n, err := export(c, w)
+	if cerr := c.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+func export(c *rethinkdb.Cursor, w Writer) (int, error) {
+	n := 0
+	for {
+		doc, ok := c.NextDocument()
+		if !ok {
+			return n, c.Err()
+		}
+		if err := w.WriteDocument(doc); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// ImportOpts contains the optional arguments for Import.
+type ImportOpts struct {
+	// BatchSize is the number of documents inserted by each query, 200 by
+	// default.
+	BatchSize int
+	// Concurrency is the number of insert queries run in parallel, 1 by
+	// default. Documents are inserted in order only when it is 1.
+	Concurrency int
+	// InsertOpts are the options of the insert queries, for example
+	// InsertOpts{Conflict: "replace"} to replace existing documents.
+	InsertOpts rethinkdb.InsertOpts
+}
+
+func (o ImportOpts) batchSize() int {
+	if o.BatchSize <= 0 {
+		return 200
+	}
+	return o.BatchSize
+}
+
+func (o ImportOpts) concurrency() int {
+	if o.Concurrency <= 0 {
+		return 1
+	}
+	return o.Concurrency
+}
+
+// Import reads every document from r and inserts them into table, returning
+// the sum of the write responses of the insert queries. Import stops at the
+// first error, either reading a document or inserting a batch, documents of
+// batches inserted before the error are not removed.
+func Import(ctx context.Context, s rethinkdb.QueryExecutor, table rethinkdb.Term, r Reader, opts ImportOpts) (rethinkdb.WriteResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		result rethinkdb.WriteResponse
+		first  error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if first == nil {
+			first = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	batches := make(chan []interface{})
+	var wg sync.WaitGroup
+	for i := 0; i < opts.concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				res, err := table.Insert(batch, opts.InsertOpts).RunWrite(s, rethinkdb.RunOpts{Context: ctx})
+				mu.Lock()
+				addWriteResponse(&result, res)
+				mu.Unlock()
+				if err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+	send := func(batch []interface{}) bool {
+		select {
+		case batches <- batch:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	size := opts.batchSize()
+	batch := make([]interface{}, 0, size)
+	for {
+		doc, err := r.ReadDocument()
+		if err == io.EOF {
+			if len(batch) > 0 {
+				send(batch)
+			}
+			break
+		}
+		if err != nil {
+			fail(err)
+			break
+		}
+
+		batch = append(batch, doc)
+		if len(batch) == size {
+			if !send(batch) {
+				break
+			}
+			batch = make([]interface{}, 0, size)
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	if first == nil {
+		first = parent.Err()
+	}
+	return result, first
+}
+
+// addWriteResponse adds the document counts of res to sum.
+func addWriteResponse(sum *rethinkdb.WriteResponse, res rethinkdb.WriteResponse) {
+	sum.Errors += res.Errors
+	sum.Inserted += res.Inserted
+	sum.Replaced += res.Replaced
+	sum.Unchanged += res.Unchanged
+	sum.Skipped += res.Skipped
+	sum.Deleted += res.Deleted
+	sum.GeneratedKeys = append(sum.GeneratedKeys, res.GeneratedKeys...)
+	sum.Changes = append(sum.Changes, res.Changes...)
+	if sum.FirstError == "" {
+		sum.FirstError = res.FirstError
+	}
+}
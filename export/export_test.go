@@ -0,0 +1,152 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/types"
+)
+
+var (
+	created = time.Date(2013, 7, 30, 1, 21, 36, 5e8, time.FixedZone("+02:00", 2*60*60))
+	office  = types.Geometry{Type: "Point", Point: types.Point{Lon: -122.423246, Lat: 37.779388}}
+	rows    = []interface{}{
+		map[string]interface{}{
+			"id":       1,
+			"name":     "Alice",
+			"created":  created,
+			"avatar":   []byte("png"),
+			"location": office,
+			"address":  map[string]interface{}{"city": "San Francisco"},
+		},
+		map[string]interface{}{
+			"id":   2,
+			"name": "Bob",
+			"tags": []interface{}{"a", "b"},
+		},
+	}
+)
+
+func exportRows(t *testing.T, w Writer) {
+	t.Helper()
+
+	mock := r.NewMock()
+	mock.On(r.Table("users")).Return(rows, nil)
+	cursor, err := r.Table("users").Run(mock)
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+
+	n, err := Export(cursor, w)
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	if n != len(rows) {
+		t.Errorf("got %d documents, want %d", n, len(rows))
+	}
+	mock.AssertExpectations(t)
+}
+
+func readAll(t *testing.T, rd Reader) []map[string]interface{} {
+	t.Helper()
+
+	var docs []map[string]interface{}
+	for {
+		doc, err := rd.ReadDocument()
+		if err == io.EOF {
+			return docs
+		}
+		if err != nil {
+			t.Fatalf("got error %v, expected nil", err)
+		}
+		docs = append(docs, doc)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	exportRows(t, NewNDJSONWriter(&buf))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], `"$reql_type$":"TIME"`) {
+		t.Errorf("got %s, want the raw TIME pseudo-type", lines[0])
+	}
+
+	docs := readAll(t, NewNDJSONReader(&buf))
+	if len(docs) != 2 {
+		t.Fatalf("got %d documents, want 2", len(docs))
+	}
+	if docs[0]["id"] != 1.0 || docs[1]["name"] != "Bob" {
+		t.Errorf("got %v", docs)
+	}
+	if avatar := docs[0]["avatar"].(map[string]interface{}); avatar["$reql_type$"] != "BINARY" {
+		t.Errorf("got %v, want a BINARY pseudo-type", avatar)
+	}
+}
+
+func TestImport(t *testing.T) {
+	input := `{"id": 1}
+{"id": 2}
+{"id": 3}
+`
+	mock := r.NewMock()
+	table := r.Table("users")
+	mock.On(table.Insert([]interface{}{
+		map[string]interface{}{"id": 1.0},
+		map[string]interface{}{"id": 2.0},
+	}, r.InsertOpts{Conflict: "replace"})).Return(map[string]interface{}{"inserted": 1, "replaced": 1}, nil).Once()
+	mock.On(table.Insert([]interface{}{
+		map[string]interface{}{"id": 3.0},
+	}, r.InsertOpts{Conflict: "replace"})).Return(map[string]interface{}{"inserted": 1}, nil).Once()
+
+	res, err := Import(context.Background(), mock, table, NewNDJSONReader(strings.NewReader(input)), ImportOpts{
+		BatchSize:  2,
+		InsertOpts: r.InsertOpts{Conflict: "replace"},
+	})
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	if res.Inserted != 2 || res.Replaced != 1 {
+		t.Errorf("got %+v, want 2 inserted and 1 replaced", res)
+	}
+	mock.AssertExpectations(t)
+}
+
+func TestImport_Error(t *testing.T) {
+	input := `{"id": 1}
+{"id": 2}
+`
+	mock := r.NewMock()
+	table := r.Table("users")
+	mock.On(table.Insert([]interface{}{
+		map[string]interface{}{"id": 1.0},
+	})).Return(map[string]interface{}{"errors": 1, "first_error": "Duplicate primary key `id`"}, nil).Once()
+
+	res, err := Import(context.Background(), mock, table, NewNDJSONReader(strings.NewReader(input)), ImportOpts{
+		BatchSize:   1,
+		Concurrency: 1,
+	})
+	if err == nil || !strings.Contains(err.Error(), "Duplicate primary key") {
+		t.Fatalf("got error %v, want the first error", err)
+	}
+	if res.Errors != 1 {
+		t.Errorf("got %d errors, want 1", res.Errors)
+	}
+	mock.AssertExpectations(t)
+}
+
+func TestImport_ReadError(t *testing.T) {
+	mock := r.NewMock()
+	_, err := Import(context.Background(), mock, r.Table("users"), NewNDJSONReader(strings.NewReader(`[1]`)), ImportOpts{})
+	if err == nil || !strings.Contains(err.Error(), "is not an object") {
+		t.Errorf("got error %v, want a document error", err)
+	}
+}
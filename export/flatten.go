@@ -0,0 +1,188 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/types"
+)
+
+// ColumnType is the type of the values of a CSV or columnar column.
+type ColumnType string
+
+const (
+	// ColumnString values are strings.
+	ColumnString ColumnType = "string"
+	// ColumnNumber values are numbers.
+	ColumnNumber ColumnType = "number"
+	// ColumnBool values are booleans, written as true or false.
+	ColumnBool ColumnType = "bool"
+	// ColumnTime values are TIME pseudo-types, written as RFC3339 timestamps.
+	ColumnTime ColumnType = "time"
+	// ColumnBinary values are BINARY pseudo-types, written as base64.
+	ColumnBinary ColumnType = "binary"
+	// ColumnGeometry values are GEOMETRY pseudo-types, written as WKT.
+	ColumnGeometry ColumnType = "geometry"
+	// ColumnJSON values are objects, arrays or values of mixed types,
+	// written as JSON.
+	ColumnJSON ColumnType = "json"
+)
+
+// decodeDocument decodes the raw JSON of a document, numbers are kept as
+// json.Number so they are written without losing precision.
+func decodeDocument(doc []byte) (map[string]interface{}, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("rethinkdb: export: document %s is not an object", doc)
+	}
+	return obj, nil
+}
+
+// lookup returns the value of the field at path in doc, where path is a list
+// of nested field names.
+func lookup(doc map[string]interface{}, path []string) interface{} {
+	var v interface{} = doc
+	for _, name := range path {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = obj[name]
+	}
+	return v
+}
+
+// assign sets the field at path in doc to v, creating any missing objects.
+func assign(doc map[string]interface{}, path []string, v interface{}) {
+	for _, name := range path[:len(path)-1] {
+		obj, ok := doc[name].(map[string]interface{})
+		if !ok {
+			obj = map[string]interface{}{}
+			doc[name] = obj
+		}
+		doc = obj
+	}
+	doc[path[len(path)-1]] = v
+}
+
+// valueType returns the column type of a decoded JSON value.
+func valueType(v interface{}) ColumnType {
+	switch v := v.(type) {
+	case string:
+		return ColumnString
+	case json.Number, float64:
+		return ColumnNumber
+	case bool:
+		return ColumnBool
+	case map[string]interface{}:
+		switch v["$reql_type$"] {
+		case "TIME":
+			return ColumnTime
+		case "BINARY":
+			return ColumnBinary
+		case "GEOMETRY":
+			return ColumnGeometry
+		}
+	}
+	return ColumnJSON
+}
+
+// flatten returns a decoded JSON value as a string, pseudo-types are
+// converted to the formats described by the ColumnType constants. Null is
+// flattened to an empty string.
+func flatten(v interface{}) (string, error) {
+	switch typ := valueType(v); typ {
+	case ColumnString:
+		return v.(string), nil
+	case ColumnNumber:
+		if f, ok := v.(float64); ok {
+			return strconv.FormatFloat(f, 'g', -1, 64), nil
+		}
+		return v.(json.Number).String(), nil
+	case ColumnBool:
+		return strconv.FormatBool(v.(bool)), nil
+	case ColumnTime, ColumnBinary, ColumnGeometry:
+		return flattenPseudoType(typ, v.(map[string]interface{}))
+	}
+
+	if v == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func flattenPseudoType(typ ColumnType, obj map[string]interface{}) (string, error) {
+	// The pseudo-type functions of the types package expect numbers to be
+	// decoded as float64
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return "", err
+	}
+
+	switch typ {
+	case ColumnTime:
+		t, err := types.UnmarshalTime(v)
+		if err != nil {
+			return "", err
+		}
+		return t.Time.Format(time.RFC3339Nano), nil
+	case ColumnBinary:
+		data, ok := obj["data"].(string)
+		if !ok {
+			return "", fmt.Errorf("pseudo-type BINARY object field 'data' is not valid")
+		}
+		return data, nil
+	default:
+		var g types.Geometry
+		if err := g.UnmarshalRQL(v); err != nil {
+			return "", err
+		}
+		return g.WKT()
+	}
+}
+
+// unflatten converts a string written by flatten back to a value which can be
+// inserted, TIME values are returned as time.Time, BINARY values as []byte and
+// GEOMETRY values as types.Geometry.
+func unflatten(typ ColumnType, s string) (interface{}, error) {
+	switch typ {
+	case "", ColumnString:
+		return s, nil
+	case ColumnNumber:
+		return strconv.ParseFloat(s, 64)
+	case ColumnBool:
+		return strconv.ParseBool(s)
+	case ColumnTime:
+		return time.Parse(time.RFC3339Nano, s)
+	case ColumnBinary:
+		return base64.StdEncoding.DecodeString(s)
+	case ColumnGeometry:
+		return types.ParseWKT(s)
+	case ColumnJSON:
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("rethinkdb: export: column type %s is not valid", typ)
+	}
+}
@@ -0,0 +1,64 @@
+package export
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/types"
+)
+
+func TestFlattenGeometry(t *testing.T) {
+	square := types.Line{{Lon: 0, Lat: 0}, {Lon: 0, Lat: 1}, {Lon: 1, Lat: 1}, {Lon: 0, Lat: 0}}
+	tests := []struct {
+		geometry types.Geometry
+		wkt      string
+	}{
+		{types.Geometry{Type: "LineString", Line: types.Line{{Lon: 1, Lat: 2}, {Lon: 3.5, Lat: -4}}}, "LINESTRING (1 2, 3.5 -4)"},
+		{types.Geometry{Type: "Polygon", Lines: types.Lines{square}}, "POLYGON ((0 0, 0 1, 1 1, 0 0))"},
+		{types.Geometry{Type: "MultiPoint", MultiPoint: types.MultiPoint{{Lon: 1, Lat: 2}, {Lon: 3, Lat: 4}}}, "MULTIPOINT (1 2, 3 4)"},
+		{types.Geometry{Type: "MultiPolygon", MultiPolygon: types.MultiPolygon{{square}, {square}}}, "MULTIPOLYGON (((0 0, 0 1, 1 1, 0 0)), ((0 0, 0 1, 1 1, 0 0)))"},
+		{types.Geometry{Type: "GeometryCollection", Geometries: types.GeometryCollection{
+			{Type: "Point", Point: types.Point{Lon: 1, Lat: 2}},
+			{Type: "MultiLineString", MultiLine: types.MultiLineString{{{Lon: 1, Lat: 2}, {Lon: 3, Lat: 4}}}},
+		}}, "GEOMETRYCOLLECTION (POINT (1 2), MULTILINESTRING ((1 2, 3 4)))"},
+	}
+
+	for _, tt := range tests {
+		obj, err := tt.geometry.MarshalRQL()
+		if err != nil {
+			t.Fatalf("got error %v, expected nil", err)
+		}
+		s, err := flatten(obj)
+		if err != nil {
+			t.Fatalf("got error %v, expected nil", err)
+		}
+		if s != tt.wkt {
+			t.Errorf("got %s, want %s", s, tt.wkt)
+		}
+
+		g, err := unflatten(ColumnGeometry, s)
+		if err != nil {
+			t.Fatalf("got error %v, expected nil", err)
+		}
+		if !reflect.DeepEqual(g, tt.geometry) {
+			t.Errorf("got %#v, want %#v", g, tt.geometry)
+		}
+	}
+}
+
+func TestParseWKT(t *testing.T) {
+	g, err := types.ParseWKT("multipoint ((1 2), (3 4))")
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	want := types.Geometry{Type: "MultiPoint", MultiPoint: types.MultiPoint{{Lon: 1, Lat: 2}, {Lon: 3, Lat: 4}}}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("got %#v, want %#v", g, want)
+	}
+
+	for _, s := range []string{"POINT (1)", "CIRCLE (1 2)", "POINT (1 2) x", "LINESTRING (1 2"} {
+		if _, err := types.ParseWKT(s); err == nil {
+			t.Errorf("got nil parsing %q, expected an error", s)
+		}
+	}
+}
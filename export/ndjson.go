@@ -0,0 +1,57 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// NDJSONWriter writes documents as newline delimited JSON, one document per
+// line. Documents are written as returned by the database, including any
+// pseudo-types.
+type NDJSONWriter struct {
+	w *bufio.Writer
+}
+
+// NewNDJSONWriter returns a Writer which writes newline delimited JSON to w.
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{w: bufio.NewWriter(w)}
+}
+
+// WriteDocument writes the raw JSON of a document followed by a newline.
+func (w *NDJSONWriter) WriteDocument(doc []byte) error {
+	if _, err := w.w.Write(doc); err != nil {
+		return err
+	}
+	return w.w.WriteByte('\n')
+}
+
+// Close flushes the buffered documents.
+func (w *NDJSONWriter) Close() error {
+	return w.w.Flush()
+}
+
+// NDJSONReader reads documents from newline delimited JSON. Pseudo-types are
+// returned as objects, which the database converts when they are inserted.
+type NDJSONReader struct {
+	dec *json.Decoder
+}
+
+// NewNDJSONReader returns a Reader which reads newline delimited JSON from r.
+func NewNDJSONReader(r io.Reader) *NDJSONReader {
+	return &NDJSONReader{dec: json.NewDecoder(r)}
+}
+
+// ReadDocument returns the next document, or io.EOF at the end of the input.
+func (r *NDJSONReader) ReadDocument() (map[string]interface{}, error) {
+	var v interface{}
+	if err := r.dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	doc, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("rethinkdb: export: document %v is not an object", v)
+	}
+	return doc, nil
+}
@@ -0,0 +1,161 @@
+package rethinkdb
+
+import (
+	"fmt"
+	"math"
+
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/types"
+)
+
+const (
+	// wgs84A is the semi-major axis of the WGS 84 ellipsoid in meters
+	wgs84A = 6378137.0
+	// wgs84F is the flattening of the WGS 84 ellipsoid
+	wgs84F = 1 / 298.257223563
+)
+
+// meters returns the length of the unit in meters, an empty unit is meters.
+func (u Unit) meters() (float64, error) {
+	switch u {
+	case "", UnitMeter:
+		return 1, nil
+	case UnitKilometer:
+		return 1000, nil
+	case UnitMile:
+		return 1609.344, nil
+	case UnitNauticalMile:
+		return 1852, nil
+	case UnitFoot:
+		return 0.3048, nil
+	default:
+		return 0, fmt.Errorf("unknown distance unit %q", string(u))
+	}
+}
+
+// GeoDistance calculates on the client the distance between two points, as
+// returned by the Distance term with the same options. It is mainly useful
+// for checking the results of geospatial queries in tests.
+//
+// Distances on the WGS 84 ellipsoid are calculated using Vincenty's formulae,
+// which agree with the server to within a millimeter except for nearly
+// antipodal points for which an error is returned.
+func GeoDistance(point1, point2 types.Point, optArgs ...DistanceOpts) (float64, error) {
+	var opts DistanceOpts
+	if len(optArgs) >= 1 {
+		opts = optArgs[0]
+	}
+
+	unit, err := geoOptString(opts.Unit, "unit")
+	if err != nil {
+		return 0, err
+	}
+	scale, err := Unit(unit).meters()
+	if err != nil {
+		return 0, err
+	}
+
+	geoSystem, err := geoOptString(opts.GeoSystem, "geo_system")
+	if err != nil {
+		return 0, err
+	}
+
+	var dist float64
+	switch GeoSystem(geoSystem) {
+	case "", GeoSystemWGS84:
+		dist, err = vincentyDistance(point1, point2)
+		if err != nil {
+			return 0, err
+		}
+	case GeoSystemUnitSphere:
+		dist = greatCircleAngle(point1, point2)
+	default:
+		return 0, fmt.Errorf("unknown geo system %q", geoSystem)
+	}
+
+	return dist / scale, nil
+}
+
+// geoOptString returns the value of a Unit or GeoSystem option which may be
+// set using the named type or a string.
+func geoOptString(v interface{}, name string) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case Unit:
+		return string(v), nil
+	case GeoSystem:
+		return string(v), nil
+	default:
+		return "", fmt.Errorf("invalid %s option %v", name, v)
+	}
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// greatCircleAngle returns the angle in radians between two points on a
+// sphere, using the haversine formula.
+func greatCircleAngle(point1, point2 types.Point) float64 {
+	lat1, lat2 := toRadians(point1.Lat), toRadians(point2.Lat)
+	dLat := lat2 - lat1
+	dLon := toRadians(point2.Lon - point1.Lon)
+
+	h := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+
+	return 2 * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// vincentyDistance returns the distance in meters between two points on the
+// WGS 84 ellipsoid using Vincenty's inverse formula.
+func vincentyDistance(point1, point2 types.Point) (float64, error) {
+	const b = wgs84A * (1 - wgs84F)
+
+	l := toRadians(point2.Lon - point1.Lon)
+	u1 := math.Atan((1 - wgs84F) * math.Tan(toRadians(point1.Lat)))
+	u2 := math.Atan((1 - wgs84F) * math.Tan(toRadians(point2.Lat)))
+	sinU1, cosU1 := math.Sincos(u1)
+	sinU2, cosU2 := math.Sincos(u2)
+
+	lambda := l
+	for i := 0; i < 200; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma := math.Sqrt(math.Pow(cosU2*sinLambda, 2) +
+			math.Pow(cosU1*sinU2-sinU1*cosU2*cosLambda, 2))
+		if sinSigma == 0 {
+			// Coincident points
+			return 0, nil
+		}
+		cosSigma := sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma := math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha := 1 - sinAlpha*sinAlpha
+		cos2SigmaM := 0.0
+		if cosSqAlpha != 0 {
+			// Both points are on the equator otherwise
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+		c := wgs84F / 16 * cosSqAlpha * (4 + wgs84F*(4-3*cosSqAlpha))
+
+		prev := lambda
+		lambda = l + (1-c)*wgs84F*sinAlpha*
+			(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) > 1e-12 {
+			continue
+		}
+
+		uSq := cosSqAlpha * (wgs84A*wgs84A - b*b) / (b * b)
+		bigA := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+		bigB := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+		deltaSigma := bigB * sinSigma * (cos2SigmaM + bigB/4*
+			(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+				bigB/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+
+		return b * bigA * (sigma - deltaSigma), nil
+	}
+
+	return 0, fmt.Errorf("distance between %v and %v did not converge, the points are nearly antipodal", point1, point2)
+}
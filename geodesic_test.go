@@ -0,0 +1,69 @@
+package rethinkdb
+
+import (
+	"math"
+
+	test "gopkg.in/check.v1"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/types"
+)
+
+type GeoSuite struct{}
+
+var _ = test.Suite(&GeoSuite{})
+
+func (s *GeoSuite) TestGeoDistance(c *test.C) {
+	// Expected values are the results returned by the server, which agree to
+	// within a micrometer
+	cases := []struct {
+		point1, point2 types.Point
+		opts           DistanceOpts
+		want           float64
+	}{
+		{types.Point{Lon: -122, Lat: 37}, types.Point{Lon: -123, Lat: 37}, DistanceOpts{}, 89011.26253835332},
+		{types.Point{Lon: -122, Lat: 37}, types.Point{Lon: -122, Lat: 36}, DistanceOpts{}, 110968.30443995494},
+		{types.Point{Lon: -122, Lat: 37}, types.Point{Lon: -123, Lat: 37}, DistanceOpts{Unit: UnitKilometer}, 89.01126253835332},
+		{types.Point{Lon: -122, Lat: 37}, types.Point{Lon: -123, Lat: 37}, DistanceOpts{Unit: "mi"}, 89011.26253835332 / 1609.344},
+		{types.Point{Lon: -122, Lat: 37}, types.Point{Lon: -123, Lat: 37}, DistanceOpts{GeoSystem: GeoSystemUnitSphere}, 0.01393875509649327},
+		{types.Point{Lon: 10, Lat: 10}, types.Point{Lon: 10, Lat: 10}, DistanceOpts{}, 0},
+	}
+
+	for _, tc := range cases {
+		dist, err := GeoDistance(tc.point1, tc.point2, tc.opts)
+		c.Assert(err, test.IsNil)
+		c.Assert(math.Abs(dist-tc.want) <= 1e-9*tc.want, test.Equals, true, test.Commentf("got %v, want %v", dist, tc.want))
+	}
+}
+
+func (s *GeoSuite) TestGeoDistance_InvalidOpts(c *test.C) {
+	_, err := GeoDistance(types.Point{}, types.Point{}, DistanceOpts{Unit: "furlong"})
+	c.Assert(err, test.ErrorMatches, `unknown distance unit "furlong"`)
+
+	_, err = GeoDistance(types.Point{}, types.Point{}, DistanceOpts{GeoSystem: "mars"})
+	c.Assert(err, test.ErrorMatches, `unknown geo system "mars"`)
+
+	_, err = GeoDistance(types.Point{}, types.Point{}, DistanceOpts{Unit: 1})
+	c.Assert(err, test.ErrorMatches, `invalid unit option 1`)
+}
+
+func (s *GeoSuite) TestGetNearestOpts_Enums(c *test.C) {
+	opts := GetNearestOpts{Unit: UnitMile, GeoSystem: GeoSystemUnitSphere}.toMap()
+	c.Assert(opts, test.DeepEquals, map[string]interface{}{"unit": "mi", "geo_system": "unit_sphere"})
+}
+
+func (s *GeoSuite) TestNearestResult_Decode(c *test.C) {
+	type place struct {
+		ID string `rethinkdb:"id"`
+	}
+
+	var res []NearestResult[place]
+	err := encoding.Decode(&res, []interface{}{
+		map[string]interface{}{"dist": 0.5, "doc": map[string]interface{}{"id": "a"}},
+		map[string]interface{}{"dist": 1.5, "doc": map[string]interface{}{"id": "b"}},
+	})
+	c.Assert(err, test.IsNil)
+	c.Assert(res, test.DeepEquals, []NearestResult[place]{
+		{Dist: 0.5, Doc: place{ID: "a"}},
+		{Dist: 1.5, Doc: place{ID: "b"}},
+	})
+}
@@ -22,3 +22,8 @@ func NewHost(name string, port int) Host {
 func (h Host) String() string {
 	return fmt.Sprintf("%s:%d", h.Name, h.Port)
 }
+
+// AddressTranslator is used to translate the address of a server reported by
+// the cluster into an address reachable by the driver, for example when the
+// servers are running behind NAT or in Kubernetes.
+type AddressTranslator func(host Host) Host
@@ -0,0 +1,119 @@
+package rethinkdb
+
+import (
+	"context"
+	"fmt"
+
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+)
+
+// QueryInterceptor inspects each query run by a session before it is sent to
+// the server, it is set using ConnectOpts.Interceptors. Interceptors are
+// called after the middleware (see QueryMiddleware) with the query which is
+// about to be sent, including the run options of the query and its full term
+// tree (see Term.Walk).
+//
+// Returning an error rejects the query and the error is returned by Run or
+// Exec, RQLQueryRejectedError should be used for queries rejected by a policy.
+// The query may also be annotated, for example with Query.SetOpt.
+type QueryInterceptor interface {
+	InterceptQuery(ctx context.Context, q *Query) error
+}
+
+// QueryInterceptorFunc is a function used as a QueryInterceptor.
+type QueryInterceptorFunc func(ctx context.Context, q *Query) error
+
+// InterceptQuery calls f(ctx, q).
+func (f QueryInterceptorFunc) InterceptQuery(ctx context.Context, q *Query) error {
+	return f(ctx, q)
+}
+
+// intercept calls the interceptors of the session with a query.
+func (s *Session) intercept(ctx context.Context, q *Query) error {
+	if len(s.opts.Interceptors) == 0 {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for _, interceptor := range s.opts.Interceptors {
+		if err := interceptor.InterceptQuery(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SafeModeOpts contains the optional arguments for SafeMode, each option
+// allows queries which are rejected by default.
+type SafeModeOpts struct {
+	// AllowDrop allows DBDrop, TableDrop and IndexDrop.
+	AllowDrop bool
+	// AllowTableWrites allows Delete, Update and Replace of every document
+	// of a table, queries writing a selection such as a Filter, Between or
+	// GetAll of a table are always allowed.
+	AllowTableWrites bool
+	// AllowReconfigure allows Reconfigure and Rebalance.
+	AllowReconfigure bool
+}
+
+// SafeMode returns a QueryInterceptor rejecting queries which are usually
+// mistakes in production, such as deleting every document of a table or
+// dropping a table, with an RQLQueryRejectedError.
+//
+//	session, err := r.Connect(r.ConnectOpts{
+//		Address:      url,
+//		Interceptors: []r.QueryInterceptor{r.SafeMode()},
+//	})
+func SafeMode(optArgs ...SafeModeOpts) QueryInterceptor {
+	var opts SafeModeOpts
+	if len(optArgs) >= 1 {
+		opts = optArgs[0]
+	}
+	return safeMode{opts}
+}
+
+type safeMode struct {
+	opts SafeModeOpts
+}
+
+func (m safeMode) InterceptQuery(ctx context.Context, q *Query) error {
+	if q.Term == nil {
+		return nil
+	}
+
+	var err error
+	q.Term.Walk(func(t Term) bool {
+		if err == nil {
+			err = m.check(t)
+		}
+		return err == nil
+	})
+	return err
+}
+
+func (m safeMode) check(t Term) error {
+	switch t.termType {
+	case p.Term_DB_DROP, p.Term_TABLE_DROP, p.Term_INDEX_DROP:
+		if !m.opts.AllowDrop {
+			return RQLQueryRejectedError{Reason: fmt.Sprintf("%s is not allowed in safe mode", t.name), Term: &t}
+		}
+	case p.Term_RECONFIGURE, p.Term_REBALANCE:
+		if !m.opts.AllowReconfigure {
+			return RQLQueryRejectedError{Reason: fmt.Sprintf("%s is not allowed in safe mode", t.name), Term: &t}
+		}
+	case p.Term_DELETE, p.Term_UPDATE, p.Term_REPLACE:
+		if !m.opts.AllowTableWrites && len(t.args) > 0 && isWholeTable(t.args[0]) {
+			return RQLQueryRejectedError{Reason: fmt.Sprintf("%s of a whole table is not allowed in safe mode", t.name), Term: &t}
+		}
+	}
+	return nil
+}
+
+// isWholeTable returns true if the term selects every document of a table.
+func isWholeTable(t Term) bool {
+	for t.termType == p.Term_ORDER_BY && len(t.args) > 0 {
+		t = t.args[0]
+	}
+	return t.termType == p.Term_TABLE
+}
@@ -0,0 +1,106 @@
+package rethinkdb
+
+import (
+	"context"
+	"errors"
+
+	test "gopkg.in/check.v1"
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+)
+
+type InterceptorSuite struct{}
+
+var _ = test.Suite(&InterceptorSuite{})
+
+func (s *InterceptorSuite) TestTerm_Walk(c *test.C) {
+	var types []p.Term_TermType
+	DB("test").Table("users").Filter(map[string]interface{}{"age": 30}, FilterOpts{Default: true}).Walk(func(t Term) bool {
+		types = append(types, t.TermType())
+		return t.TermType() != p.Term_MAKE_OBJ
+	})
+	c.Assert(types, test.DeepEquals, []p.Term_TermType{
+		p.Term_FILTER, p.Term_TABLE, p.Term_DB, p.Term_DATUM, p.Term_DATUM, p.Term_MAKE_OBJ, p.Term_DATUM,
+	})
+
+	table := DB("test").Table("users")
+	c.Assert(table.Args(), test.HasLen, 2)
+	c.Assert(table.Args()[1].Datum(), test.Equals, "users")
+	c.Assert(table.Datum(), test.IsNil)
+
+	index, ok := table.GetAllByIndex("age", 1).OptArg("index")
+	c.Assert(ok, test.Equals, true)
+	c.Assert(index.Datum(), test.Equals, "age")
+}
+
+func (s *InterceptorSuite) TestSafeMode(c *test.C) {
+	users := DB("test").Table("users")
+	check := func(interceptor QueryInterceptor, t Term) error {
+		q := Query{Type: p.Query_START, Term: &t}
+		return interceptor.InterceptQuery(context.Background(), &q)
+	}
+
+	for _, t := range []Term{
+		users.Delete(),
+		users.OrderBy(OrderByOpts{Index: "id"}).Delete(),
+		users.Update(map[string]interface{}{"active": false}),
+		users.Replace(func(doc Term) Term { return doc }),
+		DB("test").TableDrop("users"),
+		DBDrop("test"),
+		users.IndexDrop("age"),
+		users.Reconfigure(ReconfigureOpts{Shards: 1, Replicas: 1}),
+		DB("test").Rebalance(),
+		Expr([]string{"a", "b"}).ForEach(func(name Term) Term { return DB("test").Table(name).Delete() }),
+	} {
+		err := check(SafeMode(), t)
+		var rejected RQLQueryRejectedError
+		c.Assert(errors.As(err, &rejected), test.Equals, true, test.Commentf("%s", t))
+	}
+
+	for _, t := range []Term{
+		users,
+		users.Insert(map[string]interface{}{"id": 1}),
+		users.Get(1).Delete(),
+		users.GetAll(1, 2).Update(map[string]interface{}{"active": false}),
+		users.Filter(map[string]interface{}{"active": false}).Delete(),
+		users.OrderBy(OrderByOpts{Index: "id"}).Limit(10).Delete(),
+		DB("test").TableCreate("users"),
+	} {
+		c.Assert(check(SafeMode(), t), test.IsNil, test.Commentf("%s", t))
+	}
+
+	opts := SafeModeOpts{AllowDrop: true, AllowTableWrites: true, AllowReconfigure: true}
+	for _, t := range []Term{users.Delete(), DBDrop("test"), DB("test").Rebalance()} {
+		c.Assert(check(SafeMode(opts), t), test.IsNil, test.Commentf("%s", t))
+	}
+
+	c.Assert(check(SafeMode(), users.Delete()), test.ErrorMatches,
+		`rethinkdb: query rejected: Delete of a whole table is not allowed in safe mode in:\nr.DB\("test"\).Table\("users"\).Delete\(\)`)
+}
+
+func (s *InterceptorSuite) TestSession_Interceptors(c *test.C) {
+	var intercepted []p.Query_QueryType
+	srv := &pipeServer{}
+	session := srv.connect(c, ConnectOpts{
+		Interceptors: []QueryInterceptor{
+			QueryInterceptorFunc(func(ctx context.Context, q *Query) error {
+				c.Assert(ctx, test.NotNil)
+				intercepted = append(intercepted, q.Type)
+				return q.SetOpt("profile", false)
+			}),
+			SafeMode(),
+		},
+	})
+	defer session.Close()
+
+	var n int
+	c.Assert(Expr(1).ReadOne(&n, session), test.IsNil)
+	_, opts := srv.lastStarted(c)
+	c.Assert(opts, test.DeepEquals, map[string]interface{}{"profile": false})
+
+	err := Table("users").Delete().Exec(session)
+	var rejected RQLQueryRejectedError
+	c.Assert(errors.As(err, &rejected), test.Equals, true)
+	c.Assert(rejected.Term.TermType(), test.Equals, p.Term_DELETE)
+	c.Assert(srv.received(p.Query_START), test.Equals, 1)
+	c.Assert(intercepted, test.DeepEquals, []p.Query_QueryType{p.Query_START, p.Query_START})
+}
@@ -339,3 +339,26 @@ func BenchmarkSequentialSoftWritesParallel10(b *testing.B) {
 	})
 
 }
+
+// BenchmarkLargeInsert inserts a batch of documents containing binary data,
+// the allocations are dominated by the encoding of the query.
+func BenchmarkLargeInsert(b *testing.B) {
+	data := make([]interface{}, 100)
+	for i := range data {
+		data[i] = map[string]interface{}{
+			"customer_id": strconv.Itoa(i),
+			"payload":     r.Binary(make([]byte, 16*1024)),
+		}
+	}
+	term := r.DB("benchmarks").Table("benchmarks").Insert(data)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := term.RunWrite(session, r.RunOpts{Durability: "soft"})
+		if err != nil {
+			b.Errorf("insert failed [%s] ", err)
+			return
+		}
+	}
+}
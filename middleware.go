@@ -0,0 +1,82 @@
+package rethinkdb
+
+import (
+	"context"
+)
+
+// QueryMiddleware wraps the QueryExecutor used by a session to run queries,
+// it is set using ConnectOpts.Middleware. Middleware can inject run options,
+// log or time queries, enforce policies or rewrite the term of a query before
+// calling next, or return an error without calling it.
+//
+// Middleware is usually implemented by a struct embedding next, so that only
+// Query and Exec need to be implemented:
+//
+//	type timing struct {
+//		r.QueryExecutor
+//	}
+//
+//	func (t timing) Query(ctx context.Context, q r.Query) (*r.Cursor, error) {
+//		start := time.Now()
+//		cursor, err := t.QueryExecutor.Query(ctx, q)
+//		log.Printf("%s took %s", q.Term, time.Since(start))
+//		return cursor, err
+//	}
+//
+//	session, err := r.Connect(r.ConnectOpts{
+//		Address: url,
+//		Middleware: []r.QueryMiddleware{
+//			func(next r.QueryExecutor) r.QueryExecutor { return timing{next} },
+//		},
+//	})
+type QueryMiddleware func(next QueryExecutor) QueryExecutor
+
+// sessionExecutor is the innermost QueryExecutor of a session, it runs
+// queries without the middleware.
+type sessionExecutor struct {
+	s *Session
+}
+
+func (e sessionExecutor) IsConnected() bool {
+	return e.s.IsConnected()
+}
+
+func (e sessionExecutor) Query(ctx context.Context, q Query) (*Cursor, error) {
+	return e.s.query(ctx, q)
+}
+
+func (e sessionExecutor) Exec(ctx context.Context, q Query) error {
+	return e.s.exec(ctx, q)
+}
+
+func (e sessionExecutor) newQuery(t Term, opts map[string]interface{}) (Query, error) {
+	return e.s.newQuery(t, opts)
+}
+
+// SetTerm replaces the term run by the query, for example by a
+// QueryMiddleware. The term is built when the query is sent.
+func (q *Query) SetTerm(t Term) {
+	q.Term = &t
+	q.builtTerm = nil
+}
+
+// SetOpt sets a run option of the query, using the name the option has in
+// RunOpts tags, for example "durability" or "read_mode".
+func (q *Query) SetOpt(name string, value interface{}) error {
+	if !isDriverOption(name) {
+		built, err := Expr(value).Build()
+		if err != nil {
+			return err
+		}
+		value = built
+	}
+
+	// The options may be shared with the caller
+	opts := make(map[string]interface{}, len(q.Opts)+1)
+	for k, v := range q.Opts {
+		opts[k] = v
+	}
+	opts[name] = value
+	q.Opts = opts
+	return nil
+}
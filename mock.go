@@ -7,6 +7,7 @@ import (
 	"net"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
@@ -214,6 +215,9 @@ func NewMock(opts ...ConnectOpts) *Mock {
 	if len(opts) > 0 {
 		m.opts = opts[0]
 	}
+	if m.opts.Codec != nil {
+		ignoreTerms(m.opts.Codec)
+	}
 
 	return m
 }
@@ -350,13 +354,25 @@ func (m *Mock) Query(ctx context.Context, q Query) (*Cursor, error) {
 		ctx = context.Background()
 	}
 
-	conn := newConnection(newMockConn(query.Response), "mock", &ConnectOpts{})
+	conn := newConnection(newMockConn(query.Response, m.opts.codec()), "mock", &ConnectOpts{Codec: m.opts.Codec})
 
 	query.Query.Type = p.Query_CONTINUE
 	query.Query.Token = conn.nextToken()
 
+	// The results are decoded using the driver options of the query which was
+	// run, unless they were set when the query was expected
+	opts := map[string]interface{}{}
+	for k, v := range q.Opts {
+		if isDriverOption(k) {
+			opts[k] = v
+		}
+	}
+	for k, v := range query.Query.Opts {
+		opts[k] = v
+	}
+
 	// Build cursor and return
-	c := newCursor(ctx, conn, "", query.Query.Token, query.Query.Term, query.Query.Opts)
+	c := newCursor(ctx, conn, "", query.Query.Token, query.Query.Term, opts)
 	c.finished = true
 	c.fetching = false
 	c.isAtom = true
@@ -368,7 +384,7 @@ func (m *Mock) Query(ctx context.Context, q Query) (*Cursor, error) {
 	go conn.processResponses()
 
 	c.mu.Lock()
-	err := c.fetchMore()
+	err := c.fetchMore(ctx)
 	c.mu.Unlock()
 	if err != nil {
 		return nil, err
@@ -384,7 +400,7 @@ func (m *Mock) Exec(ctx context.Context, q Query) error {
 }
 
 func (m *Mock) newQuery(t Term, opts map[string]interface{}) (Query, error) {
-	return newQuery(t, opts, &m.opts)
+	return newQuery(t, withDefaultRunOpts(opts, m.opts.DefaultRunOpts), &m.opts)
 }
 
 func (m *Mock) findExpectedQuery(q Query) (int, *MockQuery) {
@@ -430,10 +446,13 @@ type mockConn struct {
 	value       []byte
 	tokens      chan int64
 	valueGetter func() []interface{}
+	codec       *encoding.Codec
+	// queries is the number of queries written to the connection.
+	queries atomic.Int32
 }
 
-func newMockConn(response interface{}) *mockConn {
-	c := &mockConn{tokens: make(chan int64, 1)}
+func newMockConn(response interface{}, codec *encoding.Codec) *mockConn {
+	c := &mockConn{tokens: make(chan int64, 1), codec: codec}
 	switch g := response.(type) {
 	case chan []interface{}:
 		c.valueGetter = func() []interface{} { return <-g }
@@ -474,7 +493,7 @@ func (c *mockConn) Read(b []byte) (n int, err error) {
 
 		jresps := make([]json.RawMessage, len(values))
 		for i := range values {
-			coded, err := encoding.Encode(values[i])
+			coded, err := c.codec.Encode(values[i])
 			if err != nil {
 				panic(fmt.Sprintf("failed to encode response: %v", err))
 			}
@@ -517,6 +536,7 @@ func (c *mockConn) Write(b []byte) (n int, err error) {
 		panic("connBad socket write")
 	}
 	token := int64(binary.LittleEndian.Uint64(b[:8]))
+	c.queries.Add(1)
 	c.tokens <- token
 	return len(b), nil
 }
@@ -5,7 +5,9 @@ import (
 	"testing"
 
 	test "gopkg.in/check.v1"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
 	"gopkg.in/rethinkdb/rethinkdb-go.v6/internal/integration/tests"
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
 // Hook up gocheck into the gotest runner.
@@ -433,3 +435,68 @@ func (t *simpleTestingT) FailNow() {
 func (t *simpleTestingT) Failed() bool {
 	return t.failed
 }
+
+func (s *MockSuite) TestMockCodec(c *test.C) {
+	type Doc struct {
+		ID   string `json:"id"`
+		Name string `json:"full_name"`
+	}
+
+	mock := NewMock(ConnectOpts{
+		Codec: encoding.NewCodec(encoding.CodecOpts{Tags: []string{encoding.JSONTagName}}),
+	})
+	term := DB("test").Table("test").Insert(Doc{ID: "mocked", Name: "a"})
+	mock.On(term).Return(WriteResponse{Inserted: 1}, nil)
+
+	q, err := mock.newQuery(term, nil)
+	c.Assert(err, test.IsNil)
+	c.Assert(q.builtTerm, tests.JsonEquals, []interface{}{
+		int(p.Term_INSERT),
+		[]interface{}{
+			[]interface{}{int(p.Term_TABLE), []interface{}{[]interface{}{int(p.Term_DB), []interface{}{"test"}}, "test"}},
+			map[string]interface{}{"id": "mocked", "full_name": "a"},
+		},
+	})
+
+	res, err := term.RunWrite(mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(res.Inserted, test.Equals, 1)
+
+	mock.On(DB("test").Table("test").Get("mocked")).Return(Doc{ID: "mocked", Name: "a"}, nil)
+	cursor, err := DB("test").Table("test").Get("mocked").Run(mock)
+	c.Assert(err, test.IsNil)
+
+	var doc Doc
+	c.Assert(cursor.One(&doc), test.IsNil)
+	c.Assert(doc, test.Equals, Doc{ID: "mocked", Name: "a"})
+	mock.AssertExpectations(c)
+}
+
+// countedDoc counts the number of times it is encoded.
+type countedDoc struct {
+	encoded *int
+}
+
+func (d countedDoc) MarshalRQL() (interface{}, error) {
+	*d.encoded++
+	return map[string]interface{}{"id": 1}, nil
+}
+
+func (s *MockSuite) TestCodec_EncodeOnce(c *test.C) {
+	for _, codec := range []*encoding.Codec{nil, encoding.NewCodec(encoding.CodecOpts{Tags: []string{encoding.JSONTagName}})} {
+		var encoded int
+		term := Table("test").Insert(countedDoc{encoded: &encoded})
+		c.Assert(encoded, test.Equals, 0)
+
+		q, err := newQuery(term, nil, &ConnectOpts{Codec: codec})
+		c.Assert(err, test.IsNil)
+		c.Assert(encoded, test.Equals, 1)
+		c.Assert(q.builtTerm, tests.JsonEquals, []interface{}{
+			int(p.Term_INSERT),
+			[]interface{}{
+				[]interface{}{int(p.Term_TABLE), []interface{}{"test"}},
+				map[string]interface{}{"id": 1},
+			},
+		})
+	}
+}
@@ -88,6 +88,14 @@ func (n *Node) NoReplyWait() error {
 	})
 }
 
+// noReplyWait is NoReplyWait for every connection of the pool.
+func (n *Node) noReplyWait(ctx context.Context) error {
+	return n.pool.execAll(ctx, Query{
+		Type: p.Query_NOREPLY_WAIT,
+		Opts: map[string]interface{}{},
+	})
+}
+
 // Query executes a ReQL query using this nodes connection pool.
 func (n *Node) Query(ctx context.Context, q Query) (cursor *Cursor, err error) {
 	if n.Closed() {
@@ -0,0 +1,695 @@
+package rethinkdb
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+)
+
+const (
+	// defaultSelectorRefreshInterval is how often the node selectors reload
+	// the information they read from the system tables.
+	defaultSelectorRefreshInterval = time.Minute
+	// defaultDatabase is the database used by the server when the query does
+	// not specify one.
+	defaultDatabase = "test"
+)
+
+// NodeSelector is used by the cluster to pick the node each query is sent
+// to and can be set using the NodeSelector field of ConnectOpts. If no
+// selector is set the driver uses the go-hostpool epsilon-greedy algorithm.
+//
+// Selectors can be combined, for example to prefer the nodes tagged "us_east"
+// and then pick the one with the lowest latency:
+//
+//	session, err := r.Connect(r.ConnectOpts{
+//		Addresses:     []string{"localhost:28015", "localhost:28016"},
+//		DiscoverHosts: true,
+//		NodeSelector: r.NewTagNodeSelector([]string{"us_east"},
+//			r.NewLatencyNodeSelector(10*time.Second, nil)),
+//	})
+//
+// Implementations must be safe for concurrent use.
+type NodeSelector interface {
+	// Select returns the node used to run the query, nodes contains the open
+	// nodes in the cluster and is never empty.
+	Select(q Query, nodes []*Node) (*Node, error)
+	// Mark is called with the result of running a query on the node returned
+	// by Select.
+	Mark(node *Node, err error)
+}
+
+// NewRoundRobinNodeSelector returns a NodeSelector which sends queries to each
+// node in turn. It is used by the other selectors when no fallback selector
+// is given.
+func NewRoundRobinNodeSelector() NodeSelector {
+	return &roundRobinNodeSelector{}
+}
+
+type roundRobinNodeSelector struct {
+	next uint32
+}
+
+func (s *roundRobinNodeSelector) Select(q Query, nodes []*Node) (*Node, error) {
+	n := atomic.AddUint32(&s.next, 1)
+	return nodes[int(n%uint32(len(nodes)))], nil
+}
+
+func (s *roundRobinNodeSelector) Mark(node *Node, err error) {}
+
+// NewTagNodeSelector returns a NodeSelector which prefers the nodes of the
+// servers with at least one of the given tags, falling back to all of the
+// nodes if none of them match. The node is then chosen from the candidates
+// using next, if next is nil the queries are sent to each candidate in turn.
+//
+// Server tags are read from the rethinkdb.server_config table and are
+// refreshed in the background every minute.
+func NewTagNodeSelector(tags []string, next NodeSelector) NodeSelector {
+	if next == nil {
+		next = NewRoundRobinNodeSelector()
+	}
+
+	return &tagNodeSelector{
+		tags:    tags,
+		next:    next,
+		servers: newSystemTableCache(defaultSelectorRefreshInterval),
+	}
+}
+
+type tagNodeSelector struct {
+	tags    []string
+	next    NodeSelector
+	servers *systemTableCache
+}
+
+func (s *tagNodeSelector) Select(q Query, nodes []*Node) (*Node, error) {
+	servers := loadServerConfigs(s.servers, nodes)
+
+	var candidates []*Node
+	for _, node := range nodes {
+		for _, tag := range servers[node.ID].Tags {
+			if containsString(s.tags, tag) {
+				candidates = append(candidates, node)
+				break
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = nodes
+	}
+
+	return s.next.Select(q, candidates)
+}
+
+func (s *tagNodeSelector) Mark(node *Node, err error) {
+	s.next.Mark(node, err)
+}
+
+// NewLatencyNodeSelector returns a NodeSelector which sends queries to the
+// node with the lowest round-trip time. The round-trip time of each node is
+// measured every interval by sending a SERVER_INFO query to each node in the
+// background. Until a node has been measured, or if the last measurement
+// failed, queries are sent using next (by default to each node in turn).
+func NewLatencyNodeSelector(interval time.Duration, next NodeSelector) NodeSelector {
+	if next == nil {
+		next = NewRoundRobinNodeSelector()
+	}
+
+	return &latencyNodeSelector{
+		interval: interval,
+		next:     next,
+		rtts:     map[string]time.Duration{},
+	}
+}
+
+type latencyNodeSelector struct {
+	interval time.Duration
+	next     NodeSelector
+
+	mu       sync.Mutex
+	rtts     map[string]time.Duration // keyed by node ID
+	probedAt time.Time
+	probing  bool
+}
+
+func (s *latencyNodeSelector) Select(q Query, nodes []*Node) (*Node, error) {
+	s.mu.Lock()
+	if !s.probing && time.Since(s.probedAt) >= s.interval {
+		s.probing = true
+		go s.probe(nodes)
+	}
+
+	var fastest *Node
+	var fastestRTT time.Duration
+	for _, node := range nodes {
+		rtt, ok := s.rtts[node.ID]
+		if ok && (fastest == nil || rtt < fastestRTT) {
+			fastest, fastestRTT = node, rtt
+		}
+	}
+	s.mu.Unlock()
+
+	if fastest == nil {
+		return s.next.Select(q, nodes)
+	}
+
+	return fastest, nil
+}
+
+func (s *latencyNodeSelector) Mark(node *Node, err error) {
+	if isConnectionError(err) {
+		// Stop using the node until it has been measured again
+		s.mu.Lock()
+		delete(s.rtts, node.ID)
+		s.mu.Unlock()
+	}
+
+	s.next.Mark(node, err)
+}
+
+// probe measures the round-trip time of each node, smoothing the result with
+// the previous measurement.
+func (s *latencyNodeSelector) probe(nodes []*Node) {
+	rtts := make(map[string]time.Duration, len(nodes))
+	for _, node := range nodes {
+		start := time.Now()
+		if _, err := node.Server(); err != nil {
+			continue
+		}
+		rtts[node.ID] = time.Since(start)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, rtt := range rtts {
+		if prev, ok := s.rtts[id]; ok {
+			rtt = (prev*3 + rtt) / 4
+		}
+		rtts[id] = rtt
+	}
+	s.rtts = rtts
+	s.probedAt = time.Now()
+	s.probing = false
+}
+
+// NewPrimaryReplicaNodeSelector returns a NodeSelector which sends point
+// writes (an Insert of a single document or an Update, Replace or Delete of a
+// document selected with Get) to the node of the primary replica of the table,
+// avoiding an extra hop inside the cluster. Other queries are sent using next
+// (by default to each node in turn).
+//
+// The shards of each table are read using Table().Config() and refreshed in
+// the background every minute. As the driver does not know the split points
+// of the shards, point writes are only routed when all the shards of the
+// table share the same primary replica.
+func NewPrimaryReplicaNodeSelector(next NodeSelector) NodeSelector {
+	if next == nil {
+		next = NewRoundRobinNodeSelector()
+	}
+
+	return &primaryReplicaNodeSelector{
+		next:    next,
+		servers: newSystemTableCache(defaultSelectorRefreshInterval),
+		tables:  newSystemTableCache(defaultSelectorRefreshInterval),
+	}
+}
+
+type primaryReplicaNodeSelector struct {
+	next    NodeSelector
+	servers *systemTableCache
+	tables  *systemTableCache
+}
+
+func (s *primaryReplicaNodeSelector) Select(q Query, nodes []*Node) (*Node, error) {
+	db, table, ok := pointWriteTable(q)
+	if !ok {
+		return s.next.Select(q, nodes)
+	}
+
+	primary, _ := s.tables.get(db+"."+table, nodes, func(node *Node) (interface{}, error) {
+		var config tableConfig
+		if err := runSystemQuery(node, DB(db).Table(table).Config(), &config); err != nil {
+			return nil, err
+		}
+
+		return config.primaryReplica(), nil
+	}).(string)
+	if primary == "" {
+		return s.next.Select(q, nodes)
+	}
+
+	servers := loadServerConfigs(s.servers, nodes)
+	for _, node := range nodes {
+		if servers[node.ID].Name == primary {
+			return node, nil
+		}
+	}
+
+	return s.next.Select(q, nodes)
+}
+
+func (s *primaryReplicaNodeSelector) Mark(node *Node, err error) {
+	s.next.Mark(node, err)
+}
+
+// NewReadModeNodeSelector returns a NodeSelector which routes queries on a
+// table based on the placement of the table's replicas, as reported by the
+// rethinkdb.table_status table:
+//
+//   - reads using the "outdated" read mode are sent to one of the nodes
+//     hosting a replica of the table, chosen using next. Combined with
+//     NewLatencyNodeSelector or NewTagNodeSelector this sends the read to
+//     the closest replica.
+//   - other reads and all writes are sent to the node of the primary replica.
+//
+// The read mode is read from RunOpts.ReadMode or from the ReadMode option of
+// the Table term. Queries which do not select a table, or for which the
+// placement is not known, are sent using next (by default to each node in
+// turn). As the driver does not know the split points of the shards, queries
+// are only sent to the primary replica when all the shards of the table share
+// the same primary replica.
+//
+// Table placement is refreshed in the background every minute.
+func NewReadModeNodeSelector(next NodeSelector) NodeSelector {
+	if next == nil {
+		next = NewRoundRobinNodeSelector()
+	}
+
+	return &readModeNodeSelector{
+		next:    next,
+		servers: newSystemTableCache(defaultSelectorRefreshInterval),
+		tables:  newSystemTableCache(defaultSelectorRefreshInterval),
+	}
+}
+
+type readModeNodeSelector struct {
+	next    NodeSelector
+	servers *systemTableCache
+	tables  *systemTableCache
+}
+
+func (s *readModeNodeSelector) Select(q Query, nodes []*Node) (*Node, error) {
+	db, table, ok := queryTable(q)
+	if !ok {
+		return s.next.Select(q, nodes)
+	}
+
+	status, ok := s.tables.get(db+"."+table, nodes, func(node *Node) (interface{}, error) {
+		var rows []tableStatus
+		err := runSystemQuery(node, DB(SystemDatabase).Table(TableStatusSystemTable).Filter(map[string]interface{}{
+			"db":   db,
+			"name": table,
+		}), &rows)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			return tableStatus{}, nil
+		}
+
+		return rows[0], nil
+	}).(tableStatus)
+	if !ok {
+		return s.next.Select(q, nodes)
+	}
+
+	servers := loadServerConfigs(s.servers, nodes)
+
+	if queryReadMode(q) == "outdated" && !isWriteTerm(*q.Term) {
+		replicas := status.replicas()
+
+		var candidates []*Node
+		for _, node := range nodes {
+			if containsString(replicas, servers[node.ID].Name) {
+				candidates = append(candidates, node)
+			}
+		}
+		if len(candidates) == 0 {
+			candidates = nodes
+		}
+
+		return s.next.Select(q, candidates)
+	}
+
+	if primary := status.primaryReplica(); primary != "" {
+		for _, node := range nodes {
+			if servers[node.ID].Name == primary {
+				return node, nil
+			}
+		}
+	}
+
+	return s.next.Select(q, nodes)
+}
+
+func (s *readModeNodeSelector) Mark(node *Node, err error) {
+	s.next.Mark(node, err)
+}
+
+type serverConfig struct {
+	ID   string   `rethinkdb:"id"`
+	Name string   `rethinkdb:"name"`
+	Tags []string `rethinkdb:"tags"`
+}
+
+type tableConfig struct {
+	Shards []struct {
+		PrimaryReplica string   `rethinkdb:"primary_replica"`
+		Replicas       []string `rethinkdb:"replicas"`
+	} `rethinkdb:"shards"`
+}
+
+// primaryReplica returns the name of the primary replica of the table if all
+// of the shards have the same primary replica.
+func (c tableConfig) primaryReplica() string {
+	primary := ""
+	for i, shard := range c.Shards {
+		if i > 0 && shard.PrimaryReplica != primary {
+			return ""
+		}
+		primary = shard.PrimaryReplica
+	}
+
+	return primary
+}
+
+type tableStatus struct {
+	Shards []struct {
+		PrimaryReplicas []string `rethinkdb:"primary_replicas"`
+		Replicas        []struct {
+			Server string `rethinkdb:"server"`
+			State  string `rethinkdb:"state"`
+		} `rethinkdb:"replicas"`
+	} `rethinkdb:"shards"`
+}
+
+// primaryReplica returns the name of the primary replica of the table if all
+// of the shards have the same single primary replica.
+func (s tableStatus) primaryReplica() string {
+	primary := ""
+	for i, shard := range s.Shards {
+		if len(shard.PrimaryReplicas) != 1 {
+			return ""
+		}
+		if i > 0 && shard.PrimaryReplicas[0] != primary {
+			return ""
+		}
+		primary = shard.PrimaryReplicas[0]
+	}
+
+	return primary
+}
+
+// replicas returns the names of the servers which have a ready replica of
+// every shard of the table.
+func (s tableStatus) replicas() []string {
+	var replicas []string
+	for i, shard := range s.Shards {
+		var ready []string
+		for _, replica := range shard.Replicas {
+			if replica.State == "ready" && (i == 0 || containsString(replicas, replica.Server)) {
+				ready = append(ready, replica.Server)
+			}
+		}
+		replicas = ready
+	}
+
+	return replicas
+}
+
+// loadServerConfigs returns the contents of the server_config table keyed by
+// server ID.
+func loadServerConfigs(cache *systemTableCache, nodes []*Node) map[string]serverConfig {
+	servers, _ := cache.get(ServerConfigSystemTable, nodes, func(node *Node) (interface{}, error) {
+		var rows []serverConfig
+		if err := runSystemQuery(node, DB(SystemDatabase).Table(ServerConfigSystemTable), &rows); err != nil {
+			return nil, err
+		}
+
+		servers := make(map[string]serverConfig, len(rows))
+		for _, row := range rows {
+			servers[row.ID] = row
+		}
+		return servers, nil
+	}).(map[string]serverConfig)
+
+	return servers
+}
+
+// systemTableCache caches values read from the system tables. A value is
+// loaded synchronously the first time it is requested, after that it is
+// reloaded in the background once it is older than the refresh interval. If
+// loading fails the previous value is kept until the next attempt.
+type systemTableCache struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*systemTableCacheEntry
+}
+
+type systemTableCacheEntry struct {
+	value    interface{}
+	loadedAt time.Time
+	loading  bool
+}
+
+func newSystemTableCache(interval time.Duration) *systemTableCache {
+	return &systemTableCache{
+		interval: interval,
+		entries:  map[string]*systemTableCacheEntry{},
+	}
+}
+
+func (c *systemTableCache) get(key string, nodes []*Node, load func(node *Node) (interface{}, error)) interface{} {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &systemTableCacheEntry{}
+		c.entries[key] = entry
+	}
+	if entry.loading || time.Since(entry.loadedAt) < c.interval {
+		value := entry.value
+		c.mu.Unlock()
+		return value
+	}
+	entry.loading = true
+	firstLoad := entry.loadedAt.IsZero()
+	c.mu.Unlock()
+
+	if !firstLoad {
+		go c.load(entry, nodes, load)
+		return c.value(entry)
+	}
+
+	c.load(entry, nodes, load)
+	return c.value(entry)
+}
+
+func (c *systemTableCache) load(entry *systemTableCacheEntry, nodes []*Node, load func(node *Node) (interface{}, error)) {
+	var value interface{}
+	var err error
+	for _, node := range nodes {
+		if value, err = load(node); err == nil {
+			break
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err == nil {
+		entry.value = value
+	}
+	entry.loadedAt = time.Now()
+	entry.loading = false
+}
+
+func (c *systemTableCache) value(entry *systemTableCacheEntry) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return entry.value
+}
+
+// runSystemQuery runs a query on the given node and reads all of the results
+// into dest.
+func runSystemQuery(node *Node, t Term, dest interface{}) error {
+	q, err := newQuery(t, map[string]interface{}{}, &ConnectOpts{})
+	if err != nil {
+		return err
+	}
+
+	cursor, err := node.Query(context.TODO(), q)
+	if err != nil {
+		return err
+	}
+
+	return cursor.All(dest)
+}
+
+// pointWriteTable returns the database and table name if the query is a write
+// to a single document.
+func pointWriteTable(q Query) (db string, table string, ok bool) {
+	t := q.Term
+	if t == nil {
+		return "", "", false
+	}
+
+	var tableTerm Term
+	switch t.termType {
+	case p.Term_INSERT:
+		if len(t.args) != 2 || !isObjectTerm(t.args[1]) {
+			return "", "", false
+		}
+		tableTerm = t.args[0]
+	case p.Term_UPDATE, p.Term_REPLACE, p.Term_DELETE:
+		if len(t.args) == 0 || t.args[0].termType != p.Term_GET || len(t.args[0].args) != 2 {
+			return "", "", false
+		}
+		tableTerm = t.args[0].args[0]
+	default:
+		return "", "", false
+	}
+
+	return tableName(tableTerm, q.Opts)
+}
+
+// isObjectTerm returns true if the term is a single document, without
+// encoding the structs and maps passed to Expr.
+func isObjectTerm(t Term) bool {
+	if t.value == nil {
+		return t.termType == p.Term_MAKE_OBJ
+	}
+
+	typ := reflect.TypeOf(t.value)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct || typ.Kind() == reflect.Map
+}
+
+// queryTable returns the database and table name of the table the query
+// operates on, following the first argument of each term until a TABLE term is
+// found.
+func queryTable(q Query) (db string, table string, ok bool) {
+	if q.Term == nil {
+		return "", "", false
+	}
+
+	for t := *q.Term; ; t = t.args[0] {
+		if t.termType == p.Term_TABLE {
+			return tableName(t, q.Opts)
+		}
+		if len(t.args) == 0 {
+			return "", "", false
+		}
+	}
+}
+
+// queryReadMode returns the read mode of the query, set either as a run option
+// or as an option of the table term.
+func queryReadMode(q Query) string {
+	if mode, ok := q.Opts["read_mode"].(string); ok {
+		return mode
+	}
+	if outdated, ok := q.Opts["use_outdated"].(bool); ok && outdated {
+		return "outdated"
+	}
+
+	if q.Term == nil {
+		return ""
+	}
+	for t := *q.Term; ; t = t.args[0] {
+		if t.termType == p.Term_TABLE {
+			if mode, ok := t.optArgs["read_mode"]; ok {
+				if mode, ok := mode.expand().data.(string); ok {
+					return mode
+				}
+			}
+			if outdated, ok := t.optArgs["use_outdated"]; ok {
+				if outdated, ok := outdated.expand().data.(bool); ok && outdated {
+					return "outdated"
+				}
+			}
+			return ""
+		}
+		if len(t.args) == 0 {
+			return ""
+		}
+	}
+}
+
+// isWriteTerm returns true if the term, or any of its arguments, writes to a
+// table.
+func isWriteTerm(t Term) bool {
+	t = t.expand()
+	switch t.termType {
+	case p.Term_INSERT, p.Term_UPDATE, p.Term_REPLACE, p.Term_DELETE:
+		return true
+	}
+
+	for _, arg := range t.args {
+		if isWriteTerm(arg) {
+			return true
+		}
+	}
+	for _, arg := range t.optArgs {
+		if isWriteTerm(arg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tableName returns the database and table name selected by a TABLE term,
+// using the db option of the query if the term does not select a database.
+func tableName(t Term, opts map[string]interface{}) (db string, table string, ok bool) {
+	if t.termType != p.Term_TABLE || len(t.args) == 0 {
+		return "", "", false
+	}
+
+	db = defaultDatabase
+	if built, ok := opts["db"].([]interface{}); ok && len(built) == 2 {
+		if args, ok := built[1].([]interface{}); ok && len(args) == 1 {
+			if name, ok := args[0].(string); ok {
+				db = name
+			}
+		}
+	}
+
+	nameTerm := t.args[0]
+	if len(t.args) == 2 {
+		dbTerm := t.args[0]
+		if dbTerm.termType != p.Term_DB || len(dbTerm.args) != 1 {
+			return "", "", false
+		}
+		if db, ok = dbTerm.args[0].expand().data.(string); !ok {
+			return "", "", false
+		}
+		nameTerm = t.args[1]
+	}
+	nameTerm = nameTerm.expand()
+
+	table, ok = nameTerm.data.(string)
+	if nameTerm.termType != p.Term_DATUM || !ok {
+		return "", "", false
+	}
+
+	return db, table, true
+}
+
+// isConnectionError returns true if the error was caused by a failure to
+// communicate with the server.
+func isConnectionError(err error) bool {
+	if _, ok := err.(RQLConnectionError); ok {
+		return true
+	}
+
+	return err == ErrConnectionClosed || err == ErrInvalidNode
+}
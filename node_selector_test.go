@@ -0,0 +1,244 @@
+package rethinkdb
+
+import (
+	"time"
+
+	test "gopkg.in/check.v1"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+)
+
+type NodeSelectorSuite struct{}
+
+var _ = test.Suite(&NodeSelectorSuite{})
+
+func testNodes(ids ...string) []*Node {
+	nodes := make([]*Node, len(ids))
+	for i, id := range ids {
+		nodes[i] = newNode(id, []Host{NewHost(id, 28015)}, nil)
+	}
+	return nodes
+}
+
+// preloadedCache returns a cache which already contains the given values.
+func preloadedCache(values map[string]interface{}) *systemTableCache {
+	cache := newSystemTableCache(time.Hour)
+	for key, value := range values {
+		cache.entries[key] = &systemTableCacheEntry{value: value, loadedAt: time.Now()}
+	}
+	return cache
+}
+
+func (s *NodeSelectorSuite) TestRoundRobinNodeSelector(c *test.C) {
+	nodes := testNodes("node1", "node2", "node3")
+	selector := NewRoundRobinNodeSelector()
+
+	seen := map[string]int{}
+	for i := 0; i < 6; i++ {
+		node, err := selector.Select(testQuery(Table("test")), nodes)
+		c.Assert(err, test.IsNil)
+		seen[node.ID]++
+	}
+	c.Assert(seen, test.DeepEquals, map[string]int{"node1": 2, "node2": 2, "node3": 2})
+}
+
+func (s *NodeSelectorSuite) TestTagNodeSelector(c *test.C) {
+	nodes := testNodes("node1", "node2", "node3")
+	selector := &tagNodeSelector{
+		tags: []string{"us_east"},
+		next: NewRoundRobinNodeSelector(),
+		servers: preloadedCache(map[string]interface{}{
+			ServerConfigSystemTable: map[string]serverConfig{
+				"node1": {ID: "node1", Tags: []string{"default", "us_west"}},
+				"node2": {ID: "node2", Tags: []string{"default", "us_east"}},
+				"node3": {ID: "node3", Tags: []string{"default"}},
+			},
+		}),
+	}
+
+	for i := 0; i < 3; i++ {
+		node, err := selector.Select(testQuery(Table("test")), nodes)
+		c.Assert(err, test.IsNil)
+		c.Assert(node.ID, test.Equals, "node2")
+	}
+
+	// Fallback to all nodes if none have the tag
+	selector.tags = []string{"eu"}
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		node, err := selector.Select(testQuery(Table("test")), nodes)
+		c.Assert(err, test.IsNil)
+		seen[node.ID] = true
+	}
+	c.Assert(seen, test.HasLen, 3)
+}
+
+func (s *NodeSelectorSuite) TestLatencyNodeSelector(c *test.C) {
+	nodes := testNodes("node1", "node2", "node3")
+	selector := NewLatencyNodeSelector(time.Hour, nil).(*latencyNodeSelector)
+	selector.probedAt = time.Now()
+	selector.rtts = map[string]time.Duration{
+		"node1": 5 * time.Millisecond,
+		"node2": time.Millisecond,
+		"node3": 2 * time.Millisecond,
+	}
+
+	node, err := selector.Select(testQuery(Table("test")), nodes)
+	c.Assert(err, test.IsNil)
+	c.Assert(node.ID, test.Equals, "node2")
+
+	// Connection errors remove the node until it is measured again
+	selector.Mark(node, ErrConnectionClosed)
+	node, err = selector.Select(testQuery(Table("test")), nodes)
+	c.Assert(err, test.IsNil)
+	c.Assert(node.ID, test.Equals, "node3")
+}
+
+func (s *NodeSelectorSuite) TestPrimaryReplicaNodeSelector(c *test.C) {
+	nodes := testNodes("id1", "id2", "id3")
+	selector := &primaryReplicaNodeSelector{
+		next: NewRoundRobinNodeSelector(),
+		servers: preloadedCache(map[string]interface{}{
+			ServerConfigSystemTable: map[string]serverConfig{
+				"id1": {ID: "id1", Name: "server1"},
+				"id2": {ID: "id2", Name: "server2"},
+				"id3": {ID: "id3", Name: "server3"},
+			},
+		}),
+		tables: preloadedCache(map[string]interface{}{
+			"db.table": "server3",
+		}),
+	}
+
+	for i := 0; i < 3; i++ {
+		node, err := selector.Select(testQuery(DB("db").Table("table").Get("id").Delete()), nodes)
+		c.Assert(err, test.IsNil)
+		c.Assert(node.ID, test.Equals, "id3")
+	}
+}
+
+func (s *NodeSelectorSuite) TestReadModeNodeSelector(c *test.C) {
+	nodes := testNodes("id1", "id2", "id3")
+
+	var status tableStatus
+	c.Assert(encoding.Decode(&status, map[string]interface{}{
+		"shards": []interface{}{
+			map[string]interface{}{
+				"primary_replicas": []interface{}{"server1"},
+				"replicas": []interface{}{
+					map[string]interface{}{"server": "server1", "state": "ready"},
+					map[string]interface{}{"server": "server2", "state": "ready"},
+					map[string]interface{}{"server": "server3", "state": "backfilling"},
+				},
+			},
+		},
+	}), test.IsNil)
+
+	selector := &readModeNodeSelector{
+		next: NewRoundRobinNodeSelector(),
+		servers: preloadedCache(map[string]interface{}{
+			ServerConfigSystemTable: map[string]serverConfig{
+				"id1": {ID: "id1", Name: "server1"},
+				"id2": {ID: "id2", Name: "server2"},
+				"id3": {ID: "id3", Name: "server3"},
+			},
+		}),
+		tables: preloadedCache(map[string]interface{}{
+			"db.table": status,
+		}),
+	}
+
+	// Up to date reads and writes go to the primary replica
+	for _, term := range []Term{
+		DB("db").Table("table").Get("id"),
+		DB("db").Table("table").Insert(map[string]interface{}{"a": 1}),
+		DB("db").Table("table", TableOpts{ReadMode: "outdated"}).Filter(map[string]interface{}{"a": 1}).Delete(),
+	} {
+		node, err := selector.Select(testQuery(term), nodes)
+		c.Assert(err, test.IsNil)
+		c.Assert(node.ID, test.Equals, "id1", test.Commentf("%s", term))
+	}
+
+	// Outdated reads go to any of the ready replicas
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		node, err := selector.Select(testQuery(DB("db").Table("table", TableOpts{ReadMode: "outdated"}).Get("id")), nodes)
+		c.Assert(err, test.IsNil)
+		seen[node.ID] = true
+
+		q := testQuery(DB("db").Table("table").Count())
+		q.Opts["read_mode"] = "outdated"
+		node, err = selector.Select(q, nodes)
+		c.Assert(err, test.IsNil)
+		seen[node.ID] = true
+	}
+	c.Assert(seen, test.DeepEquals, map[string]bool{"id1": true, "id2": true})
+}
+
+func (s *NodeSelectorSuite) TestTableStatus_Replicas(c *test.C) {
+	var status tableStatus
+	c.Assert(encoding.Decode(&status, map[string]interface{}{
+		"shards": []interface{}{
+			map[string]interface{}{
+				"primary_replicas": []interface{}{"server1"},
+				"replicas": []interface{}{
+					map[string]interface{}{"server": "server1", "state": "ready"},
+					map[string]interface{}{"server": "server2", "state": "ready"},
+				},
+			},
+			map[string]interface{}{
+				"primary_replicas": []interface{}{"server2"},
+				"replicas": []interface{}{
+					map[string]interface{}{"server": "server2", "state": "ready"},
+					map[string]interface{}{"server": "server3", "state": "ready"},
+				},
+			},
+		},
+	}), test.IsNil)
+
+	c.Assert(status.replicas(), test.DeepEquals, []string{"server2"})
+	c.Assert(status.primaryReplica(), test.Equals, "")
+}
+
+func (s *NodeSelectorSuite) TestTableConfig_PrimaryReplica(c *test.C) {
+	var config tableConfig
+	c.Assert(config.primaryReplica(), test.Equals, "")
+
+	config.Shards = make([]struct {
+		PrimaryReplica string   `rethinkdb:"primary_replica"`
+		Replicas       []string `rethinkdb:"replicas"`
+	}, 2)
+	config.Shards[0].PrimaryReplica = "server1"
+	config.Shards[1].PrimaryReplica = "server1"
+	c.Assert(config.primaryReplica(), test.Equals, "server1")
+
+	config.Shards[1].PrimaryReplica = "server2"
+	c.Assert(config.primaryReplica(), test.Equals, "")
+}
+
+func (s *NodeSelectorSuite) TestPointWriteTable(c *test.C) {
+	type result struct {
+		db, table string
+		ok        bool
+	}
+	cases := []struct {
+		term Term
+		opts map[string]interface{}
+		want result
+	}{
+		{DB("db").Table("table").Get("id").Update(map[string]interface{}{"a": 1}), nil, result{"db", "table", true}},
+		{Table("table").Get("id").Replace(map[string]interface{}{"id": "id"}), nil, result{"test", "table", true}},
+		{Table("table").Get("id").Delete(), map[string]interface{}{"db": []interface{}{int(p.Term_DB), []interface{}{"other"}}}, result{"other", "table", true}},
+		{DB("db").Table("table").Insert(map[string]interface{}{"a": 1}), nil, result{"db", "table", true}},
+		{DB("db").Table("table").Insert([]interface{}{map[string]interface{}{"a": 1}}), nil, result{}},
+		{DB("db").Table("table").Filter(map[string]interface{}{"a": 1}).Delete(), nil, result{}},
+		{DB("db").Table("table").Get("id"), nil, result{}},
+	}
+
+	for _, tc := range cases {
+		q := testQuery(tc.term)
+		q.Opts = tc.opts
+		db, table, ok := pointWriteTable(q)
+		c.Assert(result{db, table, ok}, test.Equals, tc.want, test.Commentf("%s", tc.term))
+	}
+}
@@ -188,6 +188,25 @@ func (p *Pool) Query(ctx context.Context, q Query) (*Cursor, error) {
 	return cursor, err
 }
 
+// execAll executes a query on every open connection of the pool.
+func (p *Pool) execAll(ctx context.Context, q Query) error {
+	p.mu.Lock()
+	conns := make([]*Connection, 0, len(p.conns))
+	for _, c := range p.conns {
+		if c != nil && !c.isBad() {
+			conns = append(conns, c)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, c := range conns {
+		if _, _, err := c.Query(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Server returns the server name and server UUID being used by a connection.
 func (p *Pool) Server() (ServerResponse, error) {
 	var response ServerResponse
@@ -2,8 +2,6 @@ package rethinkdb
 
 import (
 	"encoding/base64"
-	"math"
-	"strconv"
 	"time"
 
 	"gopkg.in/rethinkdb/rethinkdb-go.v6/types"
@@ -25,7 +23,14 @@ func convertPseudotype(obj map[string]interface{}, opts map[string]interface{})
 			}
 
 			if timeFormat == "native" {
-				return reqlTimeToNativeTime(obj["epoch_time"].(float64), obj["timezone"].(string))
+				t, err := types.UnmarshalTime(obj)
+				if err != nil {
+					return nil, err
+				}
+				if loc, ok := opts["time_location"].(*time.Location); ok && loc != nil {
+					return t.In(loc), nil
+				}
+				return t.Time, nil
 			} else if timeFormat == "raw" {
 				return obj, nil
 			} else {
@@ -125,33 +130,6 @@ func recursivelyConvertPseudotype(obj interface{}, opts map[string]interface{})
 
 // Pseudo-type helper functions
 
-func reqlTimeToNativeTime(timestamp float64, timezone string) (time.Time, error) {
-	sec, ms := math.Modf(timestamp)
-
-	// Convert to native time rounding to milliseconds
-	t := time.Unix(int64(sec), int64(math.Floor(ms*1000+0.5))*1000*1000)
-
-	// Calculate the timezone
-	if timezone != "" {
-		hours, err := strconv.Atoi(timezone[1:3])
-		if err != nil {
-			return time.Time{}, err
-		}
-		minutes, err := strconv.Atoi(timezone[4:6])
-		if err != nil {
-			return time.Time{}, err
-		}
-		tzOffset := ((hours * 60) + minutes) * 60
-		if timezone[:1] == "-" {
-			tzOffset = 0 - tzOffset
-		}
-
-		t = t.In(time.FixedZone(timezone, tzOffset))
-	}
-
-	return t, nil
-}
-
 func reqlGroupedDataToSlice(obj map[string]interface{}) (interface{}, error) {
 	if data, ok := obj["data"]; ok {
 		ret := []interface{}{}
@@ -199,6 +177,13 @@ func reqlGeometryToNativeGeometry(obj map[string]interface{}) (interface{}, erro
 		return nil, fmt.Errorf("pseudo-type GEOMETRY object %v does not have the expected field \"type\"", obj)
 	} else if typ, ok := typ.(string); !ok {
 		return nil, fmt.Errorf("pseudo-type GEOMETRY object %v field \"type\" is not valid", obj)
+	} else if typ == "MultiPoint" || typ == "MultiLineString" || typ == "MultiPolygon" || typ == "GeometryCollection" {
+		var geometry types.Geometry
+		if err := geometry.UnmarshalRQL(obj); err != nil {
+			return nil, err
+		}
+
+		return geometry, nil
 	} else if coords, ok := obj["coordinates"]; !ok {
 		return nil, fmt.Errorf("pseudo-type GEOMETRY object %v does not have the expected field \"coordinates\"", obj)
 	} else if typ == "Point" {
@@ -0,0 +1,159 @@
+package rethinkdb
+
+import (
+	"encoding/json"
+	"time"
+
+	test "gopkg.in/check.v1"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/types"
+)
+
+type PseudoTypesSuite struct{}
+
+var _ = test.Suite(&PseudoTypesSuite{})
+
+func (s *PseudoTypesSuite) TestGeometry_RoundTrip(c *test.C) {
+	square := types.Line{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 0}, {Lon: 1, Lat: 1}, {Lon: 0, Lat: 0}}
+	geometries := []types.Geometry{
+		{Type: "MultiPoint", MultiPoint: types.MultiPoint{{Lon: 1, Lat: 2}, {Lon: 3, Lat: 4}}},
+		{Type: "MultiLineString", MultiLine: types.MultiLineString{square, square}},
+		{Type: "MultiPolygon", MultiPolygon: types.MultiPolygon{{square}, {square, square}}},
+		{Type: "GeometryCollection", Geometries: types.GeometryCollection{
+			{Type: "Point", Point: types.Point{Lon: 1, Lat: 2}},
+			{Type: "Polygon", Lines: types.Lines{square}},
+		}},
+	}
+
+	for _, geometry := range geometries {
+		// Encode the geometry as JSON, as it would be sent to the server
+		data, err := geometry.MarshalRQL()
+		c.Assert(err, test.IsNil)
+		b, err := json.Marshal(data)
+		c.Assert(err, test.IsNil)
+		var obj interface{}
+		c.Assert(json.Unmarshal(b, &obj), test.IsNil)
+
+		native, err := recursivelyConvertPseudotype(obj, nil)
+		c.Assert(err, test.IsNil)
+		c.Assert(native, test.DeepEquals, geometry)
+
+		// GeoJSON using encoding/json
+		b, err = json.Marshal(geometry)
+		c.Assert(err, test.IsNil)
+		var decoded types.Geometry
+		c.Assert(json.Unmarshal(b, &decoded), test.IsNil)
+		c.Assert(decoded, test.DeepEquals, geometry)
+	}
+}
+
+func (s *PseudoTypesSuite) TestGeometry_GeoJSON(c *test.C) {
+	var geometry types.Geometry
+	err := json.Unmarshal([]byte(`{
+		"type": "Polygon",
+		"coordinates": [
+			[[-10, -10], [10, -10], [10, 10], [-10, -10]],
+			[[-1, -1], [1, -1], [1, 1], [-1, -1]]
+		]
+	}`), &geometry)
+	c.Assert(err, test.IsNil)
+	c.Assert(geometry.Validate(), test.IsNil)
+	c.Assert(geometry.Lines.Exterior(), test.HasLen, 4)
+	c.Assert(geometry.Lines.Holes(), test.HasLen, 1)
+
+	bbox, ok := geometry.BoundingBox()
+	c.Assert(ok, test.Equals, true)
+	c.Assert(bbox, test.Equals, types.BBox{Min: types.Point{Lon: -10, Lat: -10}, Max: types.Point{Lon: 10, Lat: 10}})
+
+	b, err := json.Marshal(struct {
+		Geometry types.Geometry `json:"geometry"`
+		Empty    types.Geometry `json:"empty"`
+	}{Geometry: types.Geometry{Type: "Point", Point: types.Point{Lon: 1, Lat: 2}}})
+	c.Assert(err, test.IsNil)
+	c.Assert(string(b), test.Equals, `{"geometry":{"coordinates":[1,2],"type":"Point"},"empty":null}`)
+}
+
+func (s *PseudoTypesSuite) TestGeometry_Validate(c *test.C) {
+	cases := []struct {
+		geometry types.Geometry
+		err      string
+	}{
+		{types.Geometry{Type: "Point", Point: types.Point{Lon: 181}}, "longitude 181 is out of range.*"},
+		{types.Geometry{Type: "Point", Point: types.Point{Lat: -91}}, "latitude -91 is out of range.*"},
+		{types.Geometry{Type: "LineString", Line: types.Line{{}}}, "LineString has 1 points.*"},
+		{types.Geometry{Type: "Polygon", Lines: types.Lines{{{}, {Lon: 1}, {Lat: 1}, {Lon: 1, Lat: 1}}}}, "Polygon ring is not closed.*"},
+		{types.Geometry{Type: "MultiPoint", MultiPoint: types.MultiPoint{{}, {Lon: 200}}}, "longitude 200 is out of range.*"},
+		{types.Geometry{Type: "Circle"}, ".*'type' Circle is not valid"},
+	}
+
+	for _, tc := range cases {
+		c.Assert(tc.geometry.Validate(), test.ErrorMatches, tc.err)
+	}
+}
+
+func (s *PseudoTypesSuite) TestGeometry_Parts(c *test.C) {
+	point := types.Geometry{Type: "Point", Point: types.Point{Lon: 1, Lat: 2}}
+	line := types.Geometry{Type: "LineString", Line: types.Line{{}, {Lon: 1, Lat: 1}}}
+	geometry := types.Geometry{Type: "GeometryCollection", Geometries: types.GeometryCollection{
+		{Type: "MultiPoint", MultiPoint: types.MultiPoint{point.Point, point.Point}},
+		line,
+	}}
+
+	c.Assert(geometry.Parts(), test.DeepEquals, []types.Geometry{point, point, line})
+}
+
+func (s *PseudoTypesSuite) TestTime_Native(c *test.C) {
+	obj := map[string]interface{}{
+		"$reql_type$": "TIME",
+		"epoch_time":  1375147296.681234,
+		"timezone":    "-07:00",
+	}
+
+	t, err := recursivelyConvertPseudotype(obj, nil)
+	c.Assert(err, test.IsNil)
+	c.Assert(t.(time.Time).Nanosecond(), test.Equals, 681234000)
+	c.Assert(t.(time.Time).Location().String(), test.Equals, "-07:00")
+	c.Assert(t.(time.Time).Format(time.RFC3339), test.Equals, "2013-07-29T18:21:36-07:00")
+
+	t, err = recursivelyConvertPseudotype(obj, map[string]interface{}{"time_location": time.UTC})
+	c.Assert(err, test.IsNil)
+	c.Assert(t.(time.Time).Location(), test.Equals, time.UTC)
+	c.Assert(t.(time.Time).Format(time.RFC3339Nano), test.Equals, "2013-07-30T01:21:36.681234Z")
+}
+
+func (s *PseudoTypesSuite) TestTime_RoundTrip(c *test.C) {
+	obj := map[string]interface{}{
+		"$reql_type$": "TIME",
+		"epoch_time":  1375147296.5,
+		"timezone":    "+00:00",
+	}
+
+	// The native time uses the UTC offset, the time keeps the timezone text
+	native, err := recursivelyConvertPseudotype(obj, nil)
+	c.Assert(err, test.IsNil)
+	var t types.Time
+	c.Assert(encoding.Decode(&t, native), test.IsNil)
+	c.Assert(t.Timezone, test.Equals, "+00:00")
+
+	c.Assert(encoding.Decode(&t, obj), test.IsNil)
+	c.Assert(t.Timezone, test.Equals, "+00:00")
+
+	encoded, err := encoding.Encode(t)
+	c.Assert(err, test.IsNil)
+	c.Assert(encoded, test.DeepEquals, obj)
+
+	c.Assert(encoding.Decode(&t, map[string]interface{}{"epoch_time": 1.0, "timezone": "+1"}), test.ErrorMatches, ".*'timezone' \\+1 is not valid")
+}
+
+func (s *PseudoTypesSuite) TestRunOpts_TimeLocation(c *test.C) {
+	loc := time.FixedZone("test", 3600)
+	q, err := newQuery(Now(), RunOpts{TimeLocation: loc, TimeFormat: "native"}.toMap(), &ConnectOpts{})
+	c.Assert(err, test.IsNil)
+	c.Assert(q.Opts["time_location"], test.Equals, loc)
+	c.Assert(q.Build()[2], test.DeepEquals, map[string]interface{}{"time_format": "native"})
+}
+
+func (s *PseudoTypesSuite) TestDuration_Arithmetic(c *test.C) {
+	c.Assert(Now().Add(90*time.Second).String(), test.Equals, "r.Now().Add(90)")
+	c.Assert(Sub(Now(), time.Hour, 1).String(), test.Equals, "r.Sub(r.Now(), 3600, 1)")
+}
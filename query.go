@@ -3,11 +3,14 @@ package rethinkdb
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"context"
 
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
@@ -34,9 +37,7 @@ func (q *Query) Build() []interface{} {
 		// Clone opts and remove custom rethinkdb options
 		opts := map[string]interface{}{}
 		for k, v := range q.Opts {
-			switch k {
-			case "geometry_format":
-			default:
+			if !isDriverOption(k) {
 				opts[k] = v
 			}
 		}
@@ -66,6 +67,20 @@ type Term struct {
 	optArgs        map[string]Term
 	lastErr        error
 	isMockAnything bool
+	// value is a value passed to Expr which is encoded by a codec. It is only
+	// encoded when the term is built, using the codec of the session, so that
+	// it is encoded once. See expand.
+	value interface{}
+}
+
+// expand returns the term of a value passed to Expr encoded using the default
+// codec, it is used to compare, print or inspect the term. Other terms are
+// returned as is.
+func (t Term) expand() Term {
+	if t.value == nil {
+		return t
+	}
+	return encodeTerm(t.value, nil)
 }
 
 func (t Term) compare(t2 Term, varMap map[int64]int64) bool {
@@ -73,6 +88,8 @@ func (t Term) compare(t2 Term, varMap map[int64]int64) bool {
 		return true
 	}
 
+	t, t2 = t.expand(), t2.expand()
+
 	if t.name != t2.name ||
 		t.rawQuery != t2.rawQuery ||
 		t.rootTerm != t2.rootTerm ||
@@ -127,8 +144,18 @@ func (t Term) compare(t2 Term, varMap map[int64]int64) bool {
 // build takes the query tree and prepares it to be sent as a JSON
 // expression
 func (t Term) Build() (interface{}, error) {
+	return t.build(nil)
+}
+
+// build builds the term, the values passed to Expr are encoded using codec,
+// or the default codec if it is nil.
+func (t Term) build(codec *encoding.Codec) (interface{}, error) {
 	var err error
 
+	if t.value != nil {
+		return encodeTerm(t.value, codec).build(codec)
+	}
+
 	if t.lastErr != nil {
 		return nil, t.lastErr
 	}
@@ -143,7 +170,7 @@ func (t Term) Build() (interface{}, error) {
 	case p.Term_MAKE_OBJ:
 		res := map[string]interface{}{}
 		for k, v := range t.optArgs {
-			res[k], err = v.Build()
+			res[k], err = v.build(codec)
 			if err != nil {
 				return nil, err
 			}
@@ -162,7 +189,7 @@ func (t Term) Build() (interface{}, error) {
 	optArgs := make(map[string]interface{}, len(t.optArgs))
 
 	for i, v := range t.args {
-		arg, err := v.Build()
+		arg, err := v.build(codec)
 		if err != nil {
 			return nil, err
 		}
@@ -170,7 +197,7 @@ func (t Term) Build() (interface{}, error) {
 	}
 
 	for k, v := range t.optArgs {
-		optArgs[k], err = v.Build()
+		optArgs[k], err = v.build(codec)
 		if err != nil {
 			return nil, err
 		}
@@ -193,6 +220,7 @@ func (t Term) String() string {
 	if t.isMockAnything {
 		return "r.MockAnything()"
 	}
+	t = t.expand()
 
 	switch t.termType {
 	case p.Term_MAKE_ARRAY:
@@ -238,6 +266,59 @@ func (t Term) String() string {
 	return fmt.Sprintf("%s.%s(%s)", t.args[0].String(), t.name, strings.Join(allArgsToStringSlice(t.args[1:], t.optArgs), ", "))
 }
 
+// TermType returns the type of the term, for example p.Term_TABLE for the
+// term returned by Table.
+func (t Term) TermType() p.Term_TermType {
+	t = t.expand()
+	return t.termType
+}
+
+// Args returns the arguments of the term, for terms created by a method the
+// first argument is the term the method was called on.
+func (t Term) Args() []Term {
+	t = t.expand()
+	return t.args
+}
+
+// OptArg returns the optional argument of the term with the given name.
+func (t Term) OptArg(name string) (Term, bool) {
+	t = t.expand()
+	arg, ok := t.optArgs[name]
+	return arg, ok
+}
+
+// Datum returns the value of a DATUM term, such as the name passed to Table,
+// or nil for other terms.
+func (t Term) Datum() interface{} {
+	t = t.expand()
+	if t.termType != p.Term_DATUM {
+		return nil
+	}
+	return t.data
+}
+
+// Walk calls fn for the term and then, depth first, for each of its arguments
+// and optional arguments. The arguments of a term are skipped if fn returns
+// false.
+func (t Term) Walk(fn func(Term) bool) {
+	t = t.expand()
+	if !fn(t) {
+		return
+	}
+	for _, arg := range t.args {
+		arg.Walk(fn)
+	}
+
+	names := make([]string, 0, len(t.optArgs))
+	for name := range t.optArgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t.optArgs[name].Walk(fn)
+	}
+}
+
 // OptArgs is an interface used to represent a terms optional arguments. All
 // optional argument types have a toMap function, the returned map can be encoded
 // and sent as part of the query.
@@ -320,11 +401,46 @@ type RunOpts struct {
 	MaxBatchSeconds           interface{} `rethinkdb:"max_batch_seconds,omitempty"`
 	FirstBatchScaledownFactor interface{} `rethinkdb:"first_batch_scaledown_factor,omitempty"`
 
+	// TimeLocation is the location native times are converted to when
+	// decoding the results, by default times use a fixed zone named after the
+	// timezone of the ReQL time.
+	TimeLocation *time.Location `rethinkdb:"-"`
+	// DecodeOpts are the options used when decoding the results into Go
+	// values, for example to return an error if a document has fields which
+	// are not in the struct it is decoded into. They can also be set using
+	// Cursor.SetDecodeOpts.
+	DecodeOpts encoding.DecodeOpts `rethinkdb:"-"`
+	// Prefetch is the number of batches of results the cursor reads ahead of
+	// the rows returned by Next, so the next batch is requested from the
+	// server while the current one is being decoded. No more batches are
+	// requested while Prefetch batches are buffered. If zero the next batch is
+	// only requested once the buffered rows have been read. It can also be set
+	// using Cursor.SetPrefetch.
+	Prefetch int `rethinkdb:"-"`
+	// DecodeWorkers is the number of goroutines used to decode each batch of
+	// results, the documents are still returned in order. If zero or one the
+	// documents are decoded by the goroutine reading the cursor. It can also
+	// be set using Cursor.SetDecodeWorkers.
+	DecodeWorkers int `rethinkdb:"-"`
+
 	Context context.Context `rethinkdb:"-"`
 }
 
 func (o RunOpts) toMap() map[string]interface{} {
-	return optArgsToMap(o)
+	opts := optArgsToMap(o)
+	if o.TimeLocation != nil {
+		opts["time_location"] = o.TimeLocation
+	}
+	if o.DecodeOpts != (encoding.DecodeOpts{}) {
+		opts["decode_opts"] = o.DecodeOpts
+	}
+	if o.Prefetch > 0 {
+		opts["prefetch"] = o.Prefetch
+	}
+	if o.DecodeWorkers > 0 {
+		opts["decode_workers"] = o.DecodeWorkers
+	}
+	return opts
 }
 
 // Run runs a query using the given connection.
@@ -6,6 +6,7 @@ import (
 
 	"reflect"
 
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
@@ -103,33 +104,10 @@ func Expr(val interface{}) Term {
 		switch valType.Kind() {
 		case reflect.Func:
 			return makeFunc(val)
-		case reflect.Struct, reflect.Map, reflect.Ptr:
-			data, err := encode(val)
-
-			if err != nil || data == nil {
-				return Term{
-					termType: p.Term_DATUM,
-					data:     nil,
-					lastErr:  err,
-				}
-			}
-
-			return Expr(data)
-
 		case reflect.Slice, reflect.Array:
-			// Check if slice is a byte slice
+			// Byte slices are sent as binary data, see encodeTerm
 			if valType.Elem().Kind() == reflect.Uint8 {
-				data, err := encode(val)
-
-				if err != nil || data == nil {
-					return Term{
-						termType: p.Term_DATUM,
-						data:     nil,
-						lastErr:  err,
-					}
-				}
-
-				return Expr(data)
+				return Term{termType: p.Term_DATUM, value: val}
 			}
 
 			vals := make([]Term, valValue.Len())
@@ -139,24 +117,43 @@ func Expr(val interface{}) Term {
 
 			return makeArray(vals)
 		default:
-			data, err := encode(val)
-
-			if err != nil || data == nil {
-				return Term{
-					termType: p.Term_DATUM,
-					data:     nil,
-					lastErr:  err,
-				}
-			}
+			// The value is encoded when the term is built
+			return Term{termType: p.Term_DATUM, value: val}
+		}
+	}
+}
 
-			return Term{
-				termType: p.Term_DATUM,
-				data:     data,
-			}
+// encodeTerm returns the term of a value encoded using codec, or the default
+// codec if it is nil. Byte slices and arrays are sent as binary data unless
+// they have their own encoding, nil byte slices are sent as null.
+func encodeTerm(val interface{}, codec *encoding.Codec) Term {
+	if codec == nil {
+		codec = encoding.DefaultCodec()
+	}
+
+	typ := reflect.TypeOf(val)
+	if (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) && typ.Elem().Kind() == reflect.Uint8 &&
+		!typ.Implements(marshalerType) && !codec.HasTypeEncoding(typ) {
+		if typ.Kind() == reflect.Slice && reflect.ValueOf(val).IsNil() {
+			return Expr(nil)
 		}
+		return Binary(val)
 	}
+
+	data, err := codec.Encode(val)
+	if err != nil || data == nil {
+		return Term{
+			termType: p.Term_DATUM,
+			data:     nil,
+			lastErr:  err,
+		}
+	}
+
+	return Expr(data)
 }
 
+var marshalerType = reflect.TypeOf((*encoding.Marshaler)(nil)).Elem()
+
 // JSOpts contains the optional arguments for the JS term
 type JSOpts struct {
 	Timeout interface{} `rethinkdb:"timeout,omitempty"`
@@ -264,16 +261,30 @@ func Binary(data interface{}) Term {
 		panic("Unsupported binary type")
 	}
 
-	return binaryTerm(base64.StdEncoding.EncodeToString(b))
+	return binaryTerm(b)
 }
 
-func binaryTerm(data string) Term {
+func binaryTerm(data []byte) Term {
 	t := constructRootTerm("Binary", p.Term_BINARY, []interface{}{}, map[string]interface{}{})
-	t.data = data
+	t.data = binaryData(data)
 
 	return t
 }
 
+// binaryData is the data of a BINARY pseudo-type. The data is only base64
+// encoded when the query is serialized, avoiding an encoded copy of the data
+// being kept with the term.
+type binaryData []byte
+
+func (b binaryData) MarshalJSON() ([]byte, error) {
+	dst := make([]byte, base64.StdEncoding.EncodedLen(len(b))+2)
+	dst[0] = '"'
+	base64.StdEncoding.Encode(dst[1:], b)
+	dst[len(dst)-1] = '"'
+
+	return dst, nil
+}
+
 // Do evaluates the expr in the context of one or more value bindings. The type of
 // the result is the type of the value returned from expr.
 func (t Term) Do(args ...interface{}) Term {
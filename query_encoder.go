@@ -0,0 +1,235 @@
+package rethinkdb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxPooledQueryBufferSize is the capacity of the largest buffer returned to
+// the pool, the buffers of exceptionally large queries are left to the garbage
+// collector instead of being kept for the lifetime of the process.
+const maxPooledQueryBufferSize = 8 * 1024 * 1024
+
+var queryBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// encodeQuery writes the wire frame of the query into a buffer from the pool:
+// the token, the length of the query and the JSON encoding of the query
+// followed by a newline. The query is written directly into the buffer and the
+// length is filled in once the query has been written. The buffer must be
+// released using releaseQueryBuffer once it has been sent.
+func encodeQuery(q Query) (*bytes.Buffer, error) {
+	buf := queryBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	// Reserve space for the header
+	var header [respHeaderLen]byte
+	buf.Write(header[:])
+
+	if err := writeJSON(buf, q.Build()); err != nil {
+		releaseQueryBuffer(buf)
+		return nil, err
+	}
+	buf.WriteByte('\n')
+
+	b := buf.Bytes()
+	binary.LittleEndian.PutUint64(b, uint64(q.Token))
+	binary.LittleEndian.PutUint32(b[8:], uint32(len(b)-respHeaderLen))
+
+	return buf, nil
+}
+
+func releaseQueryBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledQueryBufferSize {
+		queryBufferPool.Put(buf)
+	}
+}
+
+// writeJSON writes the JSON encoding of a built term to the buffer. The output
+// is the same as encoding/json, the types produced by Term.Build are written
+// without intermediate allocations and any other values are encoded using
+// encoding/json.
+func writeJSON(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.Write(strconv.AppendBool(buf.AvailableBuffer(), v))
+	case string:
+		writeJSONString(buf, v)
+	case int:
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(v), 10))
+	case int8:
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(v), 10))
+	case int16:
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(v), 10))
+	case int32:
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(v), 10))
+	case int64:
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), v, 10))
+	case uint:
+		buf.Write(strconv.AppendUint(buf.AvailableBuffer(), uint64(v), 10))
+	case uint8:
+		buf.Write(strconv.AppendUint(buf.AvailableBuffer(), uint64(v), 10))
+	case uint16:
+		buf.Write(strconv.AppendUint(buf.AvailableBuffer(), uint64(v), 10))
+	case uint32:
+		buf.Write(strconv.AppendUint(buf.AvailableBuffer(), uint64(v), 10))
+	case uint64:
+		buf.Write(strconv.AppendUint(buf.AvailableBuffer(), v, 10))
+	case float32:
+		return writeJSONFloat(buf, float64(v), 32)
+	case float64:
+		return writeJSONFloat(buf, v, 64)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		// Sort the keys to match encoding/json, most objects are small enough
+		// for the keys to fit on the stack
+		var stack [16]string
+		keys := stack[:0]
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, k)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, v[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case binaryData:
+		// Encode the data directly into the buffer
+		n := base64.StdEncoding.EncodedLen(len(v))
+		buf.Grow(n + 2)
+		buf.WriteByte('"')
+		dst := buf.AvailableBuffer()[:n]
+		base64.StdEncoding.Encode(dst, v)
+		buf.Write(dst)
+		buf.WriteByte('"')
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+
+	return nil
+}
+
+// writeJSONFloat writes a float using the same format as encoding/json.
+func writeJSONFloat(buf *bytes.Buffer, f float64, bits int) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return fmt.Errorf("json: unsupported value: %s", strconv.FormatFloat(f, 'g', -1, bits))
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+
+	b := strconv.AppendFloat(buf.AvailableBuffer(), f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	buf.Write(b)
+
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// writeJSONString writes a quoted string escaped in the same way as
+// encoding/json, including the escaping of HTML characters.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+
+			buf.WriteString(s[start:i])
+			switch b {
+			case '\\', '"':
+				buf.WriteByte('\\')
+				buf.WriteByte(b)
+			case '\b':
+				buf.WriteString(`\b`)
+			case '\f':
+				buf.WriteString(`\f`)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[b>>4])
+				buf.WriteByte(hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteRune(utf8.RuneError)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are escaped as they are not valid in JavaScript
+		if c == '\u2028' || c == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hexDigits[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
@@ -0,0 +1,95 @@
+package rethinkdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	test "gopkg.in/check.v1"
+)
+
+type QueryEncoderSuite struct{}
+
+var _ = test.Suite(&QueryEncoderSuite{})
+
+func (s *QueryEncoderSuite) TestEncodeQuery_MatchesJSON(c *test.C) {
+	i := 42
+	str := "pointer"
+	terms := []Term{
+		Expr(nil),
+		Expr([]interface{}{true, false, 1, int8(-2), int16(3), int32(-4), int64(5), uint(6), uint8(7), uint16(8), uint32(9), uint64(10)}),
+		Expr([]interface{}{1.5, float32(0.1), 1e-7, 1e21, -0.000001, 123456789.125, 0.0}),
+		Expr([]interface{}{"", "quote \" backslash \\ html <a href=\"x\">&</a>", "\b\f\n\r\t\x00\x1f", "unicode é 日本   ", "invalid \xff utf-8"}),
+		Expr(map[string]interface{}{"b": 1, "a": map[string]interface{}{"z": nil, "y": []interface{}{}}, "c": &i, "d": &str}),
+		Expr([]byte("binary data")),
+		DB("db").Table("table").Insert(map[string]interface{}{"id": 1, "data": Binary([]byte{0, 1, 2, 253, 254, 255})}),
+		Table("table").Filter(func(row Term) Term { return row.Field("age").Gt(18) }).Limit(10),
+		RawQuery([]byte(`{"a" : [1, 2,   3]}`)),
+	}
+
+	for _, t := range terms {
+		q, err := newQuery(t, map[string]interface{}{"db": "test", "read_mode": "outdated"}, &ConnectOpts{})
+		c.Assert(err, test.IsNil)
+		q.Token = 123
+
+		buf, err := encodeQuery(q)
+		c.Assert(err, test.IsNil)
+		c.Assert(buf.String(), test.Equals, string(serializeQuery(q.Token, q)), test.Commentf("%s", t))
+		releaseQueryBuffer(buf)
+	}
+}
+
+func (s *QueryEncoderSuite) TestEncodeQuery_UnsupportedValue(c *test.C) {
+	q, err := newQuery(Expr(math.NaN()), nil, &ConnectOpts{})
+	c.Assert(err, test.IsNil)
+
+	_, err = encodeQuery(q)
+	c.Assert(err, test.ErrorMatches, "json: unsupported value: NaN")
+}
+
+func benchmarkInsertQuery() Query {
+	docs := make([]interface{}, 1000)
+	for i := range docs {
+		docs[i] = map[string]interface{}{
+			"id":    i,
+			"name":  strings.Repeat("name", 10),
+			"score": float64(i) / 3,
+			"data":  Binary(bytes.Repeat([]byte{byte(i)}, 1024)),
+		}
+	}
+
+	q, _ := newQuery(Table("table").Insert(docs), map[string]interface{}{}, &ConnectOpts{})
+	return q
+}
+
+func BenchmarkEncodeQuery(b *testing.B) {
+	q := benchmarkInsertQuery()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf, err := encodeQuery(q)
+		if err != nil {
+			b.Fatal(err)
+		}
+		releaseQueryBuffer(buf)
+	}
+}
+
+// BenchmarkEncodeQuery_JSONEncoder measures encoding a query using
+// encoding/json into a new buffer, for comparison with BenchmarkEncodeQuery.
+func BenchmarkEncodeQuery_JSONEncoder(b *testing.B) {
+	q := benchmarkInsertQuery()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf := &bytes.Buffer{}
+		buf.Write(make([]byte, respHeaderLen))
+		if err := json.NewEncoder(buf).Encode(q.Build()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
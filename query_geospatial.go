@@ -4,6 +4,39 @@ import (
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
+// Unit is a unit of distance used by the geospatial terms, it can be used as
+// the Unit field of CircleOpts, DistanceOpts and GetNearestOpts.
+type Unit string
+
+const (
+	UnitMeter        Unit = "m"
+	UnitKilometer    Unit = "km"
+	UnitMile         Unit = "mi"
+	UnitNauticalMile Unit = "nm"
+	UnitFoot         Unit = "ft"
+)
+
+// GeoSystem is the reference ellipsoid used by the geospatial terms, it can be
+// used as the GeoSystem field of CircleOpts, DistanceOpts and GetNearestOpts.
+type GeoSystem string
+
+const (
+	// GeoSystemWGS84 is the WGS 84 ellipsoid, used by default.
+	GeoSystemWGS84 GeoSystem = "WGS84"
+	// GeoSystemUnitSphere is a perfect sphere with a radius of 1 meter.
+	GeoSystemUnitSphere GeoSystem = "unit_sphere"
+)
+
+// NearestResult is a single result of the GetNearest term, Dist is the
+// distance between the point and the document in the unit of the query.
+//
+//	var res []r.NearestResult[Place]
+//	err := cursor.All(&res)
+type NearestResult[T any] struct {
+	Dist float64 `rethinkdb:"dist"`
+	Doc  T       `rethinkdb:"doc"`
+}
+
 // CircleOpts contains the optional arguments for the Circle term.
 type CircleOpts struct {
 	NumVertices interface{} `rethinkdb:"num_vertices,omitempty"`
@@ -111,7 +144,8 @@ func (o GetNearestOpts) toMap() map[string]interface{} {
 }
 
 // GetNearest gets all documents where the specified geospatial index is within a
-// certain distance of the specified point (default 100 kilometers).
+// certain distance of the specified point (default 100 kilometers). The
+// results can be decoded into a slice of NearestResult.
 func (t Term) GetNearest(point interface{}, optArgs ...GetNearestOpts) Term {
 	opts := map[string]interface{}{}
 	if len(optArgs) >= 1 {
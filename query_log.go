@@ -0,0 +1,282 @@
+package rethinkdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+)
+
+// QueryLogOpts configures the logs of the queries run by a session, which are
+// written to ConnectOpts.Log once each query is done, when its cursor has
+// received the last batch of results or is closed.
+//
+// Each log contains the term of the query and the attributes
+//
+//	duration     time until the query was done
+//	first_batch  time until the first batch of results was received
+//	rows, bytes  number of rows and bytes of results received
+//	node, token  address of the server and token of the query
+//	error        error of the query, if any
+//	error_type   type of the error, such as rethinkdb.RQLRuntimeError
+//	noreply      true for queries run with ExecOpts.NoReply
+type QueryLogOpts struct {
+	// Level is the level of the logs of queries, slog.LevelDebug by default.
+	// Slow queries and queries which fail are logged at slog.LevelWarn.
+	Level slog.Leveler
+	// SlowThreshold is the duration from which queries are logged as slow,
+	// if zero no queries are slow.
+	SlowThreshold time.Duration
+	// SlowOnly only logs slow queries and queries which fail.
+	SlowOnly bool
+	// SampleRate is the fraction of queries logged, between 0 and 1, slow
+	// queries and queries which fail are always logged. If zero every query
+	// is logged.
+	SampleRate float64
+	// MaxTermLength is the number of characters of the term logged, longer
+	// terms are truncated. 500 by default, or no limit if negative.
+	MaxTermLength int
+	// Redact replaces the values in the logged terms by ?, except for the
+	// names of databases, tables, indexes and fields and settings such as
+	// the index or durability of a query, so that logs do not contain the
+	// documents written, the keys read or credentials such as HTTPOpts.Auth.
+	// The errors of failed queries are logged without the term of the query.
+	Redact bool
+}
+
+type queryLogger struct {
+	log  *slog.Logger
+	opts QueryLogOpts
+	// sampled is the number of queries considered for sampling.
+	sampled atomic.Int64
+}
+
+func newQueryLogger(log *slog.Logger, opts QueryLogOpts) *queryLogger {
+	if log == nil {
+		log = slog.Default()
+	}
+	if opts.Level == nil {
+		opts.Level = slog.LevelDebug
+	}
+	if opts.MaxTermLength == 0 {
+		opts.MaxTermLength = 500
+	}
+	return &queryLogger{log: log, opts: opts}
+}
+
+// queryLogEntry is the information logged about a query.
+type queryLogEntry struct {
+	q          Query
+	start      time.Time
+	firstBatch time.Duration
+	node       string
+	token      int64
+	rows       int
+	bytes      int
+	err        error
+}
+
+// track logs a query once its cursor is done, or immediately if it failed.
+func (l *queryLogger) track(ctx context.Context, q Query, start time.Time, cursor *Cursor, err error) {
+	e := queryLogEntry{q: q, start: start, firstBatch: time.Since(start), err: err}
+	if cursor == nil {
+		l.write(ctx, e)
+		return
+	}
+
+	cursor.addOnDone(func() {
+		// The cursor is locked
+		if cursor.conn != nil {
+			e.node = cursor.conn.address
+		}
+		e.token = cursor.token
+		e.rows = cursor.rows
+		e.bytes = cursor.bytes
+		e.err = cursor.lastErr
+		l.write(ctx, e)
+	})
+}
+
+func (l *queryLogger) write(ctx context.Context, e queryLogEntry) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	duration := time.Since(e.start)
+	slow := l.opts.SlowThreshold > 0 && duration >= l.opts.SlowThreshold
+
+	msg := "query"
+	level := l.opts.Level.Level()
+	switch {
+	case e.err != nil:
+		msg = "query failed"
+		level = slog.LevelWarn
+	case slow:
+		msg = "slow query"
+		level = slog.LevelWarn
+	case l.opts.SlowOnly || !l.sample():
+		return
+	}
+	if !l.log.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("term", l.termString(e.q)),
+		slog.Duration("duration", duration),
+		slog.Duration("first_batch", e.firstBatch),
+		slog.Int("rows", e.rows),
+		slog.Int("bytes", e.bytes),
+	}
+	if e.node != "" {
+		attrs = append(attrs, slog.String("node", e.node), slog.Int64("token", e.token))
+	}
+	if e.err != nil {
+		attrs = append(attrs, slog.String("error", l.errorString(e.err)), slog.String("error_type", fmt.Sprintf("%T", e.err)))
+	}
+	if noreply, _ := e.q.Opts["noreply"].(bool); noreply {
+		attrs = append(attrs, slog.Bool("noreply", true))
+	}
+	l.log.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// sample returns true if the query should be logged. Sampling is
+// deterministic so exactly the given fraction of queries is logged.
+func (l *queryLogger) sample() bool {
+	rate := l.opts.SampleRate
+	if rate <= 0 || rate >= 1 {
+		return true
+	}
+	n := l.sampled.Add(1)
+	return int64(float64(n)*rate) != int64(float64(n-1)*rate)
+}
+
+func (l *queryLogger) termString(q Query) string {
+	if q.Term == nil {
+		return q.Type.String()
+	}
+
+	t := *q.Term
+	if l.opts.Redact {
+		t = redactTerm(t)
+	}
+	s := t.String()
+	if max := l.opts.MaxTermLength; max > 0 && utf8.RuneCountInString(s) > max {
+		s = string([]rune(s)[:max]) + "..."
+	}
+	return s
+}
+
+// errorString returns the message of an error, without the term of the query
+// included in the errors of the server if terms are redacted.
+func (l *queryLogger) errorString(err error) string {
+	var serverErr interface{ message() string }
+	if l.opts.Redact && errors.As(err, &serverErr) {
+		return serverErr.message()
+	}
+	return err.Error()
+}
+
+// redacted is the value of the datum terms of redacted terms.
+type redacted struct{}
+
+func (redacted) String() string {
+	return "?"
+}
+
+// redactTerm returns a copy of a term with its values replaced by ?, except
+// for the names of databases, tables, indexes and fields and the values of
+// the options listed in optionNames.
+func redactTerm(t Term) Term {
+	t = t.expand()
+	switch t.termType {
+	case p.Term_DATUM:
+		if t.data != nil {
+			t.data = redacted{}
+		}
+		return t
+	case p.Term_VAR:
+		return t
+	}
+
+	args := make([]Term, len(t.args))
+	for i, arg := range t.args {
+		if isNameArg(t, i) {
+			args[i] = arg
+			continue
+		}
+		args[i] = redactTerm(arg)
+	}
+	t.args = args
+
+	if t.optArgs != nil {
+		optArgs := make(map[string]Term, len(t.optArgs))
+		for k, v := range t.optArgs {
+			// The optional arguments of an object are its fields
+			if t.termType != p.Term_MAKE_OBJ && v.value == nil && v.termType == p.Term_DATUM && optionNames[k] {
+				optArgs[k] = v
+				continue
+			}
+			optArgs[k] = redactTerm(v)
+		}
+		t.optArgs = optArgs
+	}
+	return t
+}
+
+// optionNames are the optional arguments whose values are names or settings
+// of the query rather than data, they are logged when terms are redacted.
+var optionNames = map[string]bool{
+	"index":             true,
+	"left_bound":        true,
+	"right_bound":       true,
+	"durability":        true,
+	"return_changes":    true,
+	"conflict":          true,
+	"read_mode":         true,
+	"identifier_format": true,
+	"primary_key":       true,
+	"multi":             true,
+	"geo":               true,
+	"squash":            true,
+	"include_initial":   true,
+	"include_states":    true,
+	"include_offsets":   true,
+	"include_types":     true,
+	"shards":            true,
+	"replicas":          true,
+	"dry_run":           true,
+	"non_atomic":        true,
+	"result_format":     true,
+	"method":            true,
+	"timeout":           true,
+}
+
+// isNameArg returns true if the i-th argument of a term is the name of a
+// database, table, index or field, or the variables of a function.
+func isNameArg(t Term, i int) bool {
+	if t.termType == p.Term_FUNC {
+		// The variables of the function
+		return i == 0
+	}
+	if t.args[i].expand().termType != p.Term_DATUM {
+		return false
+	}
+	switch t.termType {
+	case p.Term_DB, p.Term_DB_CREATE, p.Term_DB_DROP:
+		return i == 0
+	case p.Term_TABLE, p.Term_TABLE_CREATE, p.Term_TABLE_DROP,
+		p.Term_INDEX_CREATE, p.Term_INDEX_DROP, p.Term_INDEX_RENAME,
+		p.Term_INDEX_STATUS, p.Term_INDEX_WAIT,
+		p.Term_GET_FIELD, p.Term_BRACKET, p.Term_PLUCK, p.Term_WITHOUT,
+		p.Term_HAS_FIELDS:
+		// The first argument is the term the method was called on
+		return i > 0 || t.rootTerm
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package rethinkdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+
+	test "gopkg.in/check.v1"
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+)
+
+type QueryLogSuite struct{}
+
+var _ = test.Suite(&QueryLogSuite{})
+
+// queryLogs returns a session logging its queries and the logs written.
+func queryLogs(c *test.C, srv *pipeServer, opts QueryLogOpts) (*Session, func() []map[string]interface{}) {
+	buf := &bytes.Buffer{}
+	session := srv.connect(c, ConnectOpts{
+		Log:      slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		QueryLog: &opts,
+	})
+
+	return session, func() []map[string]interface{} {
+		var logs []map[string]interface{}
+		for _, line := range strings.Split(buf.String(), "\n") {
+			var log map[string]interface{}
+			if line == "" || json.Unmarshal([]byte(line), &log) != nil || log["term"] == nil {
+				continue
+			}
+			logs = append(logs, log)
+		}
+		buf.Reset()
+		return logs
+	}
+}
+
+func (s *QueryLogSuite) TestQueryLog(c *test.C) {
+	srv := &pipeServer{}
+	session, logs := queryLogs(c, srv, QueryLogOpts{})
+	defer session.Close()
+
+	cursor, err := Table("items").Run(session)
+	c.Assert(err, test.IsNil)
+	c.Assert(logs(), test.HasLen, 0)
+
+	var rows []int
+	c.Assert(cursor.All(&rows), test.IsNil)
+	log := logs()
+	c.Assert(log, test.HasLen, 1)
+	c.Assert(log[0]["level"], test.Equals, "DEBUG")
+	c.Assert(log[0]["msg"], test.Equals, "query")
+	c.Assert(log[0]["term"], test.Equals, `r.Table("items")`)
+	c.Assert(log[0]["rows"], test.Equals, 2.0)
+	c.Assert(log[0]["bytes"], test.Equals, 2.0)
+	c.Assert(log[0]["node"], test.Equals, "host1:28015")
+	c.Assert(log[0]["token"], test.NotNil)
+	c.Assert(log[0]["first_batch"].(float64) <= log[0]["duration"].(float64), test.Equals, true)
+
+	c.Assert(Expr(1).Exec(session, ExecOpts{NoReply: true}), test.IsNil)
+	log = logs()
+	c.Assert(log, test.HasLen, 1)
+	c.Assert(log[0]["term"], test.Equals, `1`)
+	c.Assert(log[0]["noreply"], test.Equals, true)
+}
+
+func (s *QueryLogSuite) TestQueryLog_Failed(c *test.C) {
+	srv := &pipeServer{}
+	session, logs := queryLogs(c, srv, QueryLogOpts{SlowOnly: true})
+	defer session.Close()
+
+	c.Assert(Expr(1).Exec(session), test.IsNil)
+	c.Assert(logs(), test.HasLen, 0)
+
+	_, err := Error("boom").Run(session)
+	c.Assert(err, test.ErrorMatches, "rethinkdb: boom in:\n.*")
+	log := logs()
+	c.Assert(log, test.HasLen, 1)
+	c.Assert(log[0]["level"], test.Equals, "WARN")
+	c.Assert(log[0]["msg"], test.Equals, "query failed")
+	c.Assert(log[0]["term"], test.Equals, `r.Error("boom")`)
+	c.Assert(log[0]["error_type"], test.Equals, "rethinkdb.RQLRuntimeError")
+}
+
+func (s *QueryLogSuite) TestQueryLog_Slow(c *test.C) {
+	srv := &pipeServer{}
+	session, logs := queryLogs(c, srv, QueryLogOpts{SlowOnly: true, SlowThreshold: 10 * time.Millisecond})
+	defer session.Close()
+
+	c.Assert(Expr(1).Exec(session), test.IsNil)
+	cursor, err := Table("items").Run(session)
+	c.Assert(err, test.IsNil)
+	c.Assert(logs(), test.HasLen, 0)
+
+	time.Sleep(20 * time.Millisecond)
+	c.Assert(cursor.Close(), test.IsNil)
+	log := logs()
+	c.Assert(log, test.HasLen, 1)
+	c.Assert(log[0]["level"], test.Equals, "WARN")
+	c.Assert(log[0]["msg"], test.Equals, "slow query")
+	c.Assert(log[0]["rows"], test.Equals, 1.0)
+}
+
+func (s *QueryLogSuite) TestQueryLog_Sampling(c *test.C) {
+	srv := &pipeServer{}
+	session, logs := queryLogs(c, srv, QueryLogOpts{SampleRate: 0.25})
+	defer session.Close()
+
+	for i := 0; i < 20; i++ {
+		c.Assert(Expr(i).Exec(session), test.IsNil)
+	}
+	c.Assert(logs(), test.HasLen, 5)
+}
+
+func (s *QueryLogSuite) TestQueryLog_Redact(c *test.C) {
+	l := newQueryLogger(nil, QueryLogOpts{Redact: true})
+	term := func(t Term) string {
+		return l.termString(Query{Type: p.Query_START, Term: &t})
+	}
+
+	c.Assert(term(DB("test").Table("users").Get("alice@example.com")), test.Equals,
+		`r.DB("test").Table("users").Get(?)`)
+	c.Assert(term(Table("users").GetAllByIndex("email", "alice@example.com").Pluck("name")), test.Equals,
+		`r.Table("users").GetAll(?, index="email").Pluck("name")`)
+	c.Assert(term(Table("users").Filter(func(user Term) Term {
+		return user.Field("age").Gt(30)
+	})), test.Matches, `r\.Table\("users"\)\.Filter\(func\(var_(\d+) r\.Term\) r\.Term \{ return var_\d+\.Field\("age"\)\.Gt\(\?\) \}\)`)
+	c.Assert(term(Table("users").Insert(map[string]interface{}{"email": "alice@example.com"})), test.Equals,
+		`r.Table("users").Insert({email=?})`)
+	http := term(HTTP("https://example.com/users", HTTPOpts{
+		Method: "POST",
+		Auth:   map[string]interface{}{"user": "alice", "pass": "secret"},
+		Header: map[string]interface{}{"Authorization": "Bearer secret"},
+	}))
+	c.Assert(http, test.Matches, `r\.Http\(\?, .*\)`)
+	for _, opt := range []string{`header={Authorization=?}`, `method="POST"`, `user=?`, `pass=?`} {
+		c.Assert(strings.Contains(http, opt), test.Equals, true, test.Commentf("%s", http))
+	}
+	c.Assert(http, test.Not(test.Matches), `.*(alice|secret).*`)
+	c.Assert(term(Table("users").Filter(map[string]interface{}{"active": true}, FilterOpts{Default: "secret"})), test.Equals,
+		`r.Table("users").Filter({active=?}, default=?)`)
+	c.Assert(term(Table("users").Insert(map[string]interface{}{"id": 1}, InsertOpts{
+		Conflict: func(id, oldDoc, newDoc Term) interface{} {
+			return newDoc.Merge(map[string]interface{}{"token": "secret"})
+		},
+	})), test.Not(test.Matches), `.*secret.*`)
+	c.Assert(term(Table("users").Insert(map[string]interface{}{"id": 1}, InsertOpts{Conflict: "replace"})), test.Equals,
+		`r.Table("users").Insert({id=?}, conflict="replace")`)
+	c.Assert(strings.Contains(term(Expr([]byte("secret"))), "secret"), test.Equals, false)
+	c.Assert(l.termString(Query{Type: p.Query_NOREPLY_WAIT}), test.Equals, "NOREPLY_WAIT")
+}
+
+func (s *QueryLogSuite) TestQueryLog_RedactError(c *test.C) {
+	srv := &pipeServer{}
+	session, logs := queryLogs(c, srv, QueryLogOpts{Redact: true})
+	defer session.Close()
+
+	_, err := Table("users").Insert(map[string]interface{}{"ssn": "123-45-6789"}).Run(session)
+	c.Assert(err, test.ErrorMatches, `(?s)rethinkdb: boom in:\n.*123-45-6789.*`)
+	log := logs()
+	c.Assert(log, test.HasLen, 1)
+	c.Assert(log[0]["term"], test.Equals, `r.Table("users").Insert({ssn=?})`)
+	c.Assert(log[0]["error"], test.Equals, "rethinkdb: boom")
+	c.Assert(log[0]["error_type"], test.Equals, "rethinkdb.RQLRuntimeError")
+}
+
+func (s *QueryLogSuite) TestQueryLog_MaxTermLength(c *test.C) {
+	long := Expr(strings.Repeat("a", 600))
+
+	l := newQueryLogger(nil, QueryLogOpts{})
+	c.Assert(l.termString(Query{Term: &long}), test.HasLen, 503)
+
+	l = newQueryLogger(nil, QueryLogOpts{MaxTermLength: 10})
+	c.Assert(l.termString(Query{Term: &long}), test.Equals, `"aaaaaaaaa...`)
+
+	l = newQueryLogger(nil, QueryLogOpts{MaxTermLength: -1})
+	c.Assert(l.termString(Query{Term: &long}), test.HasLen, 602)
+}
@@ -1,6 +1,8 @@
 package rethinkdb
 
 import (
+	"time"
+
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
@@ -11,24 +13,50 @@ var (
 	MaxVal = constructRootTerm("MaxVal", p.Term_MAXVAL, []interface{}{}, map[string]interface{}{})
 )
 
-// Add sums two numbers or concatenates two arrays.
+// Add sums two numbers or concatenates two arrays. A time.Duration argument
+// is converted to seconds, allowing it to be added to a time.
+//
+//	r.Now().Add(time.Hour)
 func (t Term) Add(args ...interface{}) Term {
-	return constructMethodTerm(t, "Add", p.Term_ADD, args, map[string]interface{}{})
+	return constructMethodTerm(t, "Add", p.Term_ADD, durationsToSeconds(args), map[string]interface{}{})
 }
 
-// Add sums two numbers or concatenates two arrays.
+// Add sums two numbers or concatenates two arrays. A time.Duration argument
+// is converted to seconds, allowing it to be added to a time.
 func Add(args ...interface{}) Term {
-	return constructRootTerm("Add", p.Term_ADD, args, map[string]interface{}{})
+	return constructRootTerm("Add", p.Term_ADD, durationsToSeconds(args), map[string]interface{}{})
 }
 
-// Sub subtracts two numbers.
+// Sub subtracts two numbers. A time.Duration argument is converted to
+// seconds, allowing it to be subtracted from a time.
 func (t Term) Sub(args ...interface{}) Term {
-	return constructMethodTerm(t, "Sub", p.Term_SUB, args, map[string]interface{}{})
+	return constructMethodTerm(t, "Sub", p.Term_SUB, durationsToSeconds(args), map[string]interface{}{})
 }
 
-// Sub subtracts two numbers.
+// Sub subtracts two numbers. A time.Duration argument is converted to
+// seconds, allowing it to be subtracted from a time.
 func Sub(args ...interface{}) Term {
-	return constructRootTerm("Sub", p.Term_SUB, args, map[string]interface{}{})
+	return constructRootTerm("Sub", p.Term_SUB, durationsToSeconds(args), map[string]interface{}{})
+}
+
+// durationsToSeconds converts any time.Duration arguments to seconds, the unit
+// used by ReQL time arithmetic.
+func durationsToSeconds(args []interface{}) []interface{} {
+	var converted []interface{}
+	for i, arg := range args {
+		d, ok := arg.(time.Duration)
+		if !ok {
+			continue
+		}
+		if converted == nil {
+			converted = append([]interface{}{}, args...)
+		}
+		converted[i] = d.Seconds()
+	}
+	if converted == nil {
+		return args
+	}
+	return converted
 }
 
 // Mul multiplies two numbers.
@@ -20,6 +20,15 @@ func (t Term) SetWriteHook(hookFunc WriteHookFunc) Term {
 	return constructMethodTerm(t, "SetWriteHook", p.Term_SET_WRITE_HOOK, []interface{}{f}, map[string]interface{}{})
 }
 
+// SetWriteHookFunc sets the write hook of a table in the same way as
+// SetWriteHook, but accepts any function value. This can be used to restore a
+// write hook using the binary function returned by GetWriteHook.
+//
+//	table.SetWriteHookFunc(hook.Function)
+func (t Term) SetWriteHookFunc(hookFunc interface{}) Term {
+	return constructMethodTerm(t, "SetWriteHook", p.Term_SET_WRITE_HOOK, []interface{}{funcWrap(hookFunc)}, map[string]interface{}{})
+}
+
 // WriteHookInfo is a return type of GetWriteHook func.
 type WriteHookInfo struct {
 	Function []byte `gorethink:"function,omitempty"`
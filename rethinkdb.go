@@ -26,7 +26,13 @@ const (
 
 func init() {
 	// Set encoding package
-	encoding.IgnoreType(reflect.TypeOf(Term{}))
+	ignoreTerms(encoding.DefaultCodec())
+}
+
+// ignoreTerms causes the codec to leave any terms in the values it encodes
+// unchanged.
+func ignoreTerms(codec *encoding.Codec) {
+	codec.IgnoreType(reflect.TypeOf(Term{}))
 }
 
 // SetTags allows you to override the tags used when decoding or encoding
@@ -38,6 +44,14 @@ func SetTags(tags ...string) {
 	encoding.Tags = append(tags, encoding.TagName, encoding.OldTagName)
 }
 
+// SetNamingStrategy sets the function used to name the fields of structs which
+// do not set a name in their tag, for example encoding.SnakeCase. As the fields
+// of each type are cached it must be called before any values are encoded or
+// decoded.
+func SetNamingStrategy(strategy func(name string) string) {
+	encoding.NamingStrategy = strategy
+}
+
 // SetVerbose allows the driver logging level to be set. If true is passed then
 // the log level is set to Debug otherwise it defaults to Info.
 func SetVerbose(verbose bool) {
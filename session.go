@@ -8,6 +8,7 @@ import (
 
 	"context"
 
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
@@ -20,6 +21,11 @@ type Session struct {
 	mu      sync.RWMutex
 	cluster *Cluster
 	closed  bool
+	queries *queryTracker
+
+	// executor runs the queries of the session through the middleware.
+	executor QueryExecutor
+	queryLog *queryLogger
 }
 
 // ConnectOpts is used to specify optional arguments when connecting to a cluster.
@@ -60,6 +66,20 @@ type ConnectOpts struct {
 	// TLSConfig holds the TLS configuration and can be used when connecting
 	// to a RethinkDB server protected by SSL
 	TLSConfig *tls.Config `rethinkdb:"tlsconfig,omitempty" json:"tlsconfig,omitempty"`
+	// TLSServerName is called with the hostname of each server the driver
+	// connects to and returns the server name used to verify its certificate.
+	// This is useful when DiscoverHosts is enabled and the addresses reported
+	// by the cluster do not match the names in the server certificates. If nil
+	// or if an empty string is returned then TLSConfig.ServerName is used,
+	// falling back to the hostname being dialed.
+	TLSServerName func(host string) string `rethinkdb:"-" json:"-"`
+	// Dialer is used to open the network connection to each server, it can
+	// be used to connect through a proxy or SSH tunnel, to connect to a local
+	// proxy over a Unix domain socket or to rewrite the addresses reported
+	// by the cluster (see DialWithAddressMap). The address passed to Dialer
+	// is still used to verify TLS certificates. If nil a net.Dialer using
+	// Timeout and KeepAlivePeriod is used.
+	Dialer DialFunc `rethinkdb:"-" json:"-"`
 	// HandshakeVersion is used to specify which handshake version should be
 	// used, this currently defaults to v1 which is used by RethinkDB 2.3 and
 	// later. If you are using an older version then you can set the handshake
@@ -69,6 +89,13 @@ type ConnectOpts struct {
 	// use json.Number instead of float64 while unmarshalling documents with
 	// interface{}. The default is `false`.
 	UseJSONNumber bool `json:"use_json_number,omitempty"`
+	// Codec is used to encode the values in queries and to decode the results
+	// of queries run in this session, it can be used to read different struct
+	// tags or use different custom type encodings than the rest of the
+	// program. If nil the default codec of the encoding package is used,
+	// which is configured by SetTags, SetNamingStrategy and
+	// encoding.SetTypeEncoding.
+	Codec *encoding.Codec `rethinkdb:"-" json:"-"`
 	// NumRetries is the number of times a query is retried if a connection
 	// error is detected, queries are not retried if RethinkDB returns a
 	// runtime error.
@@ -95,6 +122,36 @@ type ConnectOpts struct {
 	// HostDecayDuration is used by the go-hostpool package to calculate a weighted
 	// score when selecting a host. By default a value of 5 minutes is used.
 	HostDecayDuration time.Duration `json:"host_decay_duration,omitempty"`
+	// NodeSelector is used to choose the node each query is sent to, if nil
+	// the go-hostpool epsilon-greedy algorithm is used (see HostDecayDuration).
+	NodeSelector NodeSelector `rethinkdb:"-" json:"-"`
+	// AddressTranslator is called with each address reported by the cluster
+	// when DiscoverHosts is enabled and returns the address used to connect
+	// to the server. By default the canonical addresses reported in the
+	// rethinkdb.server_status table are used.
+	AddressTranslator AddressTranslator `rethinkdb:"-" json:"-"`
+	// DiscoverServerNames restricts host discovery to the servers with one of
+	// the given names. If empty servers are not filtered by name.
+	DiscoverServerNames []string `rethinkdb:"discover_server_names,omitempty" json:"discover_server_names,omitempty"`
+	// DiscoverServerTags restricts host discovery to the servers with at least
+	// one of the given tags (as set in the rethinkdb.server_config table). If
+	// empty servers are not filtered by tag.
+	DiscoverServerTags []string `rethinkdb:"discover_server_tags,omitempty" json:"discover_server_tags,omitempty"`
+
+	// DefaultRunOpts are the run options used by every query of the session,
+	// options set when running a query take precedence. Context is ignored.
+	// They are not used by the queries the driver runs itself, such as the
+	// queries discovering hosts.
+	DefaultRunOpts RunOpts `rethinkdb:"-" json:"-"`
+	// Middleware wraps the QueryExecutor used by Session.Query and
+	// Session.Exec, the first middleware is the outermost. See QueryMiddleware.
+	Middleware []QueryMiddleware `rethinkdb:"-" json:"-"`
+	// Interceptors are called with each query before it is sent, after the
+	// middleware, and can reject the query. See QueryInterceptor.
+	Interceptors []QueryInterceptor `rethinkdb:"-" json:"-"`
+	// QueryLog enables the structured logs of the queries of the session,
+	// which are written to Log. See QueryLogOpts.
+	QueryLog *QueryLogOpts `rethinkdb:"-" json:"-"`
 
 	// UseOpentracing is used to enable creating opentracing-go spans for queries.
 	// Each span is created as child of span from the context in `RunOpts`.
@@ -114,6 +171,14 @@ func (o ConnectOpts) toMap() map[string]interface{} {
 	return optArgsToMap(o)
 }
 
+// codec returns the codec used by the session.
+func (o *ConnectOpts) codec() *encoding.Codec {
+	if o.Codec != nil {
+		return o.Codec
+	}
+	return encoding.DefaultCodec()
+}
+
 // Connect creates a new database session. To view the available connection
 // options see ConnectOpts.
 //
@@ -137,6 +202,10 @@ func (o ConnectOpts) toMap() map[string]interface{} {
 //		AuthKey:  "14daak1cad13dj",
 //	})
 func Connect(opts ConnectOpts) (*Session, error) {
+	if opts.Codec != nil {
+		ignoreTerms(opts.Codec)
+	}
+
 	var addresses = opts.Addresses
 	if len(addresses) == 0 {
 		addresses = []string{opts.Address}
@@ -152,24 +221,35 @@ func Connect(opts ConnectOpts) (*Session, error) {
 	}
 
 	// Connect
-	s := &Session{
-		hosts: hosts,
-		opts:  &opts,
-	}
+	s := newSession(hosts, &opts)
 
 	err := s.Reconnect()
 	if err != nil {
 		// note: s.Reconnect() will initialize cluster information which
 		// will cause the .IsConnected() method to be caught in a loop
-		return &Session{
-			hosts: hosts,
-			opts:  &opts,
-		}, err
+		return newSession(hosts, &opts), err
 	}
 
 	return s, nil
 }
 
+func newSession(hosts []Host, opts *ConnectOpts) *Session {
+	s := &Session{
+		hosts: hosts,
+		opts:  opts,
+	}
+
+	if opts.QueryLog != nil {
+		s.queryLog = newQueryLogger(opts.Log, *opts.QueryLog)
+	}
+
+	s.executor = sessionExecutor{s}
+	for i := len(opts.Middleware) - 1; i >= 0; i-- {
+		s.executor = opts.Middleware[i](s.executor)
+	}
+	return s
+}
+
 // CloseOpts allows calls to the Close function to be configured.
 type CloseOpts struct {
 	NoReplyWait bool `rethinkdb:"noreplyWait,omitempty"`
@@ -206,6 +286,7 @@ func (s *Session) Reconnect(optArgs ...CloseOpts) error {
 	}
 
 	s.closed = false
+	s.queries = &queryTracker{}
 	s.mu.Unlock()
 
 	return nil
@@ -237,6 +318,51 @@ func (s *Session) Close(optArgs ...CloseOpts) error {
 	return nil
 }
 
+// Shutdown gracefully closes the session. New queries fail with
+// ErrSessionShutdown and changefeed cursors are closed, then Shutdown waits
+// for the running queries to finish and for noreply queries to be processed
+// by the server before closing the connections. A query is running until its
+// cursor has received the last batch of results or is closed.
+//
+// If ctx is done before the queries finish the connections are closed
+// immediately and the error of ctx is returned.
+func (s *Session) Shutdown(ctx context.Context) error {
+	s.mu.RLock()
+	cluster, queries, closed := s.cluster, s.queries, s.closed
+	s.mu.RUnlock()
+
+	if closed || cluster == nil {
+		return nil
+	}
+
+	feeds, idle := queries.shutdown()
+	for _, cursor := range feeds {
+		go cursor.Close()
+	}
+
+	var err error
+	select {
+	case <-idle:
+		err = cluster.noReplyWait(ctx)
+	case <-ctx.Done():
+	}
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	if cerr := cluster.Close(); err == nil {
+		err = cerr
+	}
+
+	s.mu.Lock()
+	if s.cluster == cluster {
+		s.closed = true
+	}
+	s.mu.Unlock()
+
+	return err
+}
+
 // SetInitialPoolCap sets the initial capacity of the connection pool.
 func (s *Session) SetInitialPoolCap(n int) {
 	s.mu.Lock()
@@ -299,26 +425,74 @@ func (s *Session) Database() string {
 
 // Query executes a ReQL query using the session to connect to the database
 func (s *Session) Query(ctx context.Context, q Query) (*Cursor, error) {
+	if s.executor != nil {
+		return s.executor.Query(ctx, q)
+	}
+	return s.query(ctx, q)
+}
+
+func (s *Session) query(ctx context.Context, q Query) (*Cursor, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if s.closed {
+	if s.closed || s.cluster == nil {
 		return nil, ErrConnectionClosed
 	}
+	if err := s.intercept(ctx, &q); err != nil {
+		return nil, err
+	}
+	if err := s.buildQuery(&q); err != nil {
+		return nil, err
+	}
+	if !s.queries.start() {
+		return nil, ErrSessionShutdown
+	}
 
-	return s.cluster.Query(ctx, q)
+	start := time.Now()
+	cursor, err := s.cluster.Query(ctx, q)
+	if s.queryLog != nil {
+		s.queryLog.track(ctx, q, start, cursor, err)
+	}
+	if err != nil {
+		s.queries.done()
+		return cursor, err
+	}
+	s.queries.track(cursor)
+	return cursor, nil
 }
 
 // Exec executes a ReQL query using the session to connect to the database
 func (s *Session) Exec(ctx context.Context, q Query) error {
+	if s.executor != nil {
+		return s.executor.Exec(ctx, q)
+	}
+	return s.exec(ctx, q)
+}
+
+func (s *Session) exec(ctx context.Context, q Query) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if s.closed {
+	if s.closed || s.cluster == nil {
 		return ErrConnectionClosed
 	}
+	if err := s.intercept(ctx, &q); err != nil {
+		return err
+	}
+	if err := s.buildQuery(&q); err != nil {
+		return err
+	}
+	if !s.queries.start() {
+		return ErrSessionShutdown
+	}
+	defer s.queries.done()
 
-	return s.cluster.Exec(ctx, q)
+	start := time.Now()
+	err := s.cluster.Exec(ctx, q)
+	if s.queryLog != nil {
+		s.queryLog.track(ctx, q, start, nil, err)
+	}
+	return err
 }
 
 // Server returns the server name and server UUID being used by a connection.
@@ -335,5 +509,104 @@ func (s *Session) SetHosts(hosts []Host) {
 }
 
 func (s *Session) newQuery(t Term, opts map[string]interface{}) (Query, error) {
-	return newQuery(t, opts, s.opts)
+	return newQuery(t, withDefaultRunOpts(opts, s.opts.DefaultRunOpts), s.opts)
+}
+
+// buildQuery builds the term of a query whose term was replaced by SetTerm.
+func (s *Session) buildQuery(q *Query) error {
+	if q.Term == nil || q.builtTerm != nil {
+		return nil
+	}
+	var err error
+	q.builtTerm, err = q.Term.build(s.opts.Codec)
+	return err
+}
+
+// queryTracker tracks the running queries of a session and its open
+// changefeed cursors so that Shutdown can wait for them.
+type queryTracker struct {
+	mu       sync.Mutex
+	running  int
+	feeds    map[*Cursor]struct{}
+	stopping bool
+	// idle is closed once the tracker is shut down and no query is running.
+	idle chan struct{}
+}
+
+// start adds a running query, it returns false if the session is shutting
+// down.
+func (t *queryTracker) start() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopping {
+		return false
+	}
+	t.running++
+	return true
+}
+
+func (t *queryTracker) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.running--
+	if t.stopping && t.running == 0 {
+		close(t.idle)
+	}
+}
+
+// track keeps a query running until its cursor is finished or closed. The
+// cursors of changefeeds never finish, they are closed by Shutdown instead.
+func (t *queryTracker) track(cursor *Cursor) {
+	if cursor == nil {
+		t.done()
+		return
+	}
+	if cursor.Type() == "Cursor" {
+		cursor.addOnDone(t.done)
+		return
+	}
+
+	t.mu.Lock()
+	stopping := t.stopping
+	if !stopping {
+		if t.feeds == nil {
+			t.feeds = map[*Cursor]struct{}{}
+		}
+		t.feeds[cursor] = struct{}{}
+	}
+	t.mu.Unlock()
+
+	cursor.addOnDone(func() {
+		t.mu.Lock()
+		delete(t.feeds, cursor)
+		t.mu.Unlock()
+		t.done()
+	})
+	if stopping {
+		go cursor.Close()
+	}
+}
+
+// shutdown stops new queries from starting, it returns the open changefeed
+// cursors and a channel closed once no query is running.
+func (t *queryTracker) shutdown() ([]*Cursor, <-chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopping {
+		return nil, t.idle
+	}
+	t.stopping = true
+	t.idle = make(chan struct{})
+	if t.running == 0 {
+		close(t.idle)
+	}
+
+	feeds := make([]*Cursor, 0, len(t.feeds))
+	for cursor := range t.feeds {
+		feeds = append(feeds, cursor)
+	}
+	return feeds, t.idle
 }
@@ -0,0 +1,294 @@
+package rethinkdb
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	test "gopkg.in/check.v1"
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+)
+
+type SessionSuite struct{}
+
+var _ = test.Suite(&SessionSuite{})
+
+// pipeServer is a server on the other side of net.Pipe connections. A query
+// of the feed table returns a changefeed, a query of another table returns
+// one row in each of two batches, an Error query returns a runtime error and
+// other queries return an atom.
+type pipeServer struct {
+	mu      sync.Mutex
+	queries []p.Query_QueryType
+	// started are the terms and options of the START queries.
+	started [][]json.RawMessage
+}
+
+func (s *pipeServer) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	client, server := net.Pipe()
+	go s.serve(server)
+	return client, nil
+}
+
+func (s *pipeServer) received(typ p.Query_QueryType) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, q := range s.queries {
+		if q == typ {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *pipeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	servePipeHandshake(conn)
+
+	for {
+		header := make([]byte, respHeaderLen)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		token := int64(binary.LittleEndian.Uint64(header))
+		body := make([]byte, binary.LittleEndian.Uint32(header[8:]))
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+
+		var q []json.RawMessage
+		var typ p.Query_QueryType
+		json.Unmarshal(body, &q)
+		json.Unmarshal(q[0], &typ)
+		s.mu.Lock()
+		s.queries = append(s.queries, typ)
+		if typ == p.Query_START {
+			s.started = append(s.started, q[1:])
+		}
+		s.mu.Unlock()
+
+		var resp map[string]interface{}
+		switch typ {
+		case p.Query_SERVER_INFO:
+			resp = map[string]interface{}{"t": p.Response_SERVER_INFO, "r": []interface{}{map[string]interface{}{"id": "node1", "name": "node1"}}}
+		case p.Query_START:
+			var term []interface{}
+			json.Unmarshal(q[1], &term)
+			switch {
+			case len(term) > 0 && term[0] == float64(p.Term_ERROR):
+				resp = map[string]interface{}{"t": p.Response_RUNTIME_ERROR, "r": term[1]}
+			case len(term) > 0 && term[0] == float64(p.Term_INSERT):
+				resp = map[string]interface{}{"t": p.Response_RUNTIME_ERROR, "r": []interface{}{"boom"}}
+			case len(term) == 0 || term[0] != float64(p.Term_TABLE):
+				resp = map[string]interface{}{"t": p.Response_SUCCESS_ATOM, "r": []interface{}{1}}
+			case term[1].([]interface{})[0] == "feed":
+				resp = map[string]interface{}{"t": p.Response_SUCCESS_PARTIAL, "r": []interface{}{}, "n": []interface{}{p.Response_SEQUENCE_FEED}}
+			default:
+				resp = map[string]interface{}{"t": p.Response_SUCCESS_PARTIAL, "r": []interface{}{1}}
+			}
+		case p.Query_CONTINUE:
+			resp = map[string]interface{}{"t": p.Response_SUCCESS_SEQUENCE, "r": []interface{}{2}}
+		case p.Query_STOP:
+			resp = map[string]interface{}{"t": p.Response_SUCCESS_SEQUENCE, "r": []interface{}{}}
+		case p.Query_NOREPLY_WAIT:
+			resp = map[string]interface{}{"t": p.Response_WAIT_COMPLETE}
+		}
+
+		b, _ := json.Marshal(resp)
+		if _, err := conn.Write(append(respHeader(token, b), b...)); err != nil {
+			return
+		}
+	}
+}
+
+// lastStarted returns the term and options of the last START query.
+func (s *pipeServer) lastStarted(c *test.C) (term string, opts map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c.Assert(s.started, test.Not(test.HasLen), 0)
+	q := s.started[len(s.started)-1]
+	if len(q) > 1 {
+		c.Assert(json.Unmarshal(q[1], &opts), test.IsNil)
+	}
+	return string(q[0]), opts
+}
+
+func (s *pipeServer) connect(c *test.C, opts ...ConnectOpts) *Session {
+	var o ConnectOpts
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	o.Address = "host1:28015"
+	o.HandshakeVersion = HandshakeV0_4
+	o.Dialer = s.dial
+
+	session, err := Connect(o)
+	c.Assert(err, test.IsNil)
+	return session
+}
+
+func (s *SessionSuite) TestSession_Shutdown(c *test.C) {
+	srv := &pipeServer{}
+	session := srv.connect(c)
+
+	cursor, err := Table("items").Run(session)
+	c.Assert(err, test.IsNil)
+	feed, err := Table("feed").Run(session)
+	c.Assert(err, test.IsNil)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Shutdown(context.Background())
+	}()
+
+	// New queries fail once the shutdown has started
+	for {
+		_, err := Expr(1).Run(session)
+		if err == ErrSessionShutdown {
+			break
+		}
+		c.Assert(err, test.IsNil)
+		time.Sleep(time.Millisecond)
+	}
+
+	// The changefeed is stopped
+	for srv.received(p.Query_STOP) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	var row interface{}
+	c.Assert(feed.Next(&row), test.Equals, false)
+	c.Assert(feed.Err(), test.IsNil)
+
+	// Shutdown waits for the running query to finish
+	select {
+	case err := <-done:
+		c.Fatalf("Shutdown returned %v before the query finished", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	var rows []int
+	c.Assert(cursor.All(&rows), test.IsNil)
+	c.Assert(rows, test.DeepEquals, []int{1, 2})
+
+	c.Assert(<-done, test.IsNil)
+	c.Assert(srv.received(p.Query_NOREPLY_WAIT), test.Equals, 1)
+	c.Assert(session.IsConnected(), test.Equals, false)
+
+	_, err = Expr(1).Run(session)
+	c.Assert(err, test.Equals, ErrConnectionClosed)
+}
+
+func (s *SessionSuite) TestSession_Shutdown_Deadline(c *test.C) {
+	srv := &pipeServer{}
+	session := srv.connect(c)
+
+	cursor, err := Table("items").Run(session)
+	c.Assert(err, test.IsNil)
+	defer cursor.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	c.Assert(session.Shutdown(ctx), test.Equals, context.DeadlineExceeded)
+	c.Assert(session.IsConnected(), test.Equals, false)
+	c.Assert(srv.received(p.Query_NOREPLY_WAIT), test.Equals, 0)
+}
+
+func (s *SessionSuite) TestSession_Shutdown_Reconnect(c *test.C) {
+	srv := &pipeServer{}
+	session := srv.connect(c)
+
+	c.Assert(session.Shutdown(context.Background()), test.IsNil)
+	c.Assert(session.Reconnect(), test.IsNil)
+
+	var n int
+	c.Assert(Expr(1).ReadOne(&n, session), test.IsNil)
+	c.Assert(n, test.Equals, 1)
+}
+
+func (s *SessionSuite) TestSession_DefaultRunOpts(c *test.C) {
+	srv := &pipeServer{}
+	session := srv.connect(c, ConnectOpts{
+		DefaultRunOpts: RunOpts{ReadMode: "outdated", ArrayLimit: 10},
+	})
+	defer session.Close()
+
+	var n int
+	c.Assert(Expr(1).ReadOne(&n, session, RunOpts{ArrayLimit: 20}), test.IsNil)
+	_, opts := srv.lastStarted(c)
+	c.Assert(opts, test.DeepEquals, map[string]interface{}{"read_mode": "outdated", "array_limit": 20.0})
+}
+
+// optsMiddleware sets a run option and records the queries it runs.
+type optsMiddleware struct {
+	QueryExecutor
+	name  string
+	calls *[]string
+}
+
+func (m optsMiddleware) Query(ctx context.Context, q Query) (*Cursor, error) {
+	*m.calls = append(*m.calls, m.name)
+	if err := q.SetOpt("durability", "soft"); err != nil {
+		return nil, err
+	}
+	return m.QueryExecutor.Query(ctx, q)
+}
+
+func (m optsMiddleware) Exec(ctx context.Context, q Query) error {
+	*m.calls = append(*m.calls, m.name+" exec")
+	return m.QueryExecutor.Exec(ctx, q)
+}
+
+// tableMiddleware rewrites queries of the users table to the accounts table
+// and rejects queries of the secrets table.
+type tableMiddleware struct {
+	QueryExecutor
+	calls *[]string
+}
+
+func (m tableMiddleware) Query(ctx context.Context, q Query) (*Cursor, error) {
+	*m.calls = append(*m.calls, "tables")
+	switch q.Term.String() {
+	case `r.Table("users")`:
+		q.SetTerm(Table("accounts"))
+	case `r.Table("secrets")`:
+		return nil, errors.New("secrets are not allowed")
+	}
+	return m.QueryExecutor.Query(ctx, q)
+}
+
+func (s *SessionSuite) TestSession_Middleware(c *test.C) {
+	var calls []string
+	srv := &pipeServer{}
+	session := srv.connect(c, ConnectOpts{
+		Middleware: []QueryMiddleware{
+			func(next QueryExecutor) QueryExecutor {
+				return optsMiddleware{QueryExecutor: next, name: "opts", calls: &calls}
+			},
+			func(next QueryExecutor) QueryExecutor {
+				return tableMiddleware{QueryExecutor: next, calls: &calls}
+			},
+		},
+	})
+	defer session.Close()
+
+	cursor, err := Table("users").Run(session, RunOpts{ReadMode: "single"})
+	c.Assert(err, test.IsNil)
+	c.Assert(cursor.Close(), test.IsNil)
+	term, opts := srv.lastStarted(c)
+	c.Assert(term, test.Equals, `[15,["accounts"]]`)
+	c.Assert(opts, test.DeepEquals, map[string]interface{}{"read_mode": "single", "durability": "soft"})
+
+	_, err = Table("secrets").Run(session)
+	c.Assert(err, test.ErrorMatches, "secrets are not allowed")
+
+	c.Assert(Expr(1).Exec(session), test.IsNil)
+	c.Assert(calls, test.DeepEquals, []string{"opts", "tables", "opts", "tables", "opts exec"})
+}
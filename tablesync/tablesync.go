@@ -0,0 +1,318 @@
+// Package tablesync copies a table to another table, on the same or another
+// cluster, and keeps it up to date using a changefeed, for example to migrate
+// a table between clusters without downtime.
+//
+// A sync has two phases. The documents of the source table are first copied
+// by an ordered scan of the primary key, saving a checkpoint after each batch
+// so that an interrupted scan resumes where it stopped. The changes of the
+// source table are then tailed using
+//
+//	Changes(ChangesOpts{IncludeInitial: true, IncludeStates: true, IncludeTypes: true})
+//
+// The initial values of the changefeed replace the documents of the target to
+// reconcile documents which changed during the scan, whatever the conflict
+// policy, and each later insert, update and delete is applied to the target. Changefeeds cannot be resumed, so a sync
+// restarted after the scan completed starts again from the initial values of
+// the changefeed.
+//
+//	stats, err := tablesync.Sync(ctx,
+//		tablesync.Table{Session: oldCluster, Term: r.DB("app").Table("users")},
+//		tablesync.Table{Session: newCluster, Term: r.DB("app").Table("users")},
+//		tablesync.SyncOpts{Checkpoint: tablesync.FileCheckpoint("users.checkpoint")},
+//	)
+package tablesync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	rethinkdb "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// Table is a table and the session used to query it.
+type Table struct {
+	Session rethinkdb.QueryExecutor
+	Term    rethinkdb.Term
+}
+
+// ConflictPolicy selects how documents are written to the target when the
+// target document is not the one expected, either because the document
+// already exists when it is copied by the scan or because it was modified in
+// the target since it was last written by the sync. The initial values of the
+// changefeed always replace the target documents.
+type ConflictPolicy string
+
+const (
+	// ConflictReplace replaces the target document with the source document,
+	// or deletes it if the source document was deleted.
+	ConflictReplace ConflictPolicy = "replace"
+	// ConflictUpdate merges the fields of the source document into the target
+	// document.
+	ConflictUpdate ConflictPolicy = "update"
+	// ConflictKeep keeps the target document.
+	ConflictKeep ConflictPolicy = "keep"
+	// ConflictError stops the sync with an error.
+	ConflictError ConflictPolicy = "error"
+)
+
+// SyncOpts contains the optional arguments for Sync.
+type SyncOpts struct {
+	// PrimaryKey is the primary key of the tables, by default it is read
+	// from the info of the source table.
+	PrimaryKey string
+	// Conflict is the conflict policy, ConflictReplace by default.
+	Conflict ConflictPolicy
+	// BatchSize is the number of documents written by each insert while
+	// copying documents, 200 by default.
+	BatchSize int
+	// Checkpoint stores the progress of the sync, if nil the scan is not
+	// resumable.
+	Checkpoint CheckpointStore
+	// Prune deletes the documents of the target which are not in the source
+	// once the initial values of the changefeed have been written. The
+	// primary keys of every document of the source are kept in memory until
+	// then.
+	Prune bool
+	// OnReady is called once the target is up to date with the source and
+	// changes are being applied as they happen.
+	OnReady func(Stats)
+}
+
+func (o SyncOpts) batchSize() int {
+	if o.BatchSize <= 0 {
+		return 200
+	}
+	return o.BatchSize
+}
+
+// Stats are the number of documents written to the target by a sync.
+type Stats struct {
+	// Copied is the number of documents copied by the scan and from the
+	// initial values of the changefeed.
+	Copied int64
+	// Inserted, Updated and Deleted are the number of changes applied.
+	Inserted int64
+	Updated  int64
+	Deleted  int64
+	// Conflicts is the number of changes applied to a target document which
+	// was not the expected one.
+	Conflicts int64
+	// Pruned is the number of target documents deleted by Prune.
+	Pruned int64
+}
+
+// Checkpoint is the progress of a sync.
+type Checkpoint struct {
+	// LastKey is the primary key of the last document copied by the scan.
+	LastKey interface{} `json:"last_key,omitempty"`
+	// ScanDone is true once the scan has copied every document.
+	ScanDone bool `json:"scan_done"`
+}
+
+// CheckpointStore loads and saves the checkpoint of a sync.
+type CheckpointStore interface {
+	// Load returns the saved checkpoint, or the zero Checkpoint if none has
+	// been saved.
+	Load() (Checkpoint, error)
+	Save(cp Checkpoint) error
+}
+
+// FileCheckpoint stores the checkpoint of a sync as JSON in the named file.
+type FileCheckpoint string
+
+// Load reads the checkpoint from the file.
+func (f FileCheckpoint) Load() (Checkpoint, error) {
+	var cp Checkpoint
+	b, err := os.ReadFile(string(f))
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	err = json.Unmarshal(b, &cp)
+	return cp, err
+}
+
+// Save writes the checkpoint to a temporary file and renames it to the file,
+// so an interrupted save does not lose the previous checkpoint.
+func (f FileCheckpoint) Save(cp Checkpoint) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(string(f)), filepath.Base(string(f))+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), string(f))
+}
+
+// rawOpts keeps pseudo-types in the format returned by the server, so they
+// are written to the target unchanged.
+func rawOpts(ctx context.Context) rethinkdb.RunOpts {
+	return rethinkdb.RunOpts{
+		Context:        ctx,
+		TimeFormat:     "raw",
+		BinaryFormat:   "raw",
+		GeometryFormat: "raw",
+	}
+}
+
+// Sync copies the documents of source to target and then applies the changes
+// of source to target until ctx is done or an error happens. The target table
+// must already exist.
+func Sync(ctx context.Context, source, target Table, opts SyncOpts) (Stats, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	switch opts.Conflict {
+	case "":
+		opts.Conflict = ConflictReplace
+	case ConflictReplace, ConflictUpdate, ConflictKeep, ConflictError:
+	default:
+		return Stats{}, fmt.Errorf("rethinkdb: tablesync: conflict policy %q is not valid", opts.Conflict)
+	}
+
+	s := &syncer{ctx: ctx, source: source, target: target, opts: opts}
+	err := s.run()
+	if err != nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	return s.stats(), err
+}
+
+type syncer struct {
+	ctx            context.Context
+	source, target Table
+	opts           SyncOpts
+	pk             string
+
+	copied, inserted, updated, deleted, conflicts, pruned atomic.Int64
+}
+
+func (s *syncer) stats() Stats {
+	return Stats{
+		Copied:    s.copied.Load(),
+		Inserted:  s.inserted.Load(),
+		Updated:   s.updated.Load(),
+		Deleted:   s.deleted.Load(),
+		Conflicts: s.conflicts.Load(),
+		Pruned:    s.pruned.Load(),
+	}
+}
+
+func (s *syncer) run() error {
+	s.pk = s.opts.PrimaryKey
+	if s.pk == "" {
+		if err := s.source.Term.Info().Field("primary_key").ReadOne(&s.pk, s.source.Session, rethinkdb.RunOpts{Context: s.ctx}); err != nil {
+			return err
+		}
+	}
+
+	var cp Checkpoint
+	if s.opts.Checkpoint != nil {
+		var err error
+		if cp, err = s.opts.Checkpoint.Load(); err != nil {
+			return err
+		}
+	}
+	if !cp.ScanDone {
+		if err := s.scan(cp); err != nil {
+			return err
+		}
+	}
+
+	return s.tail()
+}
+
+func (s *syncer) saveCheckpoint(cp Checkpoint) error {
+	if s.opts.Checkpoint == nil {
+		return nil
+	}
+	return s.opts.Checkpoint.Save(cp)
+}
+
+// scan copies the documents of the source in primary key order, starting
+// after the last key of the checkpoint.
+func (s *syncer) scan(cp Checkpoint) error {
+	q := s.source.Term
+	if cp.LastKey != nil {
+		q = q.Between(cp.LastKey, rethinkdb.MaxVal, rethinkdb.BetweenOpts{LeftBound: "open"})
+	}
+	cursor, err := q.OrderBy(rethinkdb.OrderByOpts{Index: s.pk}).Run(s.source.Session, rawOpts(s.ctx))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	batch := make([]interface{}, 0, s.opts.batchSize())
+	var doc map[string]interface{}
+	for cursor.Next(&doc) {
+		batch = append(batch, doc)
+		doc = nil
+		if len(batch) < cap(batch) {
+			continue
+		}
+		if err := s.copyBatch(batch, s.opts.Conflict); err != nil {
+			return err
+		}
+		cp.LastKey = batch[len(batch)-1].(map[string]interface{})[s.pk]
+		if err := s.saveCheckpoint(cp); err != nil {
+			return err
+		}
+		batch = batch[:0]
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if err := s.copyBatch(batch, s.opts.Conflict); err != nil {
+		return err
+	}
+
+	return s.saveCheckpoint(Checkpoint{ScanDone: true})
+}
+
+// copyBatch inserts documents into the target, resolving conflicts with
+// existing documents using the conflict policy.
+func (s *syncer) copyBatch(batch []interface{}, policy ConflictPolicy) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	var conflict interface{}
+	switch policy {
+	case ConflictKeep:
+		conflict = func(id, oldDoc, newDoc rethinkdb.Term) rethinkdb.Term {
+			return oldDoc
+		}
+	case ConflictError:
+		// Documents which were already copied are not conflicts
+		conflict = func(id, oldDoc, newDoc rethinkdb.Term) rethinkdb.Term {
+			return rethinkdb.Branch(oldDoc.Eq(newDoc), oldDoc, rethinkdb.Error(conflictError(id)))
+		}
+	default:
+		conflict = string(policy)
+	}
+
+	_, err := s.target.Term.Insert(batch, rethinkdb.InsertOpts{Conflict: conflict}).RunWrite(s.target.Session, rethinkdb.RunOpts{Context: s.ctx})
+	if err != nil {
+		return err
+	}
+	s.copied.Add(int64(len(batch)))
+	return nil
+}
@@ -0,0 +1,304 @@
+package tablesync
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+var (
+	users = r.DB("app").Table("users")
+	alice = map[string]interface{}{"id": 1.0, "name": "Alice"}
+	bob   = map[string]interface{}{"id": 2.0, "name": "Bob"}
+	ready = map[string]interface{}{"state": "ready", "type": "state"}
+)
+
+func changes() r.Term {
+	return users.Changes(r.ChangesOpts{IncludeInitial: true, IncludeStates: true, IncludeTypes: true})
+}
+
+func initial(doc map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"new_val": doc, "type": "initial"}
+}
+
+// replace returns the query applying a change to the target with
+// ConflictReplace.
+func replace(key, oldVal, newVal interface{}) r.Term {
+	return users.Get(key).Replace(func(doc r.Term) interface{} {
+		return r.Branch(doc.Eq(oldVal), newVal, newVal)
+	}, r.ReplaceOpts{ReturnChanges: "always"})
+}
+
+func replaced(oldVal, newVal interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"replaced": 1,
+		"changes":  []interface{}{map[string]interface{}{"old_val": oldVal, "new_val": newVal}},
+	}
+}
+
+func TestSync(t *testing.T) {
+	alice2 := map[string]interface{}{"id": 1.0, "name": "Alice Smith"}
+	carol := map[string]interface{}{"id": 3.0, "name": "Carol"}
+
+	source := r.NewMock()
+	source.On(users.Info().Field("primary_key")).Return("id", nil)
+	source.On(users.OrderBy(r.OrderByOpts{Index: "id"})).Return([]interface{}{alice, bob}, nil)
+	source.On(changes()).Return([]interface{}{
+		initial(alice),
+		initial(bob),
+		ready,
+		map[string]interface{}{"old_val": alice, "new_val": alice2, "type": "change"},
+		map[string]interface{}{"old_val": bob, "type": "remove"},
+		map[string]interface{}{"new_val": carol, "type": "add"},
+	}, nil)
+
+	target := r.NewMock()
+	target.On(users.Insert([]interface{}{alice, bob}, r.InsertOpts{Conflict: "replace"})).Return(map[string]interface{}{"inserted": 2}, nil).Twice()
+	target.On(replace(1.0, alice, alice2)).Return(replaced(alice, alice2), nil)
+	target.On(replace(2.0, bob, nil)).Return(replaced(bob, nil), nil)
+	target.On(replace(3.0, nil, carol)).Return(replaced(nil, carol), nil)
+
+	checkpoint := FileCheckpoint(filepath.Join(t.TempDir(), "users.checkpoint"))
+	var readyStats Stats
+	stats, err := Sync(context.Background(), Table{source, users}, Table{target, users}, SyncOpts{
+		Checkpoint: checkpoint,
+		OnReady:    func(s Stats) { readyStats = s },
+	})
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	source.AssertExpectations(t)
+	target.AssertExpectations(t)
+
+	if want := (Stats{Copied: 4}); readyStats != want {
+		t.Errorf("got stats %+v when ready, want %+v", readyStats, want)
+	}
+	if want := (Stats{Copied: 4, Inserted: 1, Updated: 1, Deleted: 1}); stats != want {
+		t.Errorf("got stats %+v, want %+v", stats, want)
+	}
+	cp, err := checkpoint.Load()
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	if !cp.ScanDone {
+		t.Errorf("got checkpoint %+v, want the scan to be done", cp)
+	}
+}
+
+func TestSync_ResumeScan(t *testing.T) {
+	checkpoint := FileCheckpoint(filepath.Join(t.TempDir(), "users.checkpoint"))
+	if err := checkpoint.Save(Checkpoint{LastKey: 1.0}); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+
+	source := r.NewMock()
+	source.On(users.Between(1.0, r.MaxVal, r.BetweenOpts{LeftBound: "open"}).OrderBy(r.OrderByOpts{Index: "id"})).Return([]interface{}{bob}, nil)
+	source.On(changes()).Return([]interface{}{ready}, nil)
+
+	target := r.NewMock()
+	target.On(users.Insert([]interface{}{bob}, r.InsertOpts{Conflict: "replace"})).Return(map[string]interface{}{"inserted": 1}, nil)
+
+	stats, err := Sync(context.Background(), Table{source, users}, Table{target, users}, SyncOpts{
+		PrimaryKey: "id",
+		BatchSize:  1,
+		Checkpoint: checkpoint,
+	})
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	source.AssertExpectations(t)
+	target.AssertExpectations(t)
+	if stats.Copied != 1 {
+		t.Errorf("got %d documents copied, want 1", stats.Copied)
+	}
+}
+
+func TestSync_ScanDone(t *testing.T) {
+	checkpoint := FileCheckpoint(filepath.Join(t.TempDir(), "users.checkpoint"))
+	if err := checkpoint.Save(Checkpoint{ScanDone: true}); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+
+	// The mock panics if the source is scanned again
+	source := r.NewMock()
+	source.On(changes()).Return([]interface{}{initial(alice), ready}, nil)
+
+	target := r.NewMock()
+	target.On(users.Insert([]interface{}{alice}, r.InsertOpts{Conflict: "replace"})).Return(map[string]interface{}{"inserted": 1}, nil)
+
+	_, err := Sync(context.Background(), Table{source, users}, Table{target, users}, SyncOpts{PrimaryKey: "id", Checkpoint: checkpoint})
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	target.AssertExpectations(t)
+}
+
+func TestSync_Prune(t *testing.T) {
+	source := r.NewMock()
+	source.On(users.OrderBy(r.OrderByOpts{Index: "id"})).Return([]interface{}{}, nil)
+	source.On(changes()).Return([]interface{}{initial(alice), initial(bob), ready}, nil)
+
+	target := r.NewMock()
+	target.On(users.Insert([]interface{}{alice, bob}, r.InsertOpts{Conflict: "replace"})).Return(map[string]interface{}{"inserted": 2}, nil)
+	target.On(users.Field("id")).Return([]interface{}{1.0, 2.0, 3.0, 4.0}, nil)
+	target.On(users.GetAll(3.0, 4.0).Delete()).Return(map[string]interface{}{"deleted": 2}, nil)
+
+	stats, err := Sync(context.Background(), Table{source, users}, Table{target, users}, SyncOpts{PrimaryKey: "id", Prune: true})
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	target.AssertExpectations(t)
+	if stats.Pruned != 2 {
+		t.Errorf("got %d documents pruned, want 2", stats.Pruned)
+	}
+}
+
+func TestSync_ConflictKeep(t *testing.T) {
+	alice2 := map[string]interface{}{"id": 1.0, "name": "Alice Smith"}
+	edited := map[string]interface{}{"id": 1.0, "name": "Alice Jones"}
+
+	source := r.NewMock()
+	source.On(users.OrderBy(r.OrderByOpts{Index: "id"})).Return([]interface{}{}, nil)
+	source.On(changes()).Return([]interface{}{
+		ready,
+		map[string]interface{}{"old_val": alice, "new_val": alice2, "type": "change"},
+	}, nil)
+
+	target := r.NewMock()
+	target.On(users.Get(1.0).Replace(func(doc r.Term) interface{} {
+		return r.Branch(doc.Eq(alice), alice2, doc)
+	}, r.ReplaceOpts{ReturnChanges: "always"})).Return(map[string]interface{}{
+		"unchanged": 1,
+		"changes":   []interface{}{map[string]interface{}{"old_val": edited, "new_val": edited}},
+	}, nil)
+
+	stats, err := Sync(context.Background(), Table{source, users}, Table{target, users}, SyncOpts{PrimaryKey: "id", Conflict: ConflictKeep})
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	target.AssertExpectations(t)
+	if want := (Stats{Updated: 1, Conflicts: 1}); stats != want {
+		t.Errorf("got stats %+v, want %+v", stats, want)
+	}
+}
+
+func TestSync_ConflictKeepInitial(t *testing.T) {
+	alice2 := map[string]interface{}{"id": 1.0, "name": "Alice Smith"}
+
+	// Alice changed during the scan, the initial value replaces the copy
+	source := r.NewMock()
+	source.On(users.OrderBy(r.OrderByOpts{Index: "id"})).Return([]interface{}{alice}, nil)
+	source.On(changes()).Return([]interface{}{initial(alice2), ready}, nil)
+
+	target := r.NewMock()
+	target.On(users.Insert([]interface{}{alice}, r.InsertOpts{
+		Conflict: func(id, oldDoc, newDoc r.Term) r.Term {
+			return oldDoc
+		},
+	})).Return(map[string]interface{}{"inserted": 1}, nil)
+	target.On(users.Insert([]interface{}{alice2}, r.InsertOpts{Conflict: "replace"})).Return(map[string]interface{}{"replaced": 1}, nil)
+
+	stats, err := Sync(context.Background(), Table{source, users}, Table{target, users}, SyncOpts{PrimaryKey: "id", Conflict: ConflictKeep})
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	target.AssertExpectations(t)
+	if want := (Stats{Copied: 2}); stats != want {
+		t.Errorf("got stats %+v, want %+v", stats, want)
+	}
+}
+
+func TestSync_ConflictErrorInitial(t *testing.T) {
+	alice2 := map[string]interface{}{"id": 1.0, "name": "Alice Smith"}
+
+	// Alice changed while the sync was stopped, the initial value replaces
+	// the copy rather than stopping the sync
+	source := r.NewMock()
+	source.On(changes()).Return([]interface{}{initial(alice2), ready}, nil)
+
+	target := r.NewMock()
+	target.On(users.Insert([]interface{}{alice2}, r.InsertOpts{Conflict: "replace"})).Return(map[string]interface{}{"replaced": 1}, nil)
+
+	stats, err := Sync(context.Background(), Table{source, users}, Table{target, users}, SyncOpts{
+		PrimaryKey: "id",
+		Conflict:   ConflictError,
+		Checkpoint: memoryCheckpoint{ScanDone: true},
+	})
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	target.AssertExpectations(t)
+	if want := (Stats{Copied: 1}); stats != want {
+		t.Errorf("got stats %+v, want %+v", stats, want)
+	}
+}
+
+func TestSync_ChangefeedError(t *testing.T) {
+	source := r.NewMock()
+	source.On(changes()).Return([]interface{}{
+		map[string]interface{}{"error": "Changefeed cache over array size limit"},
+	}, nil)
+
+	_, err := Sync(context.Background(), Table{source, users}, Table{r.NewMock(), users}, SyncOpts{
+		PrimaryKey: "id",
+		Checkpoint: memoryCheckpoint{ScanDone: true},
+	})
+	if err == nil || !strings.Contains(err.Error(), "array size limit") {
+		t.Errorf("got error %v, want the changefeed error", err)
+	}
+}
+
+func TestSync_InvalidConflict(t *testing.T) {
+	_, err := Sync(context.Background(), Table{r.NewMock(), users}, Table{r.NewMock(), users}, SyncOpts{Conflict: "merge"})
+	if err == nil {
+		t.Error("got nil, expected an error for an invalid conflict policy")
+	}
+}
+
+func TestFileCheckpoint(t *testing.T) {
+	checkpoint := FileCheckpoint(filepath.Join(t.TempDir(), "users.checkpoint"))
+
+	cp, err := checkpoint.Load()
+	if err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	if !reflect.DeepEqual(cp, Checkpoint{}) {
+		t.Errorf("got checkpoint %+v, want the zero checkpoint", cp)
+	}
+
+	want := Checkpoint{LastKey: []interface{}{"a", 1.0}}
+	if err := checkpoint.Save(want); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	if cp, err = checkpoint.Load(); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+	if !reflect.DeepEqual(cp, want) {
+		t.Errorf("got checkpoint %+v, want %+v", cp, want)
+	}
+}
+
+func TestKeyOf(t *testing.T) {
+	seen := map[interface{}]bool{
+		keyOf("a"):                     true,
+		keyOf(1.0):                     true,
+		keyOf([]interface{}{"a", 1.0}): true,
+	}
+	for _, key := range []interface{}{"a", 1.0, []interface{}{"a", 1.0}} {
+		if !seen[keyOf(key)] {
+			t.Errorf("got key %v not seen, want it to be seen", key)
+		}
+	}
+	if seen[keyOf(`["a",1]`)] {
+		t.Error("got a string key equal to an array key")
+	}
+}
+
+type memoryCheckpoint Checkpoint
+
+func (m memoryCheckpoint) Load() (Checkpoint, error) { return Checkpoint(m), nil }
+func (m memoryCheckpoint) Save(Checkpoint) error     { return nil }
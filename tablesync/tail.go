@@ -0,0 +1,225 @@
+package tablesync
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	rethinkdb "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// tail applies the changes of the source to the target.
+func (s *syncer) tail() error {
+	feed := s.source.Term.Changes(rethinkdb.ChangesOpts{
+		IncludeInitial: true,
+		IncludeStates:  true,
+		IncludeTypes:   true,
+	})
+	cursor, err := feed.Run(s.source.Session, rawOpts(s.ctx))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	var (
+		batch = make([]interface{}, 0, s.opts.batchSize())
+		seen  map[interface{}]bool
+	)
+	if s.opts.Prune {
+		seen = map[interface{}]bool{}
+	}
+
+	var change rethinkdb.ChangeResponse
+	for cursor.Next(&change) {
+		if change.Error != "" {
+			return fmt.Errorf("rethinkdb: tablesync: changefeed error: %s", change.Error)
+		}
+
+		switch change.Type {
+		case "state":
+			if change.State != "ready" {
+				break
+			}
+			if err := s.copyBatch(batch, ConflictReplace); err != nil {
+				return err
+			}
+			batch = batch[:0]
+			if err := s.ready(seen); err != nil {
+				return err
+			}
+			seen = nil
+		case "initial":
+			// The initial values replace the documents copied by the scan,
+			// which may have changed since, whatever the conflict policy
+			doc, _ := change.NewValue.(map[string]interface{})
+			if doc == nil {
+				break
+			}
+			if seen != nil {
+				seen[keyOf(doc[s.pk])] = true
+			}
+			batch = append(batch, doc)
+			if len(batch) == cap(batch) {
+				if err := s.copyBatch(batch, ConflictReplace); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		case "uninitial":
+			// The document changed before its initial value was sent, the
+			// change follows
+		default:
+			// Write the initial values first so changes are applied in order
+			if err := s.copyBatch(batch, ConflictReplace); err != nil {
+				return err
+			}
+			batch = batch[:0]
+			if err := s.apply(change); err != nil {
+				return err
+			}
+		}
+		change = rethinkdb.ChangeResponse{}
+	}
+
+	return cursor.Err()
+}
+
+// ready is called once the initial values have been written, pruning the
+// documents of the target which were not seen.
+func (s *syncer) ready(seen map[interface{}]bool) error {
+	if seen != nil {
+		if err := s.prune(seen); err != nil {
+			return err
+		}
+	}
+	if err := s.saveCheckpoint(Checkpoint{ScanDone: true}); err != nil {
+		return err
+	}
+	if s.opts.OnReady != nil {
+		s.opts.OnReady(s.stats())
+	}
+	return nil
+}
+
+// prune deletes the documents of the target whose primary key is not in
+// seen.
+func (s *syncer) prune(seen map[interface{}]bool) error {
+	cursor, err := s.target.Term.Field(s.pk).Run(s.target.Session, rawOpts(s.ctx))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	var (
+		keys []interface{}
+		key  interface{}
+	)
+	for cursor.Next(&key) {
+		if !seen[keyOf(key)] {
+			keys = append(keys, key)
+		}
+		key = nil
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	size := s.opts.batchSize()
+	for len(keys) > 0 {
+		n := size
+		if n > len(keys) {
+			n = len(keys)
+		}
+		res, err := s.target.Term.GetAll(keys[:n]...).Delete().RunWrite(s.target.Session, rethinkdb.RunOpts{Context: s.ctx})
+		if err != nil {
+			return err
+		}
+		s.pruned.Add(int64(res.Deleted))
+		keys = keys[n:]
+	}
+	return nil
+}
+
+// compositeKey is an array or pseudo-type primary key encoded as JSON, a
+// distinct type so it does not collide with a string key.
+type compositeKey string
+
+// keyOf returns a primary key which can be used as a map key.
+func keyOf(key interface{}) interface{} {
+	switch key.(type) {
+	case string, float64, bool, nil:
+		return key
+	}
+	b, _ := json.Marshal(key)
+	return compositeKey(b)
+}
+
+// apply writes a change to the target document, the change is applied only
+// if the target document is the old value of the change, otherwise the
+// conflict policy is used.
+func (s *syncer) apply(change rethinkdb.ChangeResponse) error {
+	oldDoc, _ := change.OldValue.(map[string]interface{})
+	newDoc, _ := change.NewValue.(map[string]interface{})
+
+	var key interface{}
+	switch {
+	case newDoc != nil:
+		key = newDoc[s.pk]
+	case oldDoc != nil:
+		key = oldDoc[s.pk]
+	default:
+		return nil
+	}
+
+	// Replace expects nil rather than an empty map for a deleted document
+	var oldVal, newVal interface{}
+	if oldDoc != nil {
+		oldVal = oldDoc
+	}
+	if newDoc != nil {
+		newVal = newDoc
+	}
+
+	res, err := s.target.Term.Get(key).Replace(func(doc rethinkdb.Term) interface{} {
+		return rethinkdb.Branch(doc.Eq(oldVal), newVal, s.resolve(doc, newVal))
+	}, rethinkdb.ReplaceOpts{ReturnChanges: "always"}).RunWrite(s.target.Session, rawOpts(s.ctx))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case oldDoc == nil:
+		s.inserted.Add(1)
+	case newDoc == nil:
+		s.deleted.Add(1)
+	default:
+		s.updated.Add(1)
+	}
+	if len(res.Changes) > 0 && !reflect.DeepEqual(res.Changes[0].OldValue, oldVal) {
+		s.conflicts.Add(1)
+	}
+	return nil
+}
+
+// resolve returns the value written to the target document doc when it is
+// not the old value of a change.
+func (s *syncer) resolve(doc rethinkdb.Term, newVal interface{}) interface{} {
+	switch s.opts.Conflict {
+	case ConflictUpdate:
+		if newVal == nil {
+			return nil
+		}
+		return rethinkdb.Branch(doc.Eq(nil), newVal, doc.Merge(newVal))
+	case ConflictKeep:
+		return doc
+	case ConflictError:
+		return rethinkdb.Error(conflictError(doc.Field(s.pk)))
+	default:
+		return newVal
+	}
+}
+
+// conflictError returns the message of the error raised by ConflictError.
+func conflictError(key rethinkdb.Term) rethinkdb.Term {
+	return rethinkdb.Expr("rethinkdb: tablesync: conflicting change to document ").Add(key.CoerceTo("string"))
+}
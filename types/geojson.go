@@ -0,0 +1,258 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// geoJSON returns the geometry as a GeoJSON object, without the $reql_type$
+// field used by the GEOMETRY pseudo-type.
+func (g Geometry) geoJSON() (map[string]interface{}, error) {
+	var coords interface{}
+	switch g.Type {
+	case "Point":
+		coords = g.Point.Coords()
+	case "LineString":
+		coords = g.Line.Coords()
+	case "Polygon":
+		coords = g.Lines.Coords()
+	case "MultiPoint":
+		coords = g.MultiPoint.Coords()
+	case "MultiLineString":
+		coords = g.MultiLine.Coords()
+	case "MultiPolygon":
+		coords = g.MultiPolygon.Coords()
+	case "GeometryCollection":
+		geometries, err := g.Geometries.geoJSON()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"geometries": geometries,
+			"type":       g.Type,
+		}, nil
+	default:
+		return nil, fmt.Errorf("GeoJSON object field 'type' %s is not valid", g.Type)
+	}
+
+	return map[string]interface{}{
+		"coordinates": coords,
+		"type":        g.Type,
+	}, nil
+}
+
+func (c GeometryCollection) geoJSON() ([]interface{}, error) {
+	geometries := make([]interface{}, len(c))
+	for i, geometry := range c {
+		obj, err := geometry.geoJSON()
+		if err != nil {
+			return nil, err
+		}
+		geometries[i] = obj
+	}
+	return geometries, nil
+}
+
+// MarshalJSON encodes the geometry as a GeoJSON geometry object. A geometry
+// without a type is encoded as null.
+func (g Geometry) MarshalJSON() ([]byte, error) {
+	if g.Type == "" {
+		return []byte("null"), nil
+	}
+
+	obj, err := g.geoJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(obj)
+}
+
+// UnmarshalJSON decodes a GeoJSON geometry object.
+func (g *Geometry) UnmarshalJSON(data []byte) error {
+	var obj interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	if obj == nil {
+		return nil
+	}
+
+	return g.UnmarshalRQL(obj)
+}
+
+// Parts returns the geometry split into Point, LineString and Polygon
+// geometries, the types of geometry stored by RethinkDB.
+func (g Geometry) Parts() []Geometry {
+	var parts []Geometry
+	switch g.Type {
+	case "MultiPoint":
+		for _, point := range g.MultiPoint {
+			parts = append(parts, Geometry{Type: "Point", Point: point})
+		}
+	case "MultiLineString":
+		for _, line := range g.MultiLine {
+			parts = append(parts, Geometry{Type: "LineString", Line: line})
+		}
+	case "MultiPolygon":
+		for _, polygon := range g.MultiPolygon {
+			parts = append(parts, Geometry{Type: "Polygon", Lines: polygon})
+		}
+	case "GeometryCollection":
+		for _, geometry := range g.Geometries {
+			parts = append(parts, geometry.Parts()...)
+		}
+	default:
+		parts = append(parts, g)
+	}
+	return parts
+}
+
+// points returns all of the points of the geometry.
+func (g Geometry) points() []Point {
+	var points []Point
+	switch g.Type {
+	case "Point":
+		points = append(points, g.Point)
+	case "LineString":
+		points = append(points, g.Line...)
+	case "Polygon":
+		for _, line := range g.Lines {
+			points = append(points, line...)
+		}
+	case "MultiPoint":
+		points = append(points, g.MultiPoint...)
+	case "MultiLineString":
+		for _, line := range g.MultiLine {
+			points = append(points, line...)
+		}
+	case "MultiPolygon":
+		for _, polygon := range g.MultiPolygon {
+			for _, line := range polygon {
+				points = append(points, line...)
+			}
+		}
+	case "GeometryCollection":
+		for _, geometry := range g.Geometries {
+			points = append(points, geometry.points()...)
+		}
+	}
+	return points
+}
+
+// BBox is the bounding box of a geometry, Min contains the lowest longitude
+// and latitude and Max the highest.
+type BBox struct {
+	Min Point
+	Max Point
+}
+
+// Contains returns true if the point is inside the bounding box.
+func (b BBox) Contains(p Point) bool {
+	return p.Lon >= b.Min.Lon && p.Lon <= b.Max.Lon &&
+		p.Lat >= b.Min.Lat && p.Lat <= b.Max.Lat
+}
+
+// BoundingBox returns the bounding box of the geometry, ok is false if the
+// geometry has no points. Geometries crossing the antimeridian are not
+// handled specially.
+func (g Geometry) BoundingBox() (bbox BBox, ok bool) {
+	points := g.points()
+	if len(points) == 0 {
+		return BBox{}, false
+	}
+
+	bbox = BBox{Min: points[0], Max: points[0]}
+	for _, p := range points[1:] {
+		bbox.Min.Lon = math.Min(bbox.Min.Lon, p.Lon)
+		bbox.Min.Lat = math.Min(bbox.Min.Lat, p.Lat)
+		bbox.Max.Lon = math.Max(bbox.Max.Lon, p.Lon)
+		bbox.Max.Lat = math.Max(bbox.Max.Lat, p.Lat)
+	}
+	return bbox, true
+}
+
+// Validate checks that the geometry is a valid GeoJSON geometry: coordinates
+// must be within range, lines must have at least two points and polygon rings
+// must be closed and have at least four points.
+func (g Geometry) Validate() error {
+	switch g.Type {
+	case "Point":
+		return g.Point.validate()
+	case "LineString":
+		return g.Line.validate()
+	case "Polygon":
+		return g.Lines.validate()
+	case "MultiPoint":
+		for _, point := range g.MultiPoint {
+			if err := point.validate(); err != nil {
+				return err
+			}
+		}
+	case "MultiLineString":
+		for _, line := range g.MultiLine {
+			if err := line.validate(); err != nil {
+				return err
+			}
+		}
+	case "MultiPolygon":
+		for _, polygon := range g.MultiPolygon {
+			if err := polygon.validate(); err != nil {
+				return err
+			}
+		}
+	case "GeometryCollection":
+		for _, geometry := range g.Geometries {
+			if err := geometry.Validate(); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("GeoJSON object field 'type' %s is not valid", g.Type)
+	}
+
+	return nil
+}
+
+func (p Point) validate() error {
+	if math.IsNaN(p.Lon) || p.Lon < -180 || p.Lon > 180 {
+		return fmt.Errorf("longitude %v is out of range, expected a value between -180 and 180", p.Lon)
+	}
+	if math.IsNaN(p.Lat) || p.Lat < -90 || p.Lat > 90 {
+		return fmt.Errorf("latitude %v is out of range, expected a value between -90 and 90", p.Lat)
+	}
+	return nil
+}
+
+func (l Line) validate() error {
+	if len(l) < 2 {
+		return fmt.Errorf("LineString has %d points, expected at least 2", len(l))
+	}
+	for _, point := range l {
+		if err := point.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l Lines) validate() error {
+	if len(l) == 0 {
+		return fmt.Errorf("Polygon has no rings")
+	}
+	for _, ring := range l {
+		if len(ring) < 4 {
+			return fmt.Errorf("Polygon ring has %d points, expected at least 4", len(ring))
+		}
+		if ring[0] != ring[len(ring)-1] {
+			return fmt.Errorf("Polygon ring is not closed, the first and last points must be the same")
+		}
+		for _, point := range ring {
+			if err := point.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
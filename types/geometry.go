@@ -4,11 +4,22 @@ import (
 	"fmt"
 )
 
+// Geometry is a GeoJSON geometry object, Type is the GeoJSON type of the
+// geometry and selects which of the other fields is used.
+//
+// RethinkDB only stores Point, LineString and Polygon geometries, the server
+// rejects the other GeoJSON types when they are used in a query. These types
+// can still be decoded from and encoded to GeoJSON using encoding/json, and
+// split into geometries RethinkDB accepts using Parts.
 type Geometry struct {
-	Type  string
-	Point Point
-	Line  Line
-	Lines Lines
+	Type         string
+	Point        Point
+	Line         Line
+	Lines        Lines
+	MultiPoint   MultiPoint
+	MultiLine    MultiLineString
+	MultiPolygon MultiPolygon
+	Geometries   GeometryCollection
 }
 
 func (g Geometry) MarshalRQL() (interface{}, error) {
@@ -19,6 +30,14 @@ func (g Geometry) MarshalRQL() (interface{}, error) {
 		return g.Line.MarshalRQL()
 	case "Polygon":
 		return g.Lines.MarshalRQL()
+	case "MultiPoint":
+		return g.MultiPoint.MarshalRQL()
+	case "MultiLineString":
+		return g.MultiLine.MarshalRQL()
+	case "MultiPolygon":
+		return g.MultiPolygon.MarshalRQL()
+	case "GeometryCollection":
+		return g.Geometries.MarshalRQL()
 	default:
 		return nil, fmt.Errorf("pseudo-type GEOMETRY object field 'type' %s is not valid", g.Type)
 	}
@@ -30,6 +49,10 @@ func (g *Geometry) UnmarshalRQL(data interface{}) error {
 		g.Point = data.Point
 		g.Line = data.Line
 		g.Lines = data.Lines
+		g.MultiPoint = data.MultiPoint
+		g.MultiLine = data.MultiLine
+		g.MultiPolygon = data.MultiPolygon
+		g.Geometries = data.Geometries
 
 		return nil
 	}
@@ -43,6 +66,23 @@ func (g *Geometry) UnmarshalRQL(data interface{}) error {
 	if !ok {
 		return fmt.Errorf("pseudo-type GEOMETRY object is not valid, expects 'type' field")
 	}
+	if typ == "GeometryCollection" {
+		geometries, ok := m["geometries"]
+		if !ok {
+			return fmt.Errorf("pseudo-type GEOMETRY object is not valid, expects 'geometries' field")
+		}
+
+		collection, err := UnmarshalGeometryCollection(geometries)
+		if err != nil {
+			return err
+		}
+
+		g.Type = "GeometryCollection"
+		g.Geometries = collection
+
+		return nil
+	}
+
 	coords, ok := m["coordinates"]
 	if !ok {
 		return fmt.Errorf("pseudo-type GEOMETRY object is not valid, expects 'coordinates' field")
@@ -59,6 +99,15 @@ func (g *Geometry) UnmarshalRQL(data interface{}) error {
 	case "Polygon":
 		g.Type = "Polygon"
 		g.Lines, err = UnmarshalPolygon(coords)
+	case "MultiPoint":
+		g.Type = "MultiPoint"
+		g.MultiPoint, err = UnmarshalMultiPoint(coords)
+	case "MultiLineString":
+		g.Type = "MultiLineString"
+		g.MultiLine, err = UnmarshalMultiLineString(coords)
+	case "MultiPolygon":
+		g.Type = "MultiPolygon"
+		g.MultiPolygon, err = UnmarshalMultiPolygon(coords)
 	default:
 		return fmt.Errorf("pseudo-type GEOMETRY object has invalid type")
 	}
@@ -75,8 +124,23 @@ type Point struct {
 	Lat float64
 }
 type Line []Point
+
+// Lines is a polygon, the first line is the exterior ring of the polygon and
+// any other lines are holes in the polygon.
 type Lines []Line
 
+// MultiPoint is a GeoJSON MultiPoint geometry.
+type MultiPoint []Point
+
+// MultiLineString is a GeoJSON MultiLineString geometry.
+type MultiLineString []Line
+
+// MultiPolygon is a GeoJSON MultiPolygon geometry.
+type MultiPolygon []Lines
+
+// GeometryCollection is a GeoJSON GeometryCollection.
+type GeometryCollection []Geometry
+
 func (p Point) Coords() interface{} {
 	return []interface{}{p.Lon, p.Lat}
 }
@@ -167,6 +231,143 @@ func (l *Lines) UnmarshalRQL(data interface{}) error {
 	return nil
 }
 
+// Exterior returns the exterior ring of the polygon.
+func (l Lines) Exterior() Line {
+	if len(l) == 0 {
+		return nil
+	}
+	return l[0]
+}
+
+// Holes returns the interior rings of the polygon.
+func (l Lines) Holes() []Line {
+	if len(l) < 2 {
+		return nil
+	}
+	return l[1:]
+}
+
+func (m MultiPoint) Coords() interface{} {
+	coords := make([]interface{}, len(m))
+	for i, point := range m {
+		coords[i] = point.Coords()
+	}
+	return coords
+}
+
+func (m MultiPoint) MarshalRQL() (interface{}, error) {
+	return map[string]interface{}{
+		"$reql_type$": "GEOMETRY",
+		"coordinates": m.Coords(),
+		"type":        "MultiPoint",
+	}, nil
+}
+
+func (m *MultiPoint) UnmarshalRQL(data interface{}) error {
+	g := &Geometry{}
+	err := g.UnmarshalRQL(data)
+	if err != nil {
+		return err
+	}
+	if g.Type != "MultiPoint" {
+		return fmt.Errorf("pseudo-type GEOMETRY object has type %s, expected type %s", g.Type, "MultiPoint")
+	}
+
+	*m = g.MultiPoint
+
+	return nil
+}
+
+func (m MultiLineString) Coords() interface{} {
+	coords := make([]interface{}, len(m))
+	for i, line := range m {
+		coords[i] = line.Coords()
+	}
+	return coords
+}
+
+func (m MultiLineString) MarshalRQL() (interface{}, error) {
+	return map[string]interface{}{
+		"$reql_type$": "GEOMETRY",
+		"coordinates": m.Coords(),
+		"type":        "MultiLineString",
+	}, nil
+}
+
+func (m *MultiLineString) UnmarshalRQL(data interface{}) error {
+	g := &Geometry{}
+	err := g.UnmarshalRQL(data)
+	if err != nil {
+		return err
+	}
+	if g.Type != "MultiLineString" {
+		return fmt.Errorf("pseudo-type GEOMETRY object has type %s, expected type %s", g.Type, "MultiLineString")
+	}
+
+	*m = g.MultiLine
+
+	return nil
+}
+
+func (m MultiPolygon) Coords() interface{} {
+	coords := make([]interface{}, len(m))
+	for i, polygon := range m {
+		coords[i] = polygon.Coords()
+	}
+	return coords
+}
+
+func (m MultiPolygon) MarshalRQL() (interface{}, error) {
+	return map[string]interface{}{
+		"$reql_type$": "GEOMETRY",
+		"coordinates": m.Coords(),
+		"type":        "MultiPolygon",
+	}, nil
+}
+
+func (m *MultiPolygon) UnmarshalRQL(data interface{}) error {
+	g := &Geometry{}
+	err := g.UnmarshalRQL(data)
+	if err != nil {
+		return err
+	}
+	if g.Type != "MultiPolygon" {
+		return fmt.Errorf("pseudo-type GEOMETRY object has type %s, expected type %s", g.Type, "MultiPolygon")
+	}
+
+	*m = g.MultiPolygon
+
+	return nil
+}
+
+func (c GeometryCollection) MarshalRQL() (interface{}, error) {
+	geometries, err := c.geoJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"$reql_type$": "GEOMETRY",
+		"geometries":  geometries,
+		"type":        "GeometryCollection",
+	}, nil
+}
+
+func (c *GeometryCollection) UnmarshalRQL(data interface{}) error {
+	g := &Geometry{}
+	err := g.UnmarshalRQL(data)
+	if err != nil {
+		return err
+	}
+	if g.Type != "GeometryCollection" {
+		return fmt.Errorf("pseudo-type GEOMETRY object has type %s, expected type %s", g.Type, "GeometryCollection")
+	}
+
+	*c = g.Geometries
+
+	return nil
+}
+
 func UnmarshalPoint(v interface{}) (Point, error) {
 	coords, ok := v.([]interface{})
 	if !ok {
@@ -223,3 +424,51 @@ func UnmarshalPolygon(v interface{}) (Lines, error) {
 	}
 	return polygon, nil
 }
+
+func UnmarshalMultiPoint(v interface{}) (MultiPoint, error) {
+	line, err := UnmarshalLineString(v)
+	if err != nil {
+		return MultiPoint{}, err
+	}
+	return MultiPoint(line), nil
+}
+
+func UnmarshalMultiLineString(v interface{}) (MultiLineString, error) {
+	lines, err := UnmarshalPolygon(v)
+	if err != nil {
+		return MultiLineString{}, err
+	}
+	return MultiLineString(lines), nil
+}
+
+func UnmarshalMultiPolygon(v interface{}) (MultiPolygon, error) {
+	polygons, ok := v.([]interface{})
+	if !ok {
+		return MultiPolygon{}, fmt.Errorf("pseudo-type GEOMETRY object field 'coordinates' is not valid")
+	}
+
+	var err error
+	multi := make(MultiPolygon, len(polygons))
+	for i, polygon := range polygons {
+		multi[i], err = UnmarshalPolygon(polygon)
+		if err != nil {
+			return MultiPolygon{}, err
+		}
+	}
+	return multi, nil
+}
+
+func UnmarshalGeometryCollection(v interface{}) (GeometryCollection, error) {
+	geometries, ok := v.([]interface{})
+	if !ok {
+		return GeometryCollection{}, fmt.Errorf("pseudo-type GEOMETRY object field 'geometries' is not valid")
+	}
+
+	collection := make(GeometryCollection, len(geometries))
+	for i, geometry := range geometries {
+		if err := collection[i].UnmarshalRQL(geometry); err != nil {
+			return GeometryCollection{}, err
+		}
+	}
+	return collection, nil
+}
@@ -0,0 +1,110 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Time is a ReQL time which keeps the timezone of the time as returned by the
+// server, so that decoding a time and writing it back does not change the
+// timezone text stored in the database.
+type Time struct {
+	time.Time
+	// Timezone is the timezone of the time in the ReQL format, for example
+	// "+01:00". If empty the offset of Time is used.
+	Timezone string
+}
+
+func (t Time) MarshalRQL() (interface{}, error) {
+	timezone := t.Timezone
+	if timezone == "" {
+		timezone = t.Format("-07:00")
+	}
+
+	return map[string]interface{}{
+		"$reql_type$": "TIME",
+		"epoch_time":  EpochTime(t.Time),
+		"timezone":    timezone,
+	}, nil
+}
+
+func (t *Time) UnmarshalRQL(data interface{}) error {
+	switch data := data.(type) {
+	case Time:
+		*t = data
+	case time.Time:
+		t.Time = data
+		t.Timezone = data.Format("-07:00")
+	default:
+		tt, err := UnmarshalTime(data)
+		if err != nil {
+			return err
+		}
+		*t = tt
+	}
+
+	return nil
+}
+
+// EpochTime returns the number of seconds since the Unix epoch of t, as used
+// by the epoch_time field of the TIME pseudo-type.
+func EpochTime(t time.Time) float64 {
+	return float64(t.Unix()) + float64(t.Nanosecond())/float64(time.Second)
+}
+
+// UnmarshalTime converts a TIME pseudo-type object to a Time. Times are
+// rounded to the nearest microsecond and use a fixed zone named after the
+// timezone of the object.
+func UnmarshalTime(v interface{}) (Time, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return Time{}, fmt.Errorf("pseudo-type TIME object is not valid")
+	}
+	epoch, ok := obj["epoch_time"].(float64)
+	if !ok {
+		return Time{}, fmt.Errorf("pseudo-type TIME object field 'epoch_time' is not valid")
+	}
+	timezone, _ := obj["timezone"].(string)
+
+	sec, frac := math.Modf(epoch)
+	t := time.Unix(int64(sec), int64(math.Floor(frac*1e6+0.5))*int64(time.Microsecond))
+
+	if timezone != "" {
+		offset, err := parseTimezone(timezone)
+		if err != nil {
+			return Time{}, err
+		}
+		t = t.In(time.FixedZone(timezone, offset))
+	}
+
+	return Time{Time: t, Timezone: timezone}, nil
+}
+
+// parseTimezone returns the offset in seconds of a ReQL timezone, either "Z"
+// or an offset in the form "+HH:MM".
+func parseTimezone(timezone string) (int, error) {
+	if timezone == "Z" {
+		return 0, nil
+	}
+	if len(timezone) != 6 || (timezone[0] != '+' && timezone[0] != '-') || timezone[3] != ':' {
+		return 0, fmt.Errorf("pseudo-type TIME object field 'timezone' %s is not valid", timezone)
+	}
+
+	hours, err := strconv.Atoi(timezone[1:3])
+	if err != nil {
+		return 0, fmt.Errorf("pseudo-type TIME object field 'timezone' %s is not valid", timezone)
+	}
+	minutes, err := strconv.Atoi(timezone[4:6])
+	if err != nil {
+		return 0, fmt.Errorf("pseudo-type TIME object field 'timezone' %s is not valid", timezone)
+	}
+
+	offset := ((hours * 60) + minutes) * 60
+	if timezone[0] == '-' {
+		offset = -offset
+	}
+
+	return offset, nil
+}
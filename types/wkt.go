@@ -0,0 +1,348 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var wktTypes = map[string]string{
+	"POINT":              "Point",
+	"LINESTRING":         "LineString",
+	"POLYGON":            "Polygon",
+	"MULTIPOINT":         "MultiPoint",
+	"MULTILINESTRING":    "MultiLineString",
+	"MULTIPOLYGON":       "MultiPolygon",
+	"GEOMETRYCOLLECTION": "GeometryCollection",
+}
+
+// WKT returns the geometry in the well-known text format, for example
+// "POINT (-122.42 37.77)".
+func (g Geometry) WKT() (string, error) {
+	var b strings.Builder
+	if err := g.writeWKT(&b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (g Geometry) writeWKT(b *strings.Builder) error {
+	switch g.Type {
+	case "Point":
+		b.WriteString("POINT (")
+		writeWKTPoint(b, g.Point)
+		b.WriteByte(')')
+	case "LineString":
+		b.WriteString("LINESTRING ")
+		writeWKTLine(b, g.Line)
+	case "Polygon":
+		b.WriteString("POLYGON ")
+		writeWKTPolygon(b, g.Lines)
+	case "MultiPoint":
+		b.WriteString("MULTIPOINT ")
+		writeWKTLine(b, Line(g.MultiPoint))
+	case "MultiLineString":
+		b.WriteString("MULTILINESTRING ")
+		writeWKTPolygon(b, Lines(g.MultiLine))
+	case "MultiPolygon":
+		b.WriteString("MULTIPOLYGON ")
+		if len(g.MultiPolygon) == 0 {
+			b.WriteString("EMPTY")
+			return nil
+		}
+		b.WriteByte('(')
+		for i, polygon := range g.MultiPolygon {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeWKTPolygon(b, polygon)
+		}
+		b.WriteByte(')')
+	case "GeometryCollection":
+		b.WriteString("GEOMETRYCOLLECTION ")
+		if len(g.Geometries) == 0 {
+			b.WriteString("EMPTY")
+			return nil
+		}
+		b.WriteByte('(')
+		for i, geometry := range g.Geometries {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			if err := geometry.writeWKT(b); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(')')
+	default:
+		return fmt.Errorf("WKT geometry type %s is not valid", g.Type)
+	}
+
+	return nil
+}
+
+func writeWKTPoint(b *strings.Builder, p Point) {
+	b.WriteString(strconv.FormatFloat(p.Lon, 'f', -1, 64))
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(p.Lat, 'f', -1, 64))
+}
+
+func writeWKTLine(b *strings.Builder, l Line) {
+	if len(l) == 0 {
+		b.WriteString("EMPTY")
+		return
+	}
+	b.WriteByte('(')
+	for i, p := range l {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		writeWKTPoint(b, p)
+	}
+	b.WriteByte(')')
+}
+
+func writeWKTPolygon(b *strings.Builder, l Lines) {
+	if len(l) == 0 {
+		b.WriteString("EMPTY")
+		return
+	}
+	b.WriteByte('(')
+	for i, line := range l {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		writeWKTLine(b, line)
+	}
+	b.WriteByte(')')
+}
+
+// ParseWKT parses a geometry in the well-known text format. Only two
+// dimensional coordinates are supported.
+func ParseWKT(s string) (Geometry, error) {
+	p := &wktParser{s: s}
+	g, err := p.geometry()
+	if err != nil {
+		return Geometry{}, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.s) {
+		return Geometry{}, p.errorf("unexpected %q", p.s[p.pos:])
+	}
+	return g, nil
+}
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+func (p *wktParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("WKT geometry is not valid at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// word returns the next upper cased keyword.
+func (p *wktParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			break
+		}
+		p.pos++
+	}
+	return strings.ToUpper(p.s[start:p.pos])
+}
+
+// consume skips the byte c if it is the next non-space byte.
+func (p *wktParser) consume(c byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *wktParser) expect(c byte) error {
+	if !p.consume(c) {
+		return p.errorf("expected %q", c)
+	}
+	return nil
+}
+
+// empty consumes the EMPTY keyword or the opening parenthesis of a list.
+func (p *wktParser) empty() (bool, error) {
+	p.skipSpace()
+	if strings.HasPrefix(strings.ToUpper(p.s[p.pos:]), "EMPTY") {
+		p.pos += len("EMPTY")
+		return true, nil
+	}
+	return false, p.expect('(')
+}
+
+func (p *wktParser) geometry() (Geometry, error) {
+	word := p.word()
+	typ, ok := wktTypes[word]
+	if !ok {
+		return Geometry{}, p.errorf("unknown geometry type %q", word)
+	}
+	g := Geometry{Type: typ}
+
+	var err error
+	switch typ {
+	case "Point":
+		if err = p.expect('('); err != nil {
+			return Geometry{}, err
+		}
+		if g.Point, err = p.point(); err != nil {
+			return Geometry{}, err
+		}
+		err = p.expect(')')
+	case "LineString":
+		g.Line, err = p.line()
+	case "Polygon":
+		g.Lines, err = p.polygon()
+	case "MultiPoint":
+		g.MultiPoint, err = p.multiPoint()
+	case "MultiLineString":
+		var lines Lines
+		lines, err = p.polygon()
+		g.MultiLine = MultiLineString(lines)
+	case "MultiPolygon":
+		g.MultiPolygon, err = p.multiPolygon()
+	case "GeometryCollection":
+		g.Geometries, err = p.collection()
+	}
+	if err != nil {
+		return Geometry{}, err
+	}
+
+	return g, nil
+}
+
+func (p *wktParser) number() (float64, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte("+-.0123456789eE", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	f, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil {
+		p.pos = start
+		return 0, p.errorf("expected a number")
+	}
+	return f, nil
+}
+
+func (p *wktParser) point() (Point, error) {
+	lon, err := p.number()
+	if err != nil {
+		return Point{}, err
+	}
+	lat, err := p.number()
+	if err != nil {
+		return Point{}, err
+	}
+	return Point{Lon: lon, Lat: lat}, nil
+}
+
+func (p *wktParser) line() (Line, error) {
+	if empty, err := p.empty(); empty || err != nil {
+		return Line{}, err
+	}
+	var line Line
+	for {
+		point, err := p.point()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, point)
+		if !p.consume(',') {
+			return line, p.expect(')')
+		}
+	}
+}
+
+func (p *wktParser) polygon() (Lines, error) {
+	if empty, err := p.empty(); empty || err != nil {
+		return Lines{}, err
+	}
+	var lines Lines
+	for {
+		line, err := p.line()
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+		if !p.consume(',') {
+			return lines, p.expect(')')
+		}
+	}
+}
+
+// multiPoint parses the points of a MULTIPOINT, which may or may not be
+// enclosed in parentheses.
+func (p *wktParser) multiPoint() (MultiPoint, error) {
+	if empty, err := p.empty(); empty || err != nil {
+		return MultiPoint{}, err
+	}
+	var points MultiPoint
+	for {
+		wrapped := p.consume('(')
+		point, err := p.point()
+		if err != nil {
+			return nil, err
+		}
+		if wrapped {
+			if err := p.expect(')'); err != nil {
+				return nil, err
+			}
+		}
+		points = append(points, point)
+		if !p.consume(',') {
+			return points, p.expect(')')
+		}
+	}
+}
+
+func (p *wktParser) multiPolygon() (MultiPolygon, error) {
+	if empty, err := p.empty(); empty || err != nil {
+		return MultiPolygon{}, err
+	}
+	var polygons MultiPolygon
+	for {
+		polygon, err := p.polygon()
+		if err != nil {
+			return nil, err
+		}
+		polygons = append(polygons, polygon)
+		if !p.consume(',') {
+			return polygons, p.expect(')')
+		}
+	}
+}
+
+func (p *wktParser) collection() (GeometryCollection, error) {
+	if empty, err := p.empty(); empty || err != nil {
+		return GeometryCollection{}, err
+	}
+	var geometries GeometryCollection
+	for {
+		g, err := p.geometry()
+		if err != nil {
+			return nil, err
+		}
+		geometries = append(geometries, g)
+		if !p.consume(',') {
+			return geometries, p.expect(')')
+		}
+	}
+}
@@ -43,7 +43,12 @@ func constructMethodTerm(prevVal Term, name string, termType p.Term_TermType, ar
 func newQuery(t Term, qopts map[string]interface{}, copts *ConnectOpts) (q Query, err error) {
 	queryOpts := map[string]interface{}{}
 	for k, v := range qopts {
-		queryOpts[k], err = Expr(v).Build()
+		if isDriverOption(k) {
+			queryOpts[k] = v
+			continue
+		}
+
+		queryOpts[k], err = Expr(v).build(copts.Codec)
 		if err != nil {
 			return
 		}
@@ -55,7 +60,7 @@ func newQuery(t Term, qopts map[string]interface{}, copts *ConnectOpts) (q Query
 		}
 	}
 
-	builtTerm, err := t.Build()
+	builtTerm, err := t.build(copts.Codec)
 	if err != nil {
 		return q, err
 	}
@@ -69,6 +74,30 @@ func newQuery(t Term, qopts map[string]interface{}, copts *ConnectOpts) (q Query
 	}, nil
 }
 
+// withDefaultRunOpts returns the run options of a query merged with the
+// default run options of a session, the options of the query take precedence.
+func withDefaultRunOpts(opts map[string]interface{}, defaults RunOpts) map[string]interface{} {
+	merged := defaults.toMap()
+	if len(merged) == 0 {
+		return opts
+	}
+	for k, v := range opts {
+		merged[k] = v
+	}
+	return merged
+}
+
+// isDriverOption returns true if the run option is used by the driver when
+// reading the results and is not sent to the server.
+func isDriverOption(k string) bool {
+	switch k {
+	case "geometry_format", "time_location", "decode_opts", "prefetch", "decode_workers":
+		return true
+	default:
+		return false
+	}
+}
+
 // makeArray takes a slice of terms and produces a single MAKE_ARRAY term
 func makeArray(args termsList) Term {
 	return Term{
@@ -268,6 +297,16 @@ func encode(data interface{}) (interface{}, error) {
 	return v, nil
 }
 
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
 // shouldRetryQuery checks the result of a query and returns true if the query
 // should be retried
 func shouldRetryQuery(q Query, err error) bool {